@@ -17,8 +17,9 @@ type Globals struct {
 type CLI struct {
 	Globals `envprefix:"BIGBOT_"`
 
-	Run    RunCmd    `cmd:"run" help:"Run BIGbot (the main Discord bot)."`
-	Bridge BridgeCmd `cmd:"bridge" help:"Run BIGbridge (the event client)."`
+	Run        RunCmd        `cmd:"run" help:"Run BIGbot (the main Discord bot)."`
+	Bridge     BridgeCmd     `cmd:"bridge" help:"Run BIGbridge (the event client)."`
+	Replicants ReplicantsCmd `cmd:"replicants" help:"List the NodeCG replicants BIGbot uses, and check them against a live bundle."`
 }
 
 func main() {
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/pkg/nodecg"
+	"os"
+	"text/tabwriter"
+)
+
+type ReplicantsCmd struct {
+	Schemas bool                `long:"schemas" help:"Print each replicant's JSON schema" default:"false"`
+	Check   string              `long:"check" help:"NodeCG host (e.g. http://localhost:9090) to check the replicants against the bundle's schemas" default:"" env:"BIGBOT_REPLICANTS_CHECK"`
+	Bundle  string              `long:"bundle" help:"NodeCG bundle name" default:"thebiggame" env:"BIGBOT_AV_NODECG_BUNDLE"`
+	Key     config.SecretString `long:"key" help:"NodeCG authentication key (if NodeCG requires login)" default:"" env:"BIGBRIDGE_AV_NODECG_AUTHKEY"`
+}
+
+func (cmd *ReplicantsCmd) Run(globals *Globals) error {
+	if cmd.Check != "" {
+		return cmd.check()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "REPLICANT\tBUNDLE\tTYPE")
+	for _, r := range ngtbg.Replicants() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name(), cmd.bundle(r), r.Type())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !cmd.Schemas {
+		return nil
+	}
+	for _, r := range ngtbg.Replicants() {
+		schema, err := json.MarshalIndent(r.Schema(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n%s:\n%s\n", r.Name(), schema)
+	}
+	return nil
+}
+
+// bundle returns the bundle a replicant belongs to.
+func (cmd *ReplicantsCmd) bundle(r ngtbg.RegisteredReplicant) string {
+	if r.Bundle() == "" {
+		return cmd.Bundle
+	}
+	return r.Bundle()
+}
+
+// check compares every replicant with the schema the live bundle has for it, failing if any don't match.
+func (cmd *ReplicantsCmd) check() error {
+	server := nodecg.New(cmd.Check).WithKey(string(cmd.Key))
+	var mismatched int
+	for _, check := range ngtbg.CheckSchemas(context.Background(), server, cmd.Bundle) {
+		switch {
+		case errors.Is(check.Err, nodecg.ErrSchemaNotFound):
+			// NodeCG doesn't insist on schemas, so this is worth knowing but not a failure.
+			fmt.Printf("❔ %s: the %s bundle has no schema for it\n", check.Replicant.Name(), check.Bundle)
+		case check.Err != nil:
+			return fmt.Errorf("unable to check %s: %w", check.Replicant.Name(), check.Err)
+		case check.OK():
+			fmt.Printf("✅ %s\n", check.Replicant.Name())
+		default:
+			mismatched++
+			fmt.Printf("❌ %s:\n", check.Replicant.Name())
+			for _, problem := range check.Problems {
+				fmt.Printf("    %s\n", problem)
+			}
+		}
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("%d replicants don't match the bundle's schemas", mismatched)
+	}
+	return nil
+}
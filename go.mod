@@ -10,7 +10,9 @@ require (
 	github.com/andreykaipov/goobs v1.5.3
 	github.com/bwmarrin/discordgo v0.28.1
 	github.com/gorilla/websocket v1.5.3
+	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d
 	golang.org/x/sync v0.11.0
+	golang.org/x/text v0.21.0
 	google.golang.org/protobuf v1.33.0
 )
 
@@ -22,7 +24,6 @@ require (
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
 	github.com/mmcloughlin/profile v0.1.1 // indirect
-	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	golang.org/x/crypto v0.21.0 // indirect
 	golang.org/x/sys v0.21.0 // indirect
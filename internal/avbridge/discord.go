@@ -30,6 +30,75 @@ var commands = []*discordgo.ApplicationCommand{
 				Description: "📽️ Transition to Infoboard (the default projector display).",
 				Type:        discordgo.ApplicationCommandOptionSubCommand,
 			},
+			{
+				Name:        "ticker",
+				Description: "📽️ Manage the scrolling ticker on the Infoboard.",
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "set",
+						Description: "📽️ Replace everything on the ticker with one item.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "text",
+								Description: "What the ticker should say.",
+								Required:    true,
+								MaxLength:   maxTickerItemLength,
+							},
+						},
+					},
+					{
+						Name:        "add",
+						Description: "📽️ Add an item to the end of the ticker.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "text",
+								Description: "What the new item should say.",
+								Required:    true,
+								MaxLength:   maxTickerItemLength,
+							},
+						},
+					},
+					{
+						Name:        "clear",
+						Description: "📽️ Remove everything from the ticker.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+					},
+				},
+			},
+			{
+				Name:        "lowerthird",
+				Description: "📽️ Show a name and title in the lower third of the screen for a while.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "Who (or what) is on screen.",
+						Required:    true,
+						MaxLength:   60,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "title",
+						Description: "Their role, or anything else to show beneath the name.",
+						Required:    false,
+						MaxLength:   100,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "duration",
+						Description: "How many seconds to show it for. Defaults to 10.",
+						Required:    false,
+						MinValue:    &minLowerThirdDuration,
+						MaxValue:    maxLowerThirdDuration,
+					},
+				},
+			},
 		},
 	},
 }
@@ -38,6 +107,14 @@ func (mod *AVBridge) DiscordHandleMessage(session *discordgo.Session, message *d
 	return nil
 }
 
+func (mod *AVBridge) DiscordHandleMessageUpdate(session *discordgo.Session, message *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *AVBridge) DiscordHandleMessageDelete(session *discordgo.Session, message *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
 func (mod *AVBridge) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
@@ -51,7 +128,7 @@ func (mod *AVBridge) DiscordHandleInteraction(s *discordgo.Session, i *discordgo
 		switch options[0].Name {
 		case "status":
 			if !bridge_wan.BridgeIsAvailable() {
-				return true, helpers.DiscordInteractionEphemeralResponse(s, i, "👻 **Event Bridge is not available**")
+				return true, helpers.DiscordInteractionEphemeralResponse(s, i, "👻 **Event Bridge is not available**"+queueStatus())
 			}
 			if helpers.DiscordDeferEphemeralInteraction(s, i) != nil {
 				return true, err
@@ -75,6 +152,24 @@ func (mod *AVBridge) DiscordHandleInteraction(s *discordgo.Session, i *discordgo
 				return true, err
 			}
 			return true, mod.discordCommandAVInfoboard(s, i)
+		case "ticker":
+			if !bridge_wan.BridgeIsAvailable() {
+				return true, helpers.DiscordInteractionEphemeralResponse(s, i, "👻 **Event Bridge is not available**")
+			}
+			// Let the client know we're working on it.
+			if helpers.DiscordDeferEphemeralInteraction(s, i) != nil {
+				return true, err
+			}
+			return true, mod.discordCommandAVTicker(s, i, options[0].Options[0])
+		case "lowerthird":
+			if !bridge_wan.BridgeIsAvailable() {
+				return true, helpers.DiscordInteractionEphemeralResponse(s, i, "👻 **Event Bridge is not available**")
+			}
+			// Let the client know we're working on it.
+			if helpers.DiscordDeferEphemeralInteraction(s, i) != nil {
+				return true, err
+			}
+			return true, mod.discordCommandAVLowerThird(s, i, options[0])
 		}
 
 		// Not handled by specific handler function, respond with content data.
@@ -105,10 +200,22 @@ func (mod *AVBridge) discordCommandAVVersions(s *discordgo.Session, i *discordgo
 	}
 
 	// Return versions
-	_, err = helpers.DiscordInteractionFollowupMessage(s, i, fmt.Sprintf("🙆 **Event Bridge is connected.**\nOBS: %s\nNodeCG Bundle: %s", *verObs, *verNcg))
+	_, err = helpers.DiscordInteractionFollowupMessage(s, i, fmt.Sprintf("🙆 **Event Bridge is connected.**\nOBS: %s\nNodeCG Bundle: %s", *verObs, *verNcg)+queueStatus())
 	return err
 }
 
+// queueStatus describes how many messages are waiting to be sent to NodeCG (or nothing, if none are).
+func queueStatus() string {
+	if bridge_wan.EventBridge == nil {
+		return ""
+	}
+	depth := bridge_wan.EventBridge.QueueDepth()
+	if depth == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n📬 %d queued messages waiting to be sent", depth)
+}
+
 func (mod *AVBridge) discordCommandAVInfoboard(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
 	err = bridge_wan.EventBridge.OBSSceneTransition(ngtbg.OBSSceneDefault, ngtbg.OBSTransStingModernWipe)
 	if err != nil {
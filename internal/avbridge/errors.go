@@ -0,0 +1,6 @@
+package avbridge
+
+import "errors"
+
+var ErrTickerFull = errors.New("The ticker is full. Clear it (or set it) first")
+var ErrTickerEmpty = errors.New("Ticker items can't be empty")
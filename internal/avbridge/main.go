@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/bwmarrin/discordgo"
 	"log/slog"
+	"sync"
+	"time"
 )
 
 type AVBridge struct {
@@ -14,6 +16,15 @@ type AVBridge struct {
 
 	// The context given to us by the main bot.
 	ctx *context.Context
+
+	// Held while changing the ticker, so that items added at the same time aren't lost.
+	tickerMtx sync.Mutex
+
+	// Held while showing or hiding the lower third. lowerThirdShown counts the lower thirds shown, so that a lower
+	// third isn't hidden early by the timer of the one it replaced.
+	lowerThirdMtx   sync.Mutex
+	lowerThirdShown int
+	lowerThirdTimer *time.Timer
 }
 
 func New(discord *discordgo.Session) (bridge *AVBridge, err error) {
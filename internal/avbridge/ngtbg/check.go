@@ -0,0 +1,45 @@
+package ngtbg
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/thebiggame/bigbot/pkg/nodecg"
+)
+
+// SchemaCheck is the result of checking one replicant against the schema a live NodeCG bundle has for it.
+type SchemaCheck struct {
+	Replicant RegisteredReplicant
+	// The bundle that was checked.
+	Bundle string
+	// The ways in which BIGbot's schema doesn't match the bundle's (empty if they match).
+	Problems []string
+	// Set if the bundle's schema couldn't be fetched (nodecg.ErrSchemaNotFound if it has none) or understood.
+	Err error
+}
+
+// OK returns whether the replicant matches the bundle's schema.
+func (check SchemaCheck) OK() bool {
+	return check.Err == nil && len(check.Problems) == 0
+}
+
+// CheckSchemas checks every registered replicant against the schemas in a live NodeCG bundle.
+// Replicants without a bundle of their own are checked against bundle.
+func CheckSchemas(ctx context.Context, server *nodecg.NodeCGServer, bundle string) []SchemaCheck {
+	var checks []SchemaCheck
+	for _, r := range Replicants() {
+		check := SchemaCheck{Replicant: r, Bundle: r.Bundle()}
+		if check.Bundle == "" {
+			check.Bundle = bundle
+		}
+		data, err := server.BundleSchema(ctx, check.Bundle, r.Name())
+		if err == nil {
+			var live Schema
+			if err = json.Unmarshal(data, &live); err == nil {
+				check.Problems = r.Schema().Compare(&live)
+			}
+		}
+		check.Err = err
+		checks = append(checks, check)
+	}
+	return checks
+}
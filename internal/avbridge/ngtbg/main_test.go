@@ -0,0 +1,169 @@
+package ngtbg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/thebiggame/bigbot/pkg/nodecg"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSchemaFor(t *testing.T) {
+	schema := SchemaFor(reflect.TypeFor[NodeCGReplicantDataMusicQueue]())
+	if schema.Draft != schemaDraft || !slices.Equal(schema.Type, SchemaType{"object"}) || !slices.Equal(schema.Required, []string{"tracks"}) {
+		t.Fatalf("Unexpected schema %+v", schema)
+	}
+	track := schema.Properties["tracks"].Items
+	if track == nil || track.Draft != "" {
+		t.Fatalf("Expected a nested item schema (without $schema), got %+v", track)
+	}
+	// omitempty fields are optional.
+	if !slices.Equal(track.Required, []string{"artist", "title"}) || track.Properties["requested_by"] == nil {
+		t.Errorf("Unexpected track schema %+v", track)
+	}
+	if got := SchemaFor(reflect.TypeFor[int]()).Type; !slices.Equal(got, SchemaType{"integer"}) {
+		t.Errorf("Unexpected type %v for int", got)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	if err := ReplicantPolls.Validate(NodeCGReplicantDataPolls{Polls: []NodeCGReplicantDataPoll{}}); err != nil {
+		t.Errorf("Expected an empty list to be valid, got %v", err)
+	}
+	// NodeCG would reject a null list, so we should too.
+	err := ReplicantPolls.Validate(NodeCGReplicantDataPolls{})
+	if !errors.Is(err, ErrInvalidReplicant) || !strings.Contains(err.Error(), "value.polls is null") {
+		t.Errorf("Expected a nil list to be invalid, got %v", err)
+	}
+	err = ReplicantPolls.Validate(NodeCGReplicantDataPolls{Polls: []NodeCGReplicantDataPoll{{Question: "Which game next?"}}})
+	if !errors.Is(err, ErrInvalidReplicant) || !strings.Contains(err.Error(), "value.polls[0].options is null") {
+		t.Errorf("Expected a nested nil list to be invalid, got %v", err)
+	}
+	if err = ReplicantLowerThirdActive.Validate(true); err != nil {
+		t.Errorf("Expected a boolean to be valid, got %v", err)
+	}
+
+	// Schemas from NodeCG can be stricter than the types they're checked against.
+	var schema Schema
+	if err = json.Unmarshal([]byte(`{"type":"object","required":["name"],"properties":{"name":{"type":"string"},"score":{"type":"integer"}}}`), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if err = schema.Validate(map[string]any{"score": 1}); err == nil || !strings.Contains(err.Error(), "value.name is missing") {
+		t.Errorf("Expected a missing property to be invalid, got %v", err)
+	}
+	if err = schema.Validate(map[string]any{"name": "a", "score": 1.5}); err == nil || !strings.Contains(err.Error(), "value.score is number, not integer") {
+		t.Errorf("Expected a fraction to be invalid, got %v", err)
+	}
+}
+
+func TestReplicantDecode(t *testing.T) {
+	data, err := ReplicantMusicData.Decode([]byte(`{"title":"Sandstorm","artist":"Darude"}`))
+	if err != nil || data.Title != "Sandstorm" || data.Artist != "Darude" {
+		t.Errorf("Unexpected music data %+v (%v)", data, err)
+	}
+	if _, err = ReplicantMusicData.Decode([]byte(`"Sandstorm"`)); err == nil {
+		t.Error("Expected an error decoding the wrong type")
+	}
+}
+
+func TestReplicants(t *testing.T) {
+	replicants := Replicants()
+	if len(replicants) != len(registry) {
+		t.Fatalf("Expected %d replicants, got %d", len(registry), len(replicants))
+	}
+	if !slices.IsSortedFunc(replicants, func(a, b RegisteredReplicant) int { return strings.Compare(a.Name(), b.Name()) }) {
+		t.Error("Expected replicants to be sorted by name")
+	}
+	if r, ok := registry[NodeCGReplicantTicker]; !ok || r.Type() != reflect.TypeFor[NodeCGReplicantDataTicker]() {
+		t.Errorf("Expected the ticker to be registered, got %v", r)
+	}
+}
+
+func TestSchemaCompare(t *testing.T) {
+	ours := ReplicantLowerThirdData.Schema()
+	tests := []struct {
+		live string
+		want []string
+	}{
+		{live: `{"type":"object","properties":{"name":{"type":"string"},"title":{"type":["string","null"]},"duration":{"type":"number"}},"required":["name"]}`},
+		// Schemas without types (e.g. using $ref) can't be checked, so anything goes.
+		{live: `{"$ref":"#/definitions/lowerThird"}`},
+		{
+			live: `{"type":"object","properties":{"name":{"type":"string"},"title":{"type":"string"},"duration":{"type":"string"},"colour":{"type":"string"}},"required":["colour"]}`,
+			want: []string{
+				"value.duration: BIGbot sends integer, but the bundle expects string",
+				"value.colour: the bundle requires it, but BIGbot doesn't send it",
+			},
+		},
+		{
+			live: `{"type":"object","properties":{"name":{"type":"string"}}}`,
+			want: []string{
+				"value.duration: BIGbot sends it, but the bundle doesn't expect it",
+				"value.title: BIGbot sends it, but the bundle doesn't expect it",
+			},
+		},
+		{live: `{"type":"array"}`, want: []string{"value: BIGbot sends object, but the bundle expects array"}},
+	}
+	for _, test := range tests {
+		var live Schema
+		if err := json.Unmarshal([]byte(test.live), &live); err != nil {
+			t.Fatal(err)
+		}
+		if got := ours.Compare(&live); !slices.Equal(got, test.want) {
+			t.Errorf("Compare(%s) = %q, want %q", test.live, got, test.want)
+		}
+	}
+
+	// Optional properties can't satisfy a bundle that requires them.
+	var live Schema
+	if err := json.Unmarshal([]byte(`{"type":"object","required":["tracks"],"properties":{"tracks":{"type":"array","items":{"type":"object","required":["requested_by"]}}}}`), &live); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"value.tracks[].requested_by: the bundle requires it, but BIGbot sometimes leaves it out"}
+	if got := ReplicantMusicQueue.Schema().Compare(&live); !slices.Equal(got, want) {
+		t.Errorf("Compare() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckSchemas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/bundles/thebiggame/schemas/ticker:items.json":
+			w.Write([]byte(`{"type":"object","properties":{"items":{"type":"array","items":{"type":"string"}}},"required":["items"]}`))
+		case "/bundles/thebiggame/schemas/lowerthird:active.json":
+			w.Write([]byte(`{"type":"string"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	checks := CheckSchemas(context.Background(), nodecg.New(server.URL), "thebiggame")
+	if len(checks) != len(registry) {
+		t.Fatalf("Expected every replicant to be checked, got %d checks", len(checks))
+	}
+	for _, check := range checks {
+		if check.Bundle != "thebiggame" {
+			t.Errorf("Expected %s to be checked against the given bundle, got %q", check.Replicant.Name(), check.Bundle)
+		}
+		switch check.Replicant.Name() {
+		case NodeCGReplicantTicker:
+			if !check.OK() {
+				t.Errorf("Expected the ticker to match, got %q (%v)", check.Problems, check.Err)
+			}
+		case NodeCGReplicantLowerThirdActive:
+			if check.Err != nil || len(check.Problems) != 1 {
+				t.Errorf("Expected the lower third to mismatch, got %q (%v)", check.Problems, check.Err)
+			}
+		default:
+			if !errors.Is(check.Err, nodecg.ErrSchemaNotFound) {
+				t.Errorf("Expected no schema for %s, got %v", check.Replicant.Name(), check.Err)
+			}
+		}
+	}
+}
@@ -17,9 +17,33 @@ type NodeCGReplicantDataMusicData struct {
 	Artist string `json:"artist"`
 }
 
+// NodeCGReplicantDataMusicQueueEntry is one song waiting to be played.
+type NodeCGReplicantDataMusicQueueEntry struct {
+	// The song title.
+	Title string `json:"title"`
+	// The song artist.
+	Artist string `json:"artist"`
+	// Who asked for the song, if anybody did.
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+// NodeCGReplicantDataMusicQueue is the content of a MusicQueue replicant.
+type NodeCGReplicantDataMusicQueue struct {
+	// The songs waiting to be played, in the order they'll be played.
+	Tracks []NodeCGReplicantDataMusicQueueEntry `json:"tracks"`
+}
+
+// NodeCGReplicantDataMusicSkip is how close the current song is to being vote-skipped.
+type NodeCGReplicantDataMusicSkip struct {
+	// How many people have voted to skip the current song.
+	Votes int `json:"votes"`
+	// How many votes are needed to skip it.
+	Needed int `json:"needed"`
+}
+
 // NodeCGReplicantDataShoutboxEntry is equivalent to one Shout.
 type NodeCGReplicantDataShoutboxEntry struct {
-	// A unique ID for this message.
+	// A unique ID for this message, prefixed by where it came from ("DISC-", "WEB-" or "TWCH-").
 	ID   string `json:"id"`
 	User struct {
 		// The name of the user who submitted this Shout.
@@ -31,14 +55,166 @@ type NodeCGReplicantDataShoutboxEntry struct {
 	Timestamp string `json:"timestamp"`
 	// The shout message content (in markdown).
 	Message string `json:"message"`
+	// Any images (or stickers) sent with the shout.
+	Media []NodeCGReplicantDataShoutboxMedia `json:"media,omitempty"`
+	// Where the shout came from (ShoutboxSourceDiscord, ShoutboxSourceWeb or ShoutboxSourceTwitch).
+	Source string `json:"source,omitempty"`
+}
+
+// Sources of NodeCGReplicantDataShoutboxEntry.
+const (
+	ShoutboxSourceDiscord = "discord"
+	ShoutboxSourceWeb     = "web"
+	ShoutboxSourceTwitch  = "twitch"
+)
+
+// NodeCGReplicantDataShoutboxMedia is an image shown alongside a Shout.
+type NodeCGReplicantDataShoutboxMedia struct {
+	// What kind of media this is (ShoutboxMediaImage or ShoutboxMediaSticker).
+	Type string `json:"type"`
+	// The URL that the image can be found at.
+	URL string `json:"url"`
+	// The size of the image, if known.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// The name of the sticker, if this is one.
+	Name string `json:"name,omitempty"`
+}
+
+// Types of NodeCGReplicantDataShoutboxMedia.
+const (
+	ShoutboxMediaImage   = "image"
+	ShoutboxMediaSticker = "sticker"
+)
+
+// NodeCGMessageDataShoutboxRemove identifies a Shout that should no longer be shown.
+type NodeCGMessageDataShoutboxRemove struct {
+	// The ID of the Shout (see NodeCGReplicantDataShoutboxEntry).
+	ID string `json:"id"`
 }
 
 type NodeCGReplicantDataShoutboxEntries struct {
 	Shouts []NodeCGReplicantDataShoutboxEntry `json:"shouts"`
 }
 
+// NodeCGReplicantDataTournamentTeam is one team registered for a tournament.
+type NodeCGReplicantDataTournamentTeam struct {
+	// The name of the team.
+	Name string `json:"name"`
+	// The display name of the team's captain.
+	Captain string `json:"captain"`
+	// The display names of the team's players.
+	Players []string `json:"players"`
+}
+
+// NodeCGReplicantDataTournament is one tournament, along with the teams registered for it.
+type NodeCGReplicantDataTournament struct {
+	// The name of the tournament.
+	Name string `json:"name"`
+	// Whether registrations are open.
+	Open bool `json:"open"`
+	// The roster size limits for the tournament. MaxPlayers is 0 if there is no limit.
+	MinPlayers int `json:"min_players"`
+	MaxPlayers int `json:"max_players"`
+	// The registered teams, in order of registration.
+	Teams []NodeCGReplicantDataTournamentTeam `json:"teams"`
+}
+
+type NodeCGReplicantDataTournaments struct {
+	Tournaments []NodeCGReplicantDataTournament `json:"tournaments"`
+}
+
+// NodeCGReplicantDataHelpDesk is a summary of the help desk tickets, for the crew dashboard.
+type NodeCGReplicantDataHelpDesk struct {
+	// How many tickets are open.
+	Open int `json:"open"`
+	// How many of the open tickets nobody in Crew has claimed yet.
+	Unclaimed int `json:"unclaimed"`
+}
+
+// NodeCGReplicantDataPollOption is one of the answers in a poll, and how many people chose it.
+type NodeCGReplicantDataPollOption struct {
+	Label string `json:"label"`
+	Votes int    `json:"votes"`
+}
+
+// NodeCGReplicantDataPoll is one poll, with its results so far.
+type NodeCGReplicantDataPoll struct {
+	// The poll's number, as shown in Discord.
+	Number int `json:"number"`
+	// What's being asked.
+	Question string `json:"question"`
+	// The answers, in the order they were given.
+	Options []NodeCGReplicantDataPollOption `json:"options"`
+	// How many people have voted.
+	TotalVotes int `json:"total_votes"`
+	// When voting ends (or ended), in RFC 3339 format.
+	EndsAt string `json:"ends_at"`
+	// Whether voting has ended, so these are the final results.
+	Closed bool `json:"closed"`
+}
+
+type NodeCGReplicantDataPolls struct {
+	// Open polls (and recently closed ones, so that their results can be shown), oldest first.
+	Polls []NodeCGReplicantDataPoll `json:"polls"`
+}
+
+// NodeCGReplicantDataCountdown is one named countdown timer.
+type NodeCGReplicantDataCountdown struct {
+	// The countdown's name (unique among the running countdowns).
+	Name string `json:"name"`
+	// What's shown alongside the timer, e.g. "Tournament starts in".
+	Label string `json:"label"`
+	// When the countdown reaches zero, in RFC 3339 format.
+	EndsAt string `json:"ends_at"`
+}
+
+type NodeCGReplicantDataCountdowns struct {
+	// The running countdowns, soonest first.
+	Countdowns []NodeCGReplicantDataCountdown `json:"countdowns"`
+}
+
+// NodeCGReplicantDataTicker is the text scrolling along the bottom of the infoboard.
+type NodeCGReplicantDataTicker struct {
+	// The items to scroll through, in order.
+	Items []string `json:"items"`
+}
+
+// NodeCGReplicantDataLowerThird is a name and title shown in the lower third of the screen.
+type NodeCGReplicantDataLowerThird struct {
+	// Who (or what) is on screen.
+	Name string `json:"name"`
+	// Their role, or anything else to show beneath the name.
+	Title string `json:"title"`
+	// How long the lower third is shown for, in seconds.
+	Duration int `json:"duration"`
+}
+
+// NodeCGMessageDataRaffleWinner is somebody who won a raffle.
+type NodeCGMessageDataRaffleWinner struct {
+	// The winner's display name.
+	Name string `json:"name"`
+	// The URL that their avatar can be found at.
+	Avatar string `json:"avatar_url"`
+}
+
+// NodeCGMessageDataRaffleReveal announces the winners of a raffle draw, for the infoboard to reveal.
+type NodeCGMessageDataRaffleReveal struct {
+	// The raffle's number and name, as shown in Discord.
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+	// What's being won.
+	Prize string `json:"prize"`
+	// How many people could have won (for a suitably dramatic reveal).
+	Entrants int `json:"entrants"`
+	// Who won.
+	Winners []NodeCGMessageDataRaffleWinner `json:"winners"`
+	// Whether this is a redraw (e.g. because a winner wasn't around to collect their prize).
+	Redraw bool `json:"redraw"`
+}
+
 const (
-	// NodeCG Replicants
+	// NodeCG Replicants (read and write these through their typed handles in replicant.go, e.g. ReplicantShoutbox)
 
 	// Whether the "event info" data is active. Boolean.
 	NodeCGReplicantEventInfoActive = "event:info:active"
@@ -48,6 +224,12 @@ const (
 	// Information on the current music traka. Object of type NodeCGReplicantDataMusicData
 	NodeCGReplicantMusicData = "music:data"
 
+	// The songs waiting to be played. Object of type NodeCGReplicantDataMusicQueue
+	NodeCGReplicantMusicQueue = "music:queue"
+
+	// The votes to skip the current song. Object of type NodeCGReplicantDataMusicSkip
+	NodeCGReplicantMusicSkip = "music:skip"
+
 	// Whether the notification "alert" is active. Boolean.
 	NodeCGReplicantNotificationAlertActive = "notify:alert:active"
 
@@ -57,6 +239,27 @@ const (
 	// A list of "shouts". Object of type NodeCGReplicantDataShoutboxEntries
 	NodeCGReplicantShoutbox = "shoutbox:messages"
 
+	// Every tournament and its registered teams. Object of type NodeCGReplicantDataTournaments
+	NodeCGReplicantTournaments = "tournaments:registrations"
+
+	// A summary of the help desk tickets. Object of type NodeCGReplicantDataHelpDesk
+	NodeCGReplicantHelpDesk = "helpdesk:tickets"
+
+	// Open and recently closed polls, with live results. Object of type NodeCGReplicantDataPolls
+	NodeCGReplicantPolls = "polls:current"
+
+	// The running countdown timers. Object of type NodeCGReplicantDataCountdowns
+	NodeCGReplicantCountdowns = "countdowns:active"
+
+	// The scrolling ticker. Object of type NodeCGReplicantDataTicker
+	NodeCGReplicantTicker = "ticker:items"
+
+	// Whether the lower third is shown. Boolean.
+	NodeCGReplicantLowerThirdActive = "lowerthird:active"
+
+	// The content of the lower third. Object of type NodeCGReplicantDataLowerThird
+	NodeCGReplicantLowerThirdData = "lowerthird:data"
+
 	// NodeCG Message channels
 
 	// Fire an "alert" message. Use NodeCGMessageAlert to construct.
@@ -69,4 +272,14 @@ const (
 
 	// Fire an "shoutbox:message-new" message with the contents of a new Shout. Use NodeCGMessageShout to construct.
 	NodeCGMessageShoutboxNew = "shoutbox:new-discord"
+
+	// Fire an "shoutbox:edit-discord" message with the new contents of an edited Shout (matched on its ID).
+	// Object of type NodeCGReplicantDataShoutboxEntry.
+	NodeCGMessageShoutboxEdit = "shoutbox:edit-discord"
+
+	// Fire an "shoutbox:remove-discord" message when a Shout is deleted. Object of type NodeCGMessageDataShoutboxRemove.
+	NodeCGMessageShoutboxRemove = "shoutbox:remove-discord"
+
+	// Fire a "raffle:reveal" message to reveal the winners of a raffle draw. Object of type NodeCGMessageDataRaffleReveal.
+	NodeCGMessageRaffleReveal = "raffle:reveal"
 )
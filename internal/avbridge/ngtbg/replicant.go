@@ -0,0 +1,103 @@
+package ngtbg
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Replicant is a typed handle on a NodeCG replicant: its name, the bundle it belongs to, and the schema its value
+// must match (generated from T).
+type Replicant[T any] struct {
+	name string
+	// The bundle the replicant belongs to. Empty means the configured bundle (--av.nodecg.bundle-name).
+	bundle string
+	schema *Schema
+}
+
+// RegisteredReplicant is a Replicant, whatever its type.
+type RegisteredReplicant interface {
+	Name() string
+	Bundle() string
+	Schema() *Schema
+	// The Go type of the replicant's value.
+	Type() reflect.Type
+}
+
+// registry holds every Replicant, keyed on its name.
+var registry = map[string]RegisteredReplicant{}
+
+// newReplicant creates (and registers) the handle for a replicant in the configured bundle.
+func newReplicant[T any](name string) Replicant[T] {
+	if _, ok := registry[name]; ok {
+		panic("replicant " + name + " registered twice")
+	}
+	r := Replicant[T]{
+		name:   name,
+		schema: SchemaFor(reflect.TypeFor[T]()),
+	}
+	registry[name] = r
+	return r
+}
+
+func (r Replicant[T]) Name() string {
+	return r.name
+}
+
+func (r Replicant[T]) Bundle() string {
+	return r.bundle
+}
+
+func (r Replicant[T]) Schema() *Schema {
+	return r.schema
+}
+
+func (r Replicant[T]) Type() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// Validate checks that value matches the replicant's schema, e.g. that no lists are nil (which NodeCG would reject).
+func (r Replicant[T]) Validate(value T) error {
+	return r.schema.Validate(value)
+}
+
+// Decode decodes the replicant's value from JSON (as NodeCG sends it).
+func (r Replicant[T]) Decode(data []byte) (value T, err error) {
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// Replicants returns every registered replicant, sorted by name.
+func Replicants() []RegisteredReplicant {
+	replicants := make([]RegisteredReplicant, 0, len(registry))
+	for _, r := range registry {
+		replicants = append(replicants, r)
+	}
+	sort.Slice(replicants, func(i, j int) bool {
+		return replicants[i].Name() < replicants[j].Name()
+	})
+	return replicants
+}
+
+// The replicants BIGbot reads or writes. See the NodeCGReplicant constants for what each is for.
+var (
+	ReplicantEventInfoActive = newReplicant[bool](NodeCGReplicantEventInfoActive)
+	ReplicantEventInfoBody   = newReplicant[string](NodeCGReplicantEventInfoBody)
+
+	ReplicantMusicData  = newReplicant[NodeCGReplicantDataMusicData](NodeCGReplicantMusicData)
+	ReplicantMusicQueue = newReplicant[NodeCGReplicantDataMusicQueue](NodeCGReplicantMusicQueue)
+	ReplicantMusicSkip  = newReplicant[NodeCGReplicantDataMusicSkip](NodeCGReplicantMusicSkip)
+
+	ReplicantNotificationAlertActive = newReplicant[bool](NodeCGReplicantNotificationAlertActive)
+	ReplicantNotificationAlertData   = newReplicant[NodeCGReplicantDataAlertData](NodeCGReplicantNotificationAlertData)
+
+	ReplicantShoutbox    = newReplicant[NodeCGReplicantDataShoutboxEntries](NodeCGReplicantShoutbox)
+	ReplicantTournaments = newReplicant[NodeCGReplicantDataTournaments](NodeCGReplicantTournaments)
+	ReplicantHelpDesk    = newReplicant[NodeCGReplicantDataHelpDesk](NodeCGReplicantHelpDesk)
+	ReplicantPolls       = newReplicant[NodeCGReplicantDataPolls](NodeCGReplicantPolls)
+	ReplicantCountdowns  = newReplicant[NodeCGReplicantDataCountdowns](NodeCGReplicantCountdowns)
+
+	ReplicantTicker           = newReplicant[NodeCGReplicantDataTicker](NodeCGReplicantTicker)
+	ReplicantLowerThirdActive = newReplicant[bool](NodeCGReplicantLowerThirdActive)
+	ReplicantLowerThirdData   = newReplicant[NodeCGReplicantDataLowerThird](NodeCGReplicantLowerThirdData)
+)
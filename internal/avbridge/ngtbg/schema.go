@@ -0,0 +1,223 @@
+package ngtbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// The JSON Schema draft NodeCG uses for replicant schemas.
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var ErrInvalidReplicant = errors.New("replicant value doesn't match its schema")
+
+// Schema is the subset of JSON Schema that BIGbot generates for (and checks) replicants.
+type Schema struct {
+	Draft string `json:"$schema,omitempty"`
+	// The JSON type(s) the value may have. NodeCG schemas sometimes allow more than one (e.g. ["string", "null"]).
+	Type SchemaType `json:"type,omitempty"`
+	// For objects: the schemas of their properties, and which of them must be present.
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// For arrays: the schema of every item.
+	Items *Schema `json:"items,omitempty"`
+}
+
+// SchemaType is the "type" of a Schema, which is either a single type or a list of them.
+type SchemaType []string
+
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return json.Unmarshal(data, (*[]string)(t))
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*t = SchemaType{single}
+	return nil
+}
+
+// allows returns whether a value of JSON type kind is acceptable. A schema without a type allows anything.
+func (t SchemaType) allows(kind string) bool {
+	if len(t) == 0 || slices.Contains(t, kind) {
+		return true
+	}
+	// Every integer is a number too.
+	return kind == "integer" && slices.Contains(t, "number")
+}
+
+func (t SchemaType) String() string {
+	return strings.Join(t, " or ")
+}
+
+// SchemaFor generates the schema of the JSON that encoding/json produces for values of type typ.
+// Struct fields are required unless they're tagged omitempty.
+func SchemaFor(typ reflect.Type) *Schema {
+	schema := schemaFor(typ)
+	schema.Draft = schemaDraft
+	return schema
+}
+
+func schemaFor(typ reflect.Type) *Schema {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: SchemaType{"array"}, Items: schemaFor(typ.Elem())}
+	case reflect.Map:
+		return &Schema{Type: SchemaType{"object"}}
+	case reflect.Struct:
+		schema := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}}
+		for _, field := range reflect.VisibleFields(typ) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			schema.Properties[name] = schemaFor(field.Type)
+			if !slices.Contains(strings.Split(options, ","), "omitempty") {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		sort.Strings(schema.Required)
+		return schema
+	default:
+		// Anything else is beyond what replicants need; allow whatever it encodes to.
+		return &Schema{}
+	}
+}
+
+// Validate checks that value, once encoded as JSON, matches the schema.
+// The error (wrapping ErrInvalidReplicant) says where the first mismatch is.
+func (schema *Schema) Validate(value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded any
+	if err = decoder.Decode(&decoded); err != nil {
+		return err
+	}
+	return schema.validate(decoded, "value")
+}
+
+func (schema *Schema) validate(value any, path string) error {
+	kind := jsonKind(value)
+	if !schema.Type.allows(kind) {
+		return fmt.Errorf("%w: %s is %s, not %s", ErrInvalidReplicant, path, kind, schema.Type)
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%w: %s.%s is missing", ErrInvalidReplicant, path, name)
+			}
+		}
+		for name, property := range schema.Properties {
+			if propertyValue, ok := value[name]; ok {
+				if err := property.validate(propertyValue, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if schema.Items != nil {
+			for idx, item := range value {
+				if err := schema.Items.validate(item, fmt.Sprintf("%s[%d]", path, idx)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonKind returns the JSON Schema type of a value decoded (with UseNumber) from JSON.
+func jsonKind(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// Compare lists the ways in which values matching the schema might not match other (e.g. the schema a NodeCG bundle
+// actually has for the replicant). An empty list means they're compatible.
+func (schema *Schema) Compare(other *Schema) (problems []string) {
+	schema.compare(other, "value", &problems)
+	return problems
+}
+
+func (schema *Schema) compare(other *Schema, path string, problems *[]string) {
+	for _, kind := range schema.Type {
+		if !other.Type.allows(kind) {
+			*problems = append(*problems, fmt.Sprintf("%s: BIGbot sends %s, but the bundle expects %s", path, schema.Type, other.Type))
+			return
+		}
+	}
+	if schema.Items != nil && other.Items != nil {
+		schema.Items.compare(other.Items, path+"[]", problems)
+	}
+	// Sort the properties, so that problems are always listed in the same order.
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if otherProperty, ok := other.Properties[name]; ok {
+			schema.Properties[name].compare(otherProperty, path+"."+name, problems)
+		} else if len(other.Properties) > 0 {
+			*problems = append(*problems, fmt.Sprintf("%s.%s: BIGbot sends it, but the bundle doesn't expect it", path, name))
+		}
+	}
+	for _, name := range other.Required {
+		if _, ok := schema.Properties[name]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%s.%s: the bundle requires it, but BIGbot doesn't send it", path, name))
+		} else if !slices.Contains(schema.Required, name) {
+			*problems = append(*problems, fmt.Sprintf("%s.%s: the bundle requires it, but BIGbot sometimes leaves it out", path, name))
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package avbridge
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// The longest a single ticker item can be.
+	maxTickerItemLength = 200
+	// The most items the ticker can hold.
+	maxTickerItems = 20
+
+	// How long the lower third is shown for, unless we're told otherwise.
+	defaultLowerThirdDuration = 10 * time.Second
+	// The longest the lower third can be shown for, in seconds.
+	maxLowerThirdDuration = 60
+)
+
+// The shortest the lower third can be shown for, in seconds (a variable, because Discord wants a pointer to it).
+var minLowerThirdDuration float64 = 3
+
+func (mod *AVBridge) discordCommandAVTicker(s *discordgo.Session, i *discordgo.InteractionCreate, option *discordgo.ApplicationCommandInteractionDataOption) (err error) {
+	var text string
+	if len(option.Options) > 0 {
+		text = strings.TrimSpace(option.Options[0].StringValue())
+	}
+
+	if option.Name != "clear" && text == "" {
+		_, err = helpers.DiscordInteractionFollowupMessage(s, i, fmt.Sprintf("⚠️ %s.", ErrTickerEmpty))
+		return err
+	}
+
+	mod.tickerMtx.Lock()
+	defer mod.tickerMtx.Unlock()
+	var ticker ngtbg.NodeCGReplicantDataTicker
+	switch option.Name {
+	case "set":
+		ticker.Items = []string{text}
+	case "add":
+		ticker, err = bridge_wan.ReplicantGet(ngtbg.ReplicantTicker)
+		if err != nil {
+			_, err = helpers.DiscordInteractionFollowupMessage(s, i, fmt.Sprintf("⚠️ unable to fetch the ticker: %s", err))
+			return err
+		}
+		if len(ticker.Items) >= maxTickerItems {
+			_, err = helpers.DiscordInteractionFollowupMessage(s, i, fmt.Sprintf("⚠️ %s.", ErrTickerFull))
+			return err
+		}
+		ticker.Items = append(ticker.Items, text)
+	case "clear":
+	default:
+		_, err = helpers.DiscordInteractionFollowupMessage(s, i, "😶 Unknown command...")
+		return err
+	}
+	if ticker.Items == nil {
+		// NodeCG expects a list, even if it's empty.
+		ticker.Items = []string{}
+	}
+
+	err = bridge_wan.ReplicantSet(ngtbg.ReplicantTicker, ticker)
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		mod.logger.Error("Unable to update ticker", slog.Any("error", err))
+		_, err = helpers.DiscordInteractionFollowupMessage(s, i, helpers.DiscordErrorContent(s, i, err))
+		return err
+	}
+	mod.logger.Info("Ticker updated", slog.String("user", i.Member.User.ID), slog.Int("items", len(ticker.Items)))
+
+	// Finally, confirm we did the thing.
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:         tickerContent(ticker.Items),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// tickerContent describes what's on the ticker.
+func tickerContent(items []string) string {
+	if len(items) == 0 {
+		return "Ticker cleared."
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Ticker updated. It now says (%d/%d):\n", len(items), maxTickerItems))
+	for _, item := range items {
+		sb.WriteString("- " + item + "\n")
+	}
+	return sb.String()
+}
+
+func (mod *AVBridge) discordCommandAVLowerThird(s *discordgo.Session, i *discordgo.InteractionCreate, option *discordgo.ApplicationCommandInteractionDataOption) (err error) {
+	lowerThird := ngtbg.NodeCGReplicantDataLowerThird{
+		Duration: int(defaultLowerThirdDuration.Seconds()),
+	}
+	for _, opt := range option.Options {
+		switch opt.Name {
+		case "name":
+			lowerThird.Name = strings.TrimSpace(opt.StringValue())
+		case "title":
+			lowerThird.Title = strings.TrimSpace(opt.StringValue())
+		case "duration":
+			lowerThird.Duration = int(opt.IntValue())
+		}
+	}
+
+	err = mod.showLowerThird(lowerThird)
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		mod.logger.Error("Unable to show lower third", slog.Any("error", err))
+		_, err = helpers.DiscordInteractionFollowupMessage(s, i, helpers.DiscordErrorContent(s, i, err))
+		return err
+	}
+	mod.logger.Info("Lower third shown", slog.String("user", i.Member.User.ID), slog.String("name", lowerThird.Name))
+
+	// Finally, confirm we did the thing.
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:         fmt.Sprintf("Showing **%s** in the lower third for %d seconds.", lowerThird.Name, lowerThird.Duration),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// showLowerThird shows the lower third, then hides it again once its duration is up (unless another lower third has
+// replaced it by then).
+func (mod *AVBridge) showLowerThird(lowerThird ngtbg.NodeCGReplicantDataLowerThird) (err error) {
+	mod.lowerThirdMtx.Lock()
+	defer mod.lowerThirdMtx.Unlock()
+	// First set the content...
+	err = bridge_wan.ReplicantSet(ngtbg.ReplicantLowerThirdData, lowerThird)
+	if err != nil {
+		return err
+	}
+	// ...then show it.
+	err = bridge_wan.ReplicantSet(ngtbg.ReplicantLowerThirdActive, true)
+	if err != nil {
+		return err
+	}
+
+	mod.lowerThirdShown++
+	shown := mod.lowerThirdShown
+	if mod.lowerThirdTimer != nil {
+		mod.lowerThirdTimer.Stop()
+	}
+	mod.lowerThirdTimer = time.AfterFunc(time.Duration(lowerThird.Duration)*time.Second, func() {
+		mod.hideLowerThird(shown)
+	})
+	return nil
+}
+
+// hideLowerThird hides the lower third, if it's still the one we were asked to hide.
+func (mod *AVBridge) hideLowerThird(shown int) {
+	mod.lowerThirdMtx.Lock()
+	defer mod.lowerThirdMtx.Unlock()
+	if shown != mod.lowerThirdShown {
+		// It's been replaced since, and that one will hide itself.
+		return
+	}
+	err := bridge_wan.ReplicantSet(ngtbg.ReplicantLowerThirdActive, false)
+	if err != nil {
+		mod.logger.Error("Unable to hide the lower third", slog.Any("error", err))
+	}
+}
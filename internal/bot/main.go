@@ -7,13 +7,20 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/thebiggame/bigbot/internal/avbridge"
 	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/checkin"
 	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/countdowns"
 	"github.com/thebiggame/bigbot/internal/helpers"
 	log "github.com/thebiggame/bigbot/internal/log"
 	"github.com/thebiggame/bigbot/internal/musicparty"
 	"github.com/thebiggame/bigbot/internal/notifications"
+	"github.com/thebiggame/bigbot/internal/polls"
+	"github.com/thebiggame/bigbot/internal/raffles"
 	"github.com/thebiggame/bigbot/internal/shoutproxy"
+	"github.com/thebiggame/bigbot/internal/storage"
 	"github.com/thebiggame/bigbot/internal/teamroles"
+	"github.com/thebiggame/bigbot/internal/tickets"
+	"github.com/thebiggame/bigbot/internal/tournaments"
 	"golang.org/x/sync/errgroup"
 	"log/slog"
 	"os"
@@ -29,6 +36,8 @@ type BotModule interface {
 	DiscordCommands() ([]*discordgo.ApplicationCommand, error)
 	DiscordHandleInteraction(session *discordgo.Session, interaction *discordgo.InteractionCreate) (handled bool, err error)
 	DiscordHandleMessage(session *discordgo.Session, message *discordgo.MessageCreate) (err error)
+	DiscordHandleMessageUpdate(session *discordgo.Session, message *discordgo.MessageUpdate) (err error)
+	DiscordHandleMessageDelete(session *discordgo.Session, message *discordgo.MessageDelete) (err error)
 }
 
 type BigBot struct {
@@ -36,6 +45,7 @@ type BigBot struct {
 	commands       []*discordgo.ApplicationCommand
 	logger         *slog.Logger
 	modules        []BotModule
+	storage        *storage.Store
 }
 
 func New() (*BigBot, error) {
@@ -45,10 +55,20 @@ func New() (*BigBot, error) {
 		return nil, fmt.Errorf("error creating Discord session: %w", err)
 	}
 
+	// open persistent storage
+	store, err := storage.Open(config.RuntimeConfig.Storage.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening storage: %w", err)
+	}
+
 	// create primary bot object
 	bot := &BigBot{
 		DiscordSession: DiscordSession,
 		logger:         log.Logger.With(slog.String("module", "main")),
+		storage:        store,
+	}
+	if !store.Persistent() {
+		bot.logger.Warn("No storage path set, state will be lost when BIGbot stops")
 	}
 	// load modules
 	bot.LoadModules()
@@ -58,9 +78,30 @@ func New() (*BigBot, error) {
 // LoadModules loads all available modules into BIGbot's structs.
 func (b *BigBot) LoadModules() *BigBot {
 	// teamRoles
-	module := teamroles.New(b.DiscordSession)
+	module := teamroles.New(b.DiscordSession, b.storage)
 	b.modules = append(b.modules, module)
 
+	// tournaments
+	modTournaments, err := tournaments.New(b.DiscordSession, b.storage, module)
+	if err != nil {
+		panic(err)
+	}
+	b.modules = append(b.modules, modTournaments)
+
+	// checkin
+	modCheckin, err := checkin.New(b.DiscordSession, b.storage)
+	if err != nil {
+		panic(err)
+	}
+	b.modules = append(b.modules, modCheckin)
+
+	// tickets
+	modTickets, err := tickets.New(b.DiscordSession, b.storage)
+	if err != nil {
+		panic(err)
+	}
+	b.modules = append(b.modules, modTickets)
+
 	// bridge
 	modBridge, err := bridge_wan.New()
 	if err != nil {
@@ -84,18 +125,38 @@ func (b *BigBot) LoadModules() *BigBot {
 	b.modules = append(b.modules, modNotify)
 
 	// MusicParty
-	modMusic, err := musicparty.New(b.DiscordSession)
+	modMusic, err := musicparty.New(b.DiscordSession, b.storage)
 	if err != nil {
 		panic(err)
 	}
 	b.modules = append(b.modules, modMusic)
 
 	// ShoutProxy
-	modShout, err := shoutproxy.New(b.DiscordSession)
+	modShout, err := shoutproxy.New(b.DiscordSession, b.storage)
 	if err != nil {
 		panic(err)
 	}
 	b.modules = append(b.modules, modShout)
+
+	// Polls
+	modPolls, err := polls.New(b.DiscordSession, b.storage)
+	if err != nil {
+		panic(err)
+	}
+	b.modules = append(b.modules, modPolls)
+
+	// Raffles
+	modRaffles, err := raffles.New(b.DiscordSession, b.storage, modCheckin)
+	if err != nil {
+		panic(err)
+	}
+	b.modules = append(b.modules, modRaffles)
+	// Countdowns
+	modCountdowns, err := countdowns.New(b.DiscordSession, b.storage)
+	if err != nil {
+		panic(err)
+	}
+	b.modules = append(b.modules, modCountdowns)
 	return b
 }
 
@@ -108,8 +169,12 @@ func (b *BigBot) handleDiscordCommand(s *discordgo.Session, i *discordgo.Interac
 				switch i.Type {
 				case discordgo.InteractionApplicationCommand:
 					b.logger.Debug("Module handled command", slog.String("module", reflect.TypeOf(m).Elem().Name()), slog.String("command", i.ApplicationCommandData().Name))
+				case discordgo.InteractionApplicationCommandAutocomplete:
+					b.logger.Debug("Module handled autocomplete", slog.String("module", reflect.TypeOf(m).Elem().Name()), slog.String("command", i.ApplicationCommandData().Name))
 				case discordgo.InteractionModalSubmit:
 					b.logger.Debug("Module handled modal.submit", slog.String("module", reflect.TypeOf(m).Elem().Name()), slog.String("modal_id", i.ModalSubmitData().CustomID))
+				case discordgo.InteractionMessageComponent:
+					b.logger.Debug("Module handled message.component", slog.String("module", reflect.TypeOf(m).Elem().Name()), slog.String("component_id", i.MessageComponentData().CustomID))
 				default:
 					b.logger.Warn("Module handled command of unexpected type", slog.String("module", reflect.TypeOf(m).Elem().Name()))
 				}
@@ -121,20 +186,7 @@ func (b *BigBot) handleDiscordCommand(s *discordgo.Session, i *discordgo.Interac
 	if err := g.Wait(); err != nil {
 		// Error occurred.
 		b.logger.Error("error handling discord command", slog.String("discord_command", fmt.Sprint(i.Interaction.Data)), slog.Any("error", err))
-		// Figure out how to report it.
-		var content string
-		if IsCrew, errHlpr := helpers.UserIsCrew(s, i.GuildID, i.Member.User); errHlpr == nil && IsCrew {
-			content = fmt.Sprintf("🚫 **An error occurred while processing your command:**\n```%s```", err)
-		} else {
-			content = "🚫 **An error occurred while processing your command. Please contact a member of theBIGGAME Crew.**"
-		}
-		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: content,
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		err = helpers.DiscordInteractionEphemeralResponse(s, i, helpers.DiscordErrorContent(s, i, err))
 		if err != nil {
 			b.logger.Error("Error returning log to client for slash command", slog.Any("error", err))
 		}
@@ -159,6 +211,36 @@ func (b *BigBot) handleDiscordMessage(s *discordgo.Session, msg *discordgo.Messa
 	}
 }
 
+func (b *BigBot) handleDiscordMessageUpdate(s *discordgo.Session, msg *discordgo.MessageUpdate) {
+	// Ignore all messages created by the bot itself (updates don't always carry the author, e.g. when embeds load).
+	if msg.Author != nil && msg.Author.ID == s.State.User.ID {
+		return
+	}
+	g := new(errgroup.Group)
+	for _, m := range b.modules {
+		g.Go(func() error {
+			return m.DiscordHandleMessageUpdate(s, msg)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		// Error occurred.
+		b.logger.Error("error handling discord message update", slog.String("discord_msg_id", fmt.Sprint(msg.Message.ID)), slog.Any("error", err))
+	}
+}
+
+func (b *BigBot) handleDiscordMessageDelete(s *discordgo.Session, msg *discordgo.MessageDelete) {
+	g := new(errgroup.Group)
+	for _, m := range b.modules {
+		g.Go(func() error {
+			return m.DiscordHandleMessageDelete(s, msg)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		// Error occurred.
+		b.logger.Error("error handling discord message delete", slog.String("discord_msg_id", fmt.Sprint(msg.Message.ID)), slog.Any("error", err))
+	}
+}
+
 func (b *BigBot) registerCommands() (err error) {
 	// Fetch all currently registered commands on the server.
 	// this is done to avoid overwrites / deduplication.
@@ -231,8 +313,21 @@ func (b *BigBot) Run() (err error) {
 		b.logger.Info(fmt.Sprintf("Logged in as: %v#%v", s.State.User.Username, s.State.User.Discriminator))
 	})
 
+	// Once our guild is available, populate the State cache with its full member list.
+	// Modules rely on this (rather than hitting the API) when enumerating members.
+	b.DiscordSession.AddHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		if g.ID != config.RuntimeConfig.Discord.GuildID {
+			return
+		}
+		err := s.RequestGuildMembers(g.ID, "", 0, "", false)
+		if err != nil {
+			b.logger.Error("error requesting guild members", slog.Any("error", err))
+		}
+	})
+
 	// Set appropriate intents.
-	b.DiscordSession.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent)
+	// GuildMembers is a privileged intent, and must also be enabled for the application on the Discord developers site.
+	b.DiscordSession.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent | discordgo.IntentsGuildMembers)
 
 	err = b.DiscordSession.Open()
 	if err != nil {
@@ -269,6 +364,8 @@ func (b *BigBot) Run() (err error) {
 
 	b.DiscordSession.AddHandler(b.handleDiscordCommand)
 	b.DiscordSession.AddHandler(b.handleDiscordMessage)
+	b.DiscordSession.AddHandler(b.handleDiscordMessageUpdate)
+	b.DiscordSession.AddHandler(b.handleDiscordMessageDelete)
 
 	guilds, err := b.DiscordSession.UserGuilds(100, "", "", false)
 
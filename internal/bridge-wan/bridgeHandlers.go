@@ -10,11 +10,20 @@ import (
 )
 
 func (bridge *BridgeWAN) BrReplicantGet(bundle, replicant string, target any) (err error) {
+	data, err := bridge.brReplicantGetRaw(bundle, replicant)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &target)
+}
+
+// brReplicantGetRaw fetches the current value of a replicant, as JSON.
+func (bridge *BridgeWAN) brReplicantGetRaw(bundle, replicant string) (data []byte, err error) {
 	if EventBridge == nil {
-		return errors.New("EventBridge not initialised")
+		return nil, errors.New("EventBridge not initialised")
 	}
 	if EventBridge.wsConn == nil {
-		return errors.New("EventBridge not connected")
+		return nil, errors.New("EventBridge not connected")
 	}
 
 	// Get an idempotency key for this request
@@ -38,11 +47,11 @@ func (bridge *BridgeWAN) BrReplicantGet(bundle, replicant string, target any) (e
 	}
 	msg, err := proto2.Marshal(event)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = EventBridge.wsConn.WriteMessage(websocket.BinaryMessage, msg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Wait for the response or timeout
@@ -50,20 +59,18 @@ func (bridge *BridgeWAN) BrReplicantGet(bundle, replicant string, target any) (e
 	case rpcResponse := <-responseCh:
 		// Handle server-side errors
 		if rpcResponse.StatusCode != 0 {
-			return errors.New(rpcResponse.ErrorMessage)
+			return nil, errors.New(rpcResponse.ErrorMessage)
 		}
 		if rpcResponse.GetNcgReplicantGet() == nil {
-			return errors.New("RPC response not of type NcgReplicantGet")
+			return nil, errors.New("RPC response not of type NcgReplicantGet")
 		}
-		err = json.Unmarshal(rpcResponse.GetNcgReplicantGet().GetReplicant(), &target)
-
-		return err
+		return rpcResponse.GetNcgReplicantGet().GetReplicant(), nil
 	case <-time.After(time.Second * 10):
 		// Clean up the channel on timeout
 		bridge.wsResponseMtx.Lock()
 		delete(bridge.wsResponseCh, requestID)
 		bridge.wsResponseMtx.Unlock()
-		return errors.New("RPC call timed out")
+		return nil, errors.New("RPC call timed out")
 	}
 }
 
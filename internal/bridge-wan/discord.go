@@ -12,6 +12,14 @@ func (mod *BridgeWAN) DiscordHandleMessage(session *discordgo.Session, message *
 	return nil
 }
 
+func (mod *BridgeWAN) DiscordHandleMessageUpdate(session *discordgo.Session, message *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *BridgeWAN) DiscordHandleMessageDelete(session *discordgo.Session, message *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
 func (mod *BridgeWAN) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
 	return false, nil
 }
@@ -97,6 +97,8 @@ func (bridge *BridgeWAN) handleAuthenticate(m *protodef.Authenticate, c *websock
 	// Set this connection as the valid connection.
 	bridge.wsConn = c
 	writeWelcome(c)
+	// Catch up on anything we missed while the bridge was away.
+	go bridge.bridgeConnected()
 	return nil
 }
 
@@ -164,5 +166,8 @@ func (bridge *BridgeWAN) wsHandle(w http.ResponseWriter, r *http.Request) {
 
 	}
 	logger.Info("Ended client session", slog.String("address", c.RemoteAddr().String()))
-	c = nil
+	if bridge.wsConn == c {
+		// The bridge has gone away (rather than being superseded by another connection).
+		bridge.wsConn = nil
+	}
 }
@@ -34,6 +34,11 @@ type BridgeWAN struct {
 	// Stores response handlers for given request IDs. (the mutex MUST be held to interact with this map)
 	wsResponseCh  map[string]chan *protodef.RPCResponse
 	wsResponseMtx sync.Mutex
+
+	// Messages waiting for the event bridge to reconnect (see BrMessageSendQueued). The mutex MUST be held to
+	// interact with the queue.
+	queue    []queuedMessage
+	queueMtx sync.Mutex
 }
 
 // logger stores the module's logger instance.
@@ -58,6 +63,12 @@ func BridgeIsAvailable() (up bool) {
 	return false
 }
 
+// HandleFunc registers a handler on the BIGbridge HTTP server, for modules that want to serve pages of their own.
+// Pages are only served while the bridge is enabled.
+func HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	http.HandleFunc(pattern, handler)
+}
+
 func New() (bridge *BridgeWAN, err error) {
 	bridge = &BridgeWAN{
 		httpServer:   &http.Server{Addr: config.RuntimeConfig.Bridge.Address},
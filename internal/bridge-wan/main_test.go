@@ -0,0 +1,77 @@
+package bridge_wan
+
+import (
+	"github.com/thebiggame/bigbot/internal/config"
+	"testing"
+	"time"
+)
+
+func newTestBridge(t *testing.T) *BridgeWAN {
+	t.Helper()
+	config.RuntimeConfig.Bridge.Enabled = true
+	config.RuntimeConfig.Bridge.Queue.Size = 3
+	config.RuntimeConfig.Bridge.Queue.TTL = time.Minute
+	t.Cleanup(func() { config.RuntimeConfig.Bridge.Enabled = false })
+	bridge, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bridge
+}
+
+func TestQueueWhileDisconnected(t *testing.T) {
+	bridge := newTestBridge(t)
+	for _, shout := range []string{"one", "two", "three", "four"} {
+		if err := MessageSendQueued("thebiggame", "shoutbox:new-discord", shout); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the most recent messages are kept.
+	if depth := bridge.QueueDepth(); depth != 3 {
+		t.Fatalf("Expected 3 queued messages, got %d", depth)
+	}
+	if bridge.queue[0].value != "two" || bridge.queue[2].value != "four" {
+		t.Errorf("Expected the oldest message to be dropped, got %+v", bridge.queue)
+	}
+}
+
+func TestQueueTTL(t *testing.T) {
+	bridge := newTestBridge(t)
+	now := time.Now()
+	bridge.enqueue(queuedMessage{channel: "old", queuedAt: now.Add(-2 * time.Minute)})
+	bridge.enqueue(queuedMessage{channel: "new", queuedAt: now})
+	if depth := bridge.QueueDepth(); depth != 1 || bridge.queue[0].channel != "new" {
+		t.Errorf("Expected stale messages to be dropped, got %+v", bridge.queue)
+	}
+}
+
+func TestQueueDisabled(t *testing.T) {
+	bridge := newTestBridge(t)
+	config.RuntimeConfig.Bridge.Queue.Size = 0
+	if err := bridge.BrMessageSendQueued("thebiggame", "shoutbox:new-discord", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if depth := bridge.QueueDepth(); depth != 0 {
+		t.Errorf("Messages shouldn't be queued when queueing is disabled, got %d", depth)
+	}
+
+	config.RuntimeConfig.Bridge.Queue.Size = 3
+	config.RuntimeConfig.Bridge.Enabled = false
+	if err := bridge.BrMessageSendQueued("thebiggame", "shoutbox:new-discord", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if depth := bridge.QueueDepth(); depth != 0 {
+		t.Errorf("Messages shouldn't be queued when the bridge is disabled, got %d", depth)
+	}
+}
+
+func TestFlushQueueWhileDisconnected(t *testing.T) {
+	bridge := newTestBridge(t)
+	bridge.enqueue(queuedMessage{channel: "one", queuedAt: time.Now()})
+	bridge.enqueue(queuedMessage{channel: "two", queuedAt: time.Now()})
+	// Sending fails without a connection, so everything should stay queued (in order).
+	bridge.flushQueue()
+	if depth := bridge.QueueDepth(); depth != 2 || bridge.queue[0].channel != "one" {
+		t.Errorf("Unsent messages should stay queued, got %+v", bridge.queue)
+	}
+}
@@ -0,0 +1,136 @@
+package bridge_wan
+
+import (
+	"errors"
+	"github.com/thebiggame/bigbot/internal/config"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// This file handles holding on to best-effort NodeCG messages (like shouts) while the event bridge is disconnected,
+// and catching NodeCG up once it's back.
+
+// connectHandlers are called each time the event bridge (re)connects, once any queued messages have been sent.
+var (
+	connectHandlers    []func()
+	connectHandlersMtx sync.Mutex
+)
+
+// OnConnect registers a function to be called each time the event bridge (re)connects. Modules use this to send
+// NodeCG their full state, in case it was lost while the bridge was away (e.g. NodeCG restarted).
+func OnConnect(handler func()) {
+	connectHandlersMtx.Lock()
+	defer connectHandlersMtx.Unlock()
+	connectHandlers = append(connectHandlers, handler)
+}
+
+// bridgeConnected catches up with anything that was missed while the event bridge was away.
+func (bridge *BridgeWAN) bridgeConnected() {
+	bridge.flushQueue()
+	connectHandlersMtx.Lock()
+	handlers := connectHandlers
+	connectHandlersMtx.Unlock()
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+// queuedMessage is a NodeCG message waiting for the event bridge to reconnect.
+type queuedMessage struct {
+	bundle   string
+	channel  string
+	value    any
+	queuedAt time.Time
+}
+
+// BrMessageSendQueued sends a NodeCG message like BrMessageSend, but if the event bridge isn't connected (or sending
+// fails), the message is held and sent once it reconnects. Only the most recent messages are held, and messages that
+// have been waiting too long are dropped (see config.Bridge.Queue).
+func (bridge *BridgeWAN) BrMessageSendQueued(bundle, channel string, value any) (err error) {
+	if bridge.EventAvailable() {
+		err = bridge.BrMessageSend(bundle, channel, value)
+		if err == nil {
+			return nil
+		}
+		logger.Warn("Unable to send message, queueing it", slog.String("channel", channel), slog.Any("error", err))
+	}
+	bridge.enqueue(queuedMessage{
+		bundle:   bundle,
+		channel:  channel,
+		value:    value,
+		queuedAt: time.Now(),
+	})
+	return nil
+}
+
+// MessageSendQueued sends a NodeCG message via the event bridge, queueing it if the bridge isn't connected.
+// See BrMessageSendQueued.
+func MessageSendQueued(bundle, channel string, value any) (err error) {
+	if EventBridge == nil {
+		return errors.New("EventBridge not initialised")
+	}
+	return EventBridge.BrMessageSendQueued(bundle, channel, value)
+}
+
+// QueueDepth returns how many messages are waiting for the event bridge to reconnect.
+func (bridge *BridgeWAN) QueueDepth() int {
+	bridge.queueMtx.Lock()
+	defer bridge.queueMtx.Unlock()
+	bridge.pruneQueue(time.Now())
+	return len(bridge.queue)
+}
+
+// enqueue adds a message to the queue, dropping the oldest messages if it's full.
+func (bridge *BridgeWAN) enqueue(msg queuedMessage) {
+	size := config.RuntimeConfig.Bridge.Queue.Size
+	if size <= 0 || !config.RuntimeConfig.Bridge.Enabled {
+		// There's no point holding on to messages for a bridge that will never connect.
+		logger.Debug("Message queueing disabled, dropping message", slog.String("channel", msg.channel))
+		return
+	}
+	bridge.queueMtx.Lock()
+	defer bridge.queueMtx.Unlock()
+	bridge.pruneQueue(msg.queuedAt)
+	bridge.queue = append(bridge.queue, msg)
+	if dropped := len(bridge.queue) - size; dropped > 0 {
+		logger.Debug("Message queue full, dropping oldest messages", slog.Int("dropped", dropped))
+		bridge.queue = bridge.queue[dropped:]
+	}
+}
+
+// pruneQueue drops messages that have been waiting too long to be worth sending. bridge.queueMtx must be held.
+func (bridge *BridgeWAN) pruneQueue(now time.Time) {
+	ttl := config.RuntimeConfig.Bridge.Queue.TTL
+	if ttl <= 0 {
+		return
+	}
+	for len(bridge.queue) > 0 && now.Sub(bridge.queue[0].queuedAt) >= ttl {
+		bridge.queue = bridge.queue[1:]
+	}
+}
+
+// flushQueue sends any messages that were queued while the event bridge was disconnected, oldest first.
+// If sending fails, the remaining messages stay queued for next time.
+func (bridge *BridgeWAN) flushQueue() {
+	bridge.queueMtx.Lock()
+	bridge.pruneQueue(time.Now())
+	pending := bridge.queue
+	bridge.queue = nil
+	bridge.queueMtx.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	logger.Info("Sending queued messages", slog.Int("count", len(pending)))
+	for idx, msg := range pending {
+		if err := bridge.BrMessageSend(msg.bundle, msg.channel, msg.value); err != nil {
+			logger.Warn("Unable to send queued messages", slog.Int("remaining", len(pending)-idx), slog.Any("error", err))
+			bridge.queueMtx.Lock()
+			// Anything queued in the meantime is newer, so goes after what we couldn't send.
+			bridge.queue = append(pending[idx:], bridge.queue...)
+			bridge.queueMtx.Unlock()
+			return
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package bridge_wan
+
+import (
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	"github.com/thebiggame/bigbot/internal/config"
+)
+
+// replicantBundle returns the bundle a replicant belongs to.
+func replicantBundle(bundle string) string {
+	if bundle == "" {
+		return config.RuntimeConfig.AV.NodeCG.BundleName
+	}
+	return bundle
+}
+
+// ReplicantSet sets a replicant in NodeCG, once value has been checked against the replicant's schema.
+func ReplicantSet[T any](replicant ngtbg.Replicant[T], value T) error {
+	if err := replicant.Validate(value); err != nil {
+		return err
+	}
+	return EventBridge.BrReplicantSet(replicantBundle(replicant.Bundle()), replicant.Name(), value)
+}
+
+// ReplicantGet fetches the current value of a replicant from NodeCG.
+func ReplicantGet[T any](replicant ngtbg.Replicant[T]) (value T, err error) {
+	data, err := EventBridge.brReplicantGetRaw(replicantBundle(replicant.Bundle()), replicant.Name())
+	if err != nil {
+		return value, err
+	}
+	return replicant.Decode(data)
+}
@@ -0,0 +1,191 @@
+package checkin
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+)
+
+// This file handles the Crew-only check-in administration commands.
+
+var adminCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:                     "checkins",
+		Description:              "🎟️🛠️ Administer event check-in (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "admin",
+				Description: "🎟️🛠️ Check-in administration.",
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "import",
+						Description: "📥 Import the seating plan or attendee list (CSV or JSON).",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "kind",
+								Description: "What the file contains.",
+								Required:    true,
+								Choices: []*discordgo.ApplicationCommandOptionChoice{
+									{Name: "Seating plan", Value: "seats"},
+									{Name: "Attendees", Value: "attendees"},
+								},
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionAttachment,
+								Name:        "file",
+								Description: "The file to import.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "status",
+						Description: "📊 See how many attendees have checked in.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+					},
+					{
+						Name:        "undo",
+						Description: "↩️ Undo a check-in, so the ticket can be used again.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "ticket",
+								Description: "The ticket code.",
+								Required:    true,
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func (mod *Checkin) discordHandleAdminCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 || options[0].Name != "admin" || len(options[0].Options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+	subcommand := options[0].Options[0]
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subcommand.Options))
+	for _, opt := range subcommand.Options {
+		optionMap[opt.Name] = opt
+	}
+
+	// Imports can take a while, so let the client know we're working on it.
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+
+	var content string
+	switch subcommand.Name {
+	case "import":
+		attachment := i.ApplicationCommandData().Resolved.Attachments[optionMap["file"].Value.(string)]
+		content, err = mod.adminImport(optionMap["kind"].StringValue(), attachment)
+	case "status":
+		content, err = mod.adminStatus()
+	case "undo":
+		content, err = mod.adminUndo(s, i.GuildID, optionMap["ticket"].StringValue())
+	default:
+		content = "😶 Please use a sub-command."
+	}
+	if err != nil {
+		content, err = errorContent(err)
+	}
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		logger.Error("Check-in administration failed", slog.String("action", subcommand.Name), slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		content = helpers.DiscordErrorContent(s, i, err)
+	} else {
+		logger.Info("Check-in administration performed", slog.String("action", subcommand.Name), slog.String("user", i.Member.User.Username))
+	}
+	if _, err = helpers.DiscordInteractionFollowupMessage(s, i, content); err != nil {
+		logger.Error("Unable to send check-in administration response", slog.Any("error", err))
+	}
+	return nil
+}
+
+func (mod *Checkin) adminImport(kind string, attachment *discordgo.MessageAttachment) (content string, err error) {
+	data, err := fetchAttachment(attachment)
+	if err != nil {
+		return "", err
+	}
+	records, err := importRecords(attachment.Filename, data)
+	if err != nil {
+		return "", err
+	}
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	switch kind {
+	case "seats":
+		seats, err := parseSeats(records)
+		if err != nil {
+			return "", err
+		}
+		return mod.importSeats(seats)
+	case "attendees":
+		attendees, err := parseAttendees(records)
+		if err != nil {
+			return "", err
+		}
+		return mod.importAttendees(attendees)
+	default:
+		return fmt.Sprintf("⚠️ Unknown import kind %s.", kind), nil
+	}
+}
+
+func (mod *Checkin) adminStatus() (content string, err error) {
+	var total, checkedIn int
+	for _, key := range mod.store.Keys(attendeesBucket) {
+		var a attendee
+		if err = mod.store.Get(attendeesBucket, key, &a); err != nil {
+			return "", err
+		}
+		total++
+		if a.DiscordID != "" {
+			checkedIn++
+		}
+	}
+	seats := len(mod.store.Keys(seatsBucket))
+	return fmt.Sprintf("📊 %d of %d %s checked in. The seating plan has %d %s.", checkedIn, total, helpers.Pluralise(total, "attendee", "attendees"), seats, helpers.Pluralise(seats, "seat", "seats")), nil
+}
+
+func (mod *Checkin) adminUndo(s *discordgo.Session, guild string, ticket string) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	a, err := mod.getAttendee(ticket)
+	if isNotFound(err) {
+		return fmt.Sprintf("⚠️ %s.", ErrUnknownTicket), nil
+	} else if err != nil {
+		return "", err
+	}
+	if a.DiscordID == "" {
+		return fmt.Sprintf("🤷 Ticket %s hasn't been used to check in.", a.Ticket), nil
+	}
+	userID := a.DiscordID
+	if err = mod.unlinkUser(a); err != nil {
+		return "", err
+	}
+	if roleID := config.RuntimeConfig.Checkin.AttendeeRoleID; roleID != "" {
+		if err = s.GuildMemberRoleRemove(guild, userID, roleID); err != nil {
+			// They may have left the server; the check-in is undone either way.
+			logger.Warn("Failed to remove attendee role", slog.String("user", userID), slog.Any("error", err))
+		}
+	}
+	return fmt.Sprintf("↩️ Undid <@%s>'s check-in with ticket %s.", userID, a.Ticket), nil
+}
+
+var defaultCrewCommandPermissions int64 = discordgo.PermissionAdministrator
+var defaultCrewCommandDMPermissions = false
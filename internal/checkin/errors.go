@@ -0,0 +1,11 @@
+package checkin
+
+import "errors"
+
+var ErrUnknownTicket = errors.New("That ticket code wasn't recognised. Please check it and try again")
+var ErrTicketAlreadyUsed = errors.New("That ticket has already been used to check in. If that wasn't you, please contact a member of Crew")
+var ErrAlreadyCheckedIn = errors.New("You've already checked in with a different ticket. Please contact a member of Crew if that's wrong")
+var ErrTooManyAttempts = errors.New("Too many wrong ticket codes. Please wait a few minutes, or ask a member of Crew for help")
+var ErrImportFormat = errors.New("Import files must be .csv or .json")
+var ErrImportInvalid = errors.New("The import file couldn't be read")
+var ErrImportTooLarge = errors.New("The import file is too large")
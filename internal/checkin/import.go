@@ -0,0 +1,169 @@
+package checkin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// This file handles importing the seating plan and attendee list.
+// Both can be given as CSV (with a header row) or as a JSON array of objects, using the same field names.
+
+const (
+	// The largest import file we'll accept.
+	maxImportSize = 5 << 20
+)
+
+// importClient fetches import files from Discord's CDN.
+var importClient = &http.Client{Timeout: 30 * time.Second}
+
+// fetchAttachment downloads an attachment that was given to a command.
+func fetchAttachment(attachment *discordgo.MessageAttachment) ([]byte, error) {
+	if attachment.Size > maxImportSize {
+		return nil, fmt.Errorf("%w (the limit is %d MB)", ErrImportTooLarge, maxImportSize>>20)
+	}
+	resp, err := importClient.Get(attachment.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching attachment: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxImportSize))
+}
+
+// importRecords parses an import file into a list of records (field name to value).
+// Field names are lowercased. The format is decided by the file name's extension.
+func importRecords(fileName string, data []byte) (records []map[string]string, err error) {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".csv":
+		return importCSV(data)
+	case ".json":
+		return importJSON(data)
+	default:
+		return nil, ErrImportFormat
+	}
+}
+
+func importCSV(data []byte) (records []map[string]string, err error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImportInvalid, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	for idx := range header {
+		header[idx] = strings.ToLower(strings.TrimSpace(header[idx]))
+	}
+	for _, row := range rows[1:] {
+		record := make(map[string]string, len(header))
+		for idx, field := range header {
+			record[field] = strings.TrimSpace(row[idx])
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func importJSON(data []byte) (records []map[string]string, err error) {
+	var raw []map[string]any
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImportInvalid, err)
+	}
+	for _, obj := range raw {
+		record := make(map[string]string, len(obj))
+		for field, value := range obj {
+			switch v := value.(type) {
+			case nil:
+				continue
+			case string:
+				record[strings.ToLower(field)] = strings.TrimSpace(v)
+			default:
+				// Numbers (and the like) are fine as seat labels or ticket codes.
+				record[strings.ToLower(field)] = fmt.Sprint(v)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseSeats turns imported records into seats. Every record needs a "seat" field.
+func parseSeats(records []map[string]string) (seats []seat, err error) {
+	for idx, record := range records {
+		st := seat{
+			ID:          seatKey(record["seat"]),
+			Zone:        record["zone"],
+			Description: record["description"],
+		}
+		if st.ID == "" {
+			return nil, fmt.Errorf("%w: record %d has no seat", ErrImportInvalid, idx+1)
+		}
+		seats = append(seats, st)
+	}
+	return seats, nil
+}
+
+// parseAttendees turns imported records into attendees. Every record needs a "ticket" field.
+func parseAttendees(records []map[string]string) (attendees []attendee, err error) {
+	for idx, record := range records {
+		a := attendee{
+			Ticket: ticketKey(record["ticket"]),
+			Name:   record["name"],
+			Seat:   seatKey(record["seat"]),
+		}
+		if a.Ticket == "" {
+			return nil, fmt.Errorf("%w: record %d has no ticket", ErrImportInvalid, idx+1)
+		}
+		attendees = append(attendees, a)
+	}
+	return attendees, nil
+}
+
+// importSeats adds (or updates) seats in the seating plan.
+func (mod *Checkin) importSeats(seats []seat) (content string, err error) {
+	for _, st := range seats {
+		if err = mod.saveSeat(st); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("🪑 Imported %d %s.", len(seats), helpers.Pluralise(len(seats), "seat", "seats")), nil
+}
+
+// importAttendees adds (or updates) attendees, keeping track of anybody who has already checked in.
+func (mod *Checkin) importAttendees(attendees []attendee) (content string, err error) {
+	var unknownSeats int
+	for _, a := range attendees {
+		existing, err := mod.getAttendee(a.Ticket)
+		if err == nil {
+			a.DiscordID = existing.DiscordID
+			a.CheckedInAt = existing.CheckedInAt
+		} else if !isNotFound(err) {
+			return "", err
+		}
+		if a.Seat != "" && !mod.store.Has(seatsBucket, a.Seat) {
+			unknownSeats++
+		}
+		if err = mod.saveAttendee(a); err != nil {
+			return "", err
+		}
+	}
+	content = fmt.Sprintf("🎟️ Imported %d %s.", len(attendees), helpers.Pluralise(len(attendees), "attendee", "attendees"))
+	if unknownSeats > 0 {
+		content += fmt.Sprintf(" ⚠️ %d %s allocated a seat that isn't in the seating plan.", unknownSeats, helpers.Pluralise(unknownSeats, "was", "were"))
+	}
+	return content, nil
+}
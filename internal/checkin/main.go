@@ -0,0 +1,309 @@
+package checkin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How many wrong ticket codes somebody can enter before they have to wait.
+	maxFailedCheckins = 5
+	// How long wrong ticket codes count against somebody.
+	failedCheckinWindow = 10 * time.Minute
+)
+
+// logger stores the module's logger instance.
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+type Checkin struct {
+	discord *discordgo.Session
+
+	// Persistent storage for the seating plan and attendees.
+	store *storage.Store
+
+	// Held while checking somebody in, so a ticket can't be claimed twice at once.
+	mtx sync.Mutex
+
+	// When each user last entered a wrong ticket code, to stop people guessing.
+	failures    map[string][]time.Time
+	failuresMtx sync.Mutex
+}
+
+var commands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "checkin",
+		Description: "🎟️ Check in to the event with your ticket.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "ticket",
+				Description: "Your ticket code.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:                     "whereis",
+		Description:              "🗺️ Find out where somebody is sitting (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "Who you're looking for.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:                     "seat",
+		Description:              "🪑 Find out who is sitting in a seat (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "seat",
+				Description: "The seat, e.g. B12.",
+				Required:    true,
+			},
+		},
+	},
+}
+
+func New(discord *discordgo.Session, store *storage.Store) (mod *Checkin, err error) {
+	return &Checkin{
+		discord:  discord,
+		store:    store,
+		failures: make(map[string][]time.Time),
+	}, nil
+}
+
+func (mod *Checkin) SetLogger(log *slog.Logger) {
+	logger = log
+}
+
+func (mod *Checkin) DiscordCommands() ([]*discordgo.ApplicationCommand, error) {
+	return append(commands, adminCommands...), nil
+}
+
+func (mod *Checkin) Start(ctx context.Context) (err error) {
+	// This module simply registers handlers, and does not need to run continuously (so we don't need the context)
+	return ctx.Err()
+}
+
+func (mod *Checkin) DiscordHandleMessage(_ *discordgo.Session, _ *discordgo.MessageCreate) (err error) {
+	return nil
+}
+
+func (mod *Checkin) DiscordHandleMessageUpdate(_ *discordgo.Session, _ *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *Checkin) DiscordHandleMessageDelete(_ *discordgo.Session, _ *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
+func (mod *Checkin) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return false, nil
+	}
+	name := i.ApplicationCommandData().Name
+	switch name {
+	case "checkin", "whereis", "seat", "checkins":
+	default:
+		return false, nil
+	}
+	if i.Interaction.GuildID == "" {
+		return true, helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	options := i.ApplicationCommandData().Options
+
+	var content string
+	switch name {
+	case "checkin":
+		content, err = mod.checkin(s, i.GuildID, i.Member.User, options[0].StringValue())
+	case "whereis", "seat":
+		if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+			return true, err
+		}
+		if name == "whereis" {
+			content, err = mod.whereIs(options[0].UserValue(s))
+		} else {
+			content, err = mod.whoIsIn(options[0].StringValue())
+		}
+		if err != nil {
+			return true, err
+		}
+	case "checkins":
+		return true, mod.discordHandleAdminCommand(s, i)
+	}
+	if err != nil {
+		return true, err
+	}
+	return true, s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// recordFailure notes that the given user entered a wrong ticket code.
+func (mod *Checkin) recordFailure(userID string) {
+	mod.failuresMtx.Lock()
+	defer mod.failuresMtx.Unlock()
+	mod.failures[userID] = append(mod.failures[userID], time.Now())
+}
+
+// tooManyFailures returns whether the given user has entered too many wrong ticket codes recently.
+func (mod *Checkin) tooManyFailures(userID string) bool {
+	mod.failuresMtx.Lock()
+	defer mod.failuresMtx.Unlock()
+	var recent []time.Time
+	for _, t := range mod.failures[userID] {
+		if time.Since(t) < failedCheckinWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		delete(mod.failures, userID)
+	} else {
+		mod.failures[userID] = recent
+	}
+	return len(recent) >= maxFailedCheckins
+}
+
+// checkin links the given user to the attendee with the given ticket, and gives them the attendee role.
+func (mod *Checkin) checkin(s *discordgo.Session, guild string, u *discordgo.User, ticket string) (content string, err error) {
+	if mod.tooManyFailures(u.ID) {
+		return fmt.Sprintf("⏳ %s.", ErrTooManyAttempts), nil
+	}
+
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	a, err := mod.getAttendee(ticket)
+	if isNotFound(err) {
+		mod.recordFailure(u.ID)
+		logger.Info("Unknown ticket used to check in", slog.String("user", u.Username))
+		return fmt.Sprintf("⚠️ %s.", ErrUnknownTicket), nil
+	} else if err != nil {
+		return "", err
+	}
+	if a.DiscordID != "" && a.DiscordID != u.ID {
+		logger.Warn("Ticket already used to check in", slog.String("user", u.Username), slog.String("ticket", a.Ticket))
+		return fmt.Sprintf("⚠️ %s.", ErrTicketAlreadyUsed), nil
+	}
+	if existing, err := mod.getAttendeeByUser(u.ID); err == nil && ticketKey(existing.Ticket) != ticketKey(a.Ticket) {
+		return fmt.Sprintf("⚠️ %s.", ErrAlreadyCheckedIn), nil
+	} else if err != nil && !isNotFound(err) {
+		return "", err
+	}
+
+	if a.DiscordID == "" {
+		if err = mod.linkUser(a, u.ID); err != nil {
+			return "", err
+		}
+		logger.Info("Attendee checked in", slog.String("user", u.Username), slog.String("ticket", a.Ticket), slog.String("seat", a.Seat))
+	}
+	if roleID := config.RuntimeConfig.Checkin.AttendeeRoleID; roleID != "" {
+		if err = s.GuildMemberRoleAdd(guild, u.ID, roleID); err != nil {
+			return "", err
+		}
+	}
+
+	var sb strings.Builder
+	if a.Name != "" {
+		sb.WriteString(fmt.Sprintf("✅ Welcome to the event, %s!", a.Name))
+	} else {
+		sb.WriteString("✅ Welcome to the event!")
+	}
+	if a.Seat != "" {
+		sb.WriteString(fmt.Sprintf(" You're in seat **%s**", a.Seat))
+		if st, err := mod.getSeat(a.Seat); err == nil && st.Zone != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", st.Zone))
+		}
+		sb.WriteString(".")
+	}
+	return sb.String(), nil
+}
+
+// whereIs describes where the given user is sitting.
+func (mod *Checkin) whereIs(u *discordgo.User) (content string, err error) {
+	a, err := mod.getAttendeeByUser(u.ID)
+	if isNotFound(err) {
+		return fmt.Sprintf("🤷 %s hasn't checked in.", u.Mention()), nil
+	} else if err != nil {
+		return "", err
+	}
+	if a.Seat == "" {
+		return fmt.Sprintf("🤷 %s (%s, ticket %s) hasn't been allocated a seat.", u.Mention(), a.Name, a.Ticket), nil
+	}
+	return fmt.Sprintf("🗺️ %s (%s, ticket %s) is in %s", u.Mention(), a.Name, a.Ticket, mod.describeSeat(a.Seat)), nil
+}
+
+// whoIsIn describes who is sitting in the given seat.
+func (mod *Checkin) whoIsIn(id string) (content string, err error) {
+	attendees, err := mod.getAttendeesBySeat(id)
+	if err != nil {
+		return "", err
+	}
+	_, seatErr := mod.getSeat(id)
+	if len(attendees) == 0 {
+		if isNotFound(seatErr) {
+			return fmt.Sprintf("🤷 There is no seat %s.", seatKey(id)), nil
+		}
+		return fmt.Sprintf("🪑 Nobody has been allocated %s.", mod.describeSeat(id)), nil
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🪑 %s:\n", mod.describeSeat(id)))
+	for _, a := range attendees {
+		if a.DiscordID != "" {
+			sb.WriteString(fmt.Sprintf("- %s (ticket %s) - checked in as <@%s> <t:%d:R>\n", a.Name, a.Ticket, a.DiscordID, a.CheckedInAt.Unix()))
+		} else {
+			sb.WriteString(fmt.Sprintf("- %s (ticket %s) - not checked in\n", a.Name, a.Ticket))
+		}
+	}
+	return sb.String(), nil
+}
+
+// describeSeat returns the label of the given seat, along with anything the seating plan says about it.
+func (mod *Checkin) describeSeat(id string) string {
+	st, err := mod.getSeat(id)
+	if err != nil {
+		return fmt.Sprintf("**%s**", seatKey(id))
+	}
+	var details []string
+	for _, detail := range []string{st.Zone, st.Description} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if len(details) == 0 {
+		return fmt.Sprintf("**%s**", st.ID)
+	}
+	return fmt.Sprintf("**%s** (%s)", st.ID, strings.Join(details, ", "))
+}
+
+// errorContent turns expected errors into a message for the user.
+func errorContent(err error) (string, error) {
+	for _, expected := range []error{ErrImportFormat, ErrImportInvalid, ErrImportTooLarge} {
+		if errors.Is(err, expected) {
+			return fmt.Sprintf("⚠️ %s.", err), nil
+		}
+	}
+	return "", err
+}
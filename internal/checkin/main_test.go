@@ -0,0 +1,148 @@
+package checkin
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/storage/storagetest"
+	"strings"
+	"testing"
+)
+
+func newTestCheckin(t *testing.T) *Checkin {
+	t.Helper()
+	store := storagetest.Open(t)
+	mod, err := New(nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mod
+}
+
+func TestImportRecords(t *testing.T) {
+	csvData := "Ticket, Name, Seat\nab-123, Alice ,b12\nCD-456,Bob,\n"
+	jsonData := `[{"ticket": "ab-123", "name": "Alice", "seat": "b12"}, {"Ticket": 456, "Name": "Bob", "Seat": null}]`
+
+	records, err := importRecords("attendees.CSV", []byte(csvData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0]["name"] != "Alice" || records[0]["seat"] != "b12" || records[1]["seat"] != "" {
+		t.Errorf("Unexpected CSV records %v", records)
+	}
+
+	records, err = importRecords("attendees.json", []byte(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0]["ticket"] != "ab-123" || records[1]["ticket"] != "456" {
+		t.Errorf("Unexpected JSON records %v", records)
+	}
+	if _, ok := records[1]["seat"]; ok {
+		t.Errorf("Null fields should be left out, got %v", records[1])
+	}
+
+	if _, err = importRecords("attendees.xlsx", []byte(csvData)); !errors.Is(err, ErrImportFormat) {
+		t.Errorf("Unknown formats should be rejected, got %v", err)
+	}
+	if _, err = importRecords("attendees.json", []byte("{")); !errors.Is(err, ErrImportInvalid) {
+		t.Errorf("Broken JSON should be rejected, got %v", err)
+	}
+	if _, err = importRecords("attendees.csv", []byte("ticket,name\nAB-123\n")); !errors.Is(err, ErrImportInvalid) {
+		t.Errorf("Ragged CSV should be rejected, got %v", err)
+	}
+}
+
+func TestParseRecords(t *testing.T) {
+	seats, err := parseSeats([]map[string]string{{"seat": " b12", "zone": "Main hall"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seats[0].ID != "B12" || seats[0].Zone != "Main hall" {
+		t.Errorf("Unexpected seat %+v", seats[0])
+	}
+	if _, err = parseSeats([]map[string]string{{"zone": "Main hall"}}); !errors.Is(err, ErrImportInvalid) {
+		t.Errorf("Seats without a label should be rejected, got %v", err)
+	}
+
+	attendees, err := parseAttendees([]map[string]string{{"ticket": "ab 123", "name": "Alice", "seat": "b12"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attendees[0].Ticket != "AB123" || attendees[0].Seat != "B12" {
+		t.Errorf("Unexpected attendee %+v", attendees[0])
+	}
+	if _, err = parseAttendees([]map[string]string{{"name": "Alice"}}); !errors.Is(err, ErrImportInvalid) {
+		t.Errorf("Attendees without a ticket should be rejected, got %v", err)
+	}
+}
+
+func TestCheckin(t *testing.T) {
+	mod := newTestCheckin(t)
+	alice := &discordgo.User{ID: "1", Username: "alice"}
+	bob := &discordgo.User{ID: "2", Username: "bob"}
+	if _, err := mod.importSeats([]seat{{ID: "B12", Zone: "Main hall"}}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := mod.importAttendees([]attendee{{Ticket: "AB123", Name: "Alice", Seat: "B12"}, {Ticket: "CD456", Name: "Bob", Seat: "Z99"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "1 was allocated a seat") {
+		t.Errorf("Import should warn about unknown seats, got %q", content)
+	}
+
+	content, err = mod.checkin(nil, "", alice, "ab-123 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "B12") || !strings.Contains(content, "Main hall") {
+		t.Errorf("Check-in should say where Alice is sitting, got %q", content)
+	}
+	if a, err := mod.getAttendeeByUser(alice.ID); err != nil || a.Ticket != "AB123" {
+		t.Errorf("Alice should be linked to her ticket, got %+v (%v)", a, err)
+	}
+	if content, _ = mod.checkin(nil, "", bob, "AB123"); !strings.Contains(content, ErrTicketAlreadyUsed.Error()) {
+		t.Errorf("Bob shouldn't be able to use Alice's ticket, got %q", content)
+	}
+	if content, _ = mod.checkin(nil, "", alice, "CD456"); !strings.Contains(content, ErrAlreadyCheckedIn.Error()) {
+		t.Errorf("Alice shouldn't be able to check in twice, got %q", content)
+	}
+
+	// Re-importing shouldn't lose anybody's check-in.
+	if _, err = mod.importAttendees([]attendee{{Ticket: "AB123", Name: "Alice Smith", Seat: "B12"}}); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := mod.getAttendee("AB123"); a.DiscordID != alice.ID || a.Name != "Alice Smith" {
+		t.Errorf("Re-import should keep the check-in, got %+v", a)
+	}
+
+	a, _ := mod.getAttendee("AB123")
+	if err = mod.unlinkUser(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = mod.getAttendeeByUser(alice.ID); !isNotFound(err) {
+		t.Errorf("Undone check-in should unlink Alice, got %v", err)
+	}
+	if content, _ = mod.checkin(nil, "", bob, "AB123"); !strings.HasPrefix(content, "✅") {
+		t.Errorf("Undone ticket should be usable again, got %q", content)
+	}
+}
+
+func TestTooManyFailures(t *testing.T) {
+	mod := newTestCheckin(t)
+	u := &discordgo.User{ID: "1", Username: "alice"}
+	for idx := 0; idx < maxFailedCheckins; idx++ {
+		if mod.tooManyFailures(u.ID) {
+			t.Fatalf("Should not be rate limited after %d failures", idx)
+		}
+		if content, _ := mod.checkin(nil, "", u, "NOPE"); !strings.Contains(content, ErrUnknownTicket.Error()) {
+			t.Errorf("Unknown ticket should be rejected, got %q", content)
+		}
+	}
+	if content, _ := mod.checkin(nil, "", u, "NOPE"); !strings.Contains(content, ErrTooManyAttempts.Error()) {
+		t.Errorf("Should be rate limited after %d failures, got %q", maxFailedCheckins, content)
+	}
+	if mod.tooManyFailures("2") {
+		t.Errorf("Other users should not be rate limited")
+	}
+}
@@ -0,0 +1,128 @@
+package checkin
+
+import (
+	"errors"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"strings"
+	"time"
+)
+
+const (
+	// The storage bucket the seating plan is kept in, keyed on seatKey(ID).
+	seatsBucket = "checkin_seats"
+	// The storage bucket attendees are kept in, keyed on ticketKey(Ticket).
+	attendeesBucket = "checkin_attendees"
+	// The storage bucket linking Discord users to their ticket, keyed on user ID.
+	usersBucket = "checkin_users"
+)
+
+// seat is one seat in the seating plan.
+type seat struct {
+	// The seat's label, e.g. B12.
+	ID string `json:"seat"`
+	// Where the seat is (e.g. which hall or area), if the plan says.
+	Zone string `json:"zone,omitempty"`
+	// Anything else worth knowing about the seat.
+	Description string `json:"description,omitempty"`
+}
+
+// attendee is somebody with a ticket to the event.
+type attendee struct {
+	// The attendee's ticket code.
+	Ticket string `json:"ticket"`
+	// The attendee's name, as it appears on the ticket.
+	Name string `json:"name"`
+	// The seat the attendee has been allocated, if any.
+	Seat string `json:"seat,omitempty"`
+	// The Discord user who checked in with this ticket, and when.
+	DiscordID   string    `json:"discord_id,omitempty"`
+	CheckedInAt time.Time `json:"checked_in_at,omitempty"`
+}
+
+// seatKey normalises a seat label, so that "b12" and " B12" are the same seat.
+func seatKey(id string) string {
+	return strings.ToUpper(strings.TrimSpace(id))
+}
+
+// ticketKey normalises a ticket code, so that people can type it however they like (e.g. "ab-123" is AB123).
+func ticketKey(ticket string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(ticket)))
+}
+
+// getSeat fetches the given seat from the seating plan. Returns storage.ErrNotFound if there is no such seat.
+func (mod *Checkin) getSeat(id string) (st seat, err error) {
+	err = mod.store.Get(seatsBucket, seatKey(id), &st)
+	return st, err
+}
+
+func (mod *Checkin) saveSeat(st seat) error {
+	return mod.store.Put(seatsBucket, seatKey(st.ID), st)
+}
+
+// getAttendee fetches the attendee with the given ticket. Returns storage.ErrNotFound if there is no such ticket.
+func (mod *Checkin) getAttendee(ticket string) (a attendee, err error) {
+	err = mod.store.Get(attendeesBucket, ticketKey(ticket), &a)
+	return a, err
+}
+
+func (mod *Checkin) saveAttendee(a attendee) error {
+	return mod.store.Put(attendeesBucket, ticketKey(a.Ticket), a)
+}
+
+// getAttendeeByUser fetches the attendee that the given Discord user checked in as.
+// Returns storage.ErrNotFound if they haven't checked in.
+func (mod *Checkin) getAttendeeByUser(userID string) (a attendee, err error) {
+	var ticket string
+	err = mod.store.Get(usersBucket, userID, &ticket)
+	if err != nil {
+		return attendee{}, err
+	}
+	return mod.getAttendee(ticket)
+}
+
+// getAttendeesBySeat returns every attendee allocated to the given seat.
+func (mod *Checkin) getAttendeesBySeat(id string) (attendees []attendee, err error) {
+	id = seatKey(id)
+	for _, key := range mod.store.Keys(attendeesBucket) {
+		var a attendee
+		if err = mod.store.Get(attendeesBucket, key, &a); err != nil {
+			return nil, err
+		}
+		if seatKey(a.Seat) == id {
+			attendees = append(attendees, a)
+		}
+	}
+	return attendees, nil
+}
+
+// linkUser records that the given Discord user checked in with the given attendee's ticket.
+func (mod *Checkin) linkUser(a attendee, userID string) error {
+	a.DiscordID = userID
+	a.CheckedInAt = time.Now()
+	if err := mod.saveAttendee(a); err != nil {
+		return err
+	}
+	return mod.store.Put(usersBucket, userID, ticketKey(a.Ticket))
+}
+
+// unlinkUser undoes a check-in, freeing up the ticket to be used again.
+func (mod *Checkin) unlinkUser(a attendee) error {
+	if a.DiscordID != "" {
+		if err := mod.store.Delete(usersBucket, a.DiscordID); err != nil {
+			return err
+		}
+	}
+	a.DiscordID = ""
+	a.CheckedInAt = time.Time{}
+	return mod.saveAttendee(a)
+}
+
+// isNotFound returns whether the given error is storage telling us something doesn't exist.
+func isNotFound(err error) bool {
+	return errors.Is(err, storage.ErrNotFound)
+}
+
+// CheckedInUsers returns the user IDs of everybody who has checked in (for other modules, e.g. raffles).
+func (mod *Checkin) CheckedInUsers() []string {
+	return mod.store.Keys(usersBucket)
+}
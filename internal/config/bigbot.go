@@ -1,11 +1,17 @@
 package config
 
+import "time"
+
 // Config defines the format of the application configuration.
 type Config struct {
 	Bridge struct {
 		Enabled bool         `long:"enabled" help:"Enable the BIGbot -> Bridge Server" default:"false" env:"ENABLED"`
 		Address string       `long:"listen" help:"Listen address and port" default:"localhost:8080" env:"LISTEN"`
 		Key     SecretString `long:"key" help:"BIGbot authentication key" env:"KEY"`
+		Queue   struct {
+			Size int           `long:"size" help:"How many messages (e.g. shouts) to hold while the event bridge is disconnected (0 to drop them)" default:"50" env:"SIZE"`
+			TTL  time.Duration `long:"ttl" help:"How long held messages are worth sending once the event bridge reconnects" default:"10m" env:"TTL"`
+		} `prefix:"queue." embed:"" envprefix:"QUEUE_"`
 	} `prefix:"bridge." embed:"" envprefix:"BRIDGE_"`
 	Discord struct {
 		Token         SecretString `short:"t" long:"token" help:"Discord bot token" required:"" env:"TOKEN"`
@@ -17,7 +23,23 @@ type Config struct {
 			CrewRole string `help:"If a user is a member of this role ID, treat them as Crew." default:"" env:"ROLE_CREW"`
 		} `prefix:"permissions." embed:"" envprefix:"PERMISSIONS_"`
 		Shoutbox struct {
-			ChannelID string `json:"channelID" help:"Channel ID" default:"" env:"CHANNEL"`
+			ChannelID       string        `json:"channelID" help:"Channel ID" default:"" env:"CHANNEL"`
+			MaxLength       int           `long:"maxLength" help:"Maximum length of a shout (0 for no limit)" default:"280" env:"MAX_LENGTH"`
+			DenyList        []string      `long:"denyList" help:"Words that stop a shout being shown" default:"" env:"DENY_LIST"`
+			AllowLinks      bool          `long:"allowLinks" help:"Allow shouts to contain links" default:"false" env:"ALLOW_LINKS"`
+			AllowMentions   bool          `long:"allowMentions" help:"Allow shouts to mention people, roles and channels" default:"false" env:"ALLOW_MENTIONS"`
+			RateLimit       int           `long:"rateLimit" help:"How many shouts each person can send per window (0 for no limit)" default:"3" env:"RATE_LIMIT"`
+			RateWindow      time.Duration `long:"rateWindow" help:"How long shouts count towards the rate limit" default:"1m" env:"RATE_WINDOW"`
+			MinAccountAge   time.Duration `long:"minAccountAge" help:"How old a Discord account must be before it can shout (0 for no limit)" default:"0" env:"MIN_ACCOUNT_AGE"`
+			ReviewChannelID string        `long:"reviewChannelID" help:"Channel ID to send shouts to for Crew approval before they're shown (shown straight away if unset)" default:"" env:"REVIEW_CHANNEL"`
+			RecentLength    int           `long:"recentLength" help:"How many recent shouts to keep in NodeCG's shoutbox:messages replicant (0 to leave it alone)" default:"20" env:"RECENT_LENGTH"`
+			Web             struct {
+				Enabled bool `long:"enabled" help:"Accept shouts from a web form at /shout on the BIGbridge server" default:"false" env:"ENABLED"`
+			} `prefix:"web." embed:"" envprefix:"WEB_"`
+			Twitch struct {
+				Channel string `long:"channel" help:"Twitch channel whose chat is read for shouts (disabled if unset)" default:"" env:"CHANNEL"`
+				Address string `long:"address" help:"Twitch chat (IRC over TLS) address" default:"irc.chat.twitch.tv:6697" env:"ADDRESS"`
+			} `prefix:"twitch." embed:"" envprefix:"TWITCH_"`
 		} `prefix:"shoutbox." embed:"" envprefix:"SHOUTBOX_"`
 	} `prefix:"discord." embed:"" envprefix:"DISCORD_"`
 	AV struct {
@@ -25,9 +47,64 @@ type Config struct {
 			BundleName string `long:"bundle" help:"NodeCG bundle name" default:"thebiggame" env:"BUNDLE"`
 		} `prefix:"nodecg." embed:"" envprefix:"NODECG_"`
 	} `prefix:"av." embed:"" envprefix:"AV_"`
+	Music struct {
+		URL          string        `long:"url" help:"MusicParty server URL (if unset, /music reads what's playing from NodeCG)" default:"" env:"URL"`
+		Key          SecretString  `long:"key" help:"MusicParty API key" env:"KEY"`
+		PollInterval time.Duration `long:"pollInterval" help:"How often to check what's playing (with MusicParty, or NodeCG if not set)" default:"5s" env:"POLL_INTERVAL"`
+		Requests     struct {
+			Limit  int           `long:"limit" help:"How many songs each person can request per window (0 for no limit)" default:"3" env:"LIMIT"`
+			Window time.Duration `long:"window" help:"How long requests count towards the limit" default:"30m" env:"WINDOW"`
+		} `prefix:"requests." embed:"" envprefix:"REQUESTS_"`
+		Skip struct {
+			Ratio        float64       `long:"ratio" help:"Fraction of active listeners who must vote to skip a song" default:"0.5" env:"RATIO"`
+			MinVotes     int           `long:"minVotes" help:"Minimum number of votes needed to skip a song" default:"2" env:"MIN_VOTES"`
+			ActiveWindow time.Duration `long:"activeWindow" help:"How recently somebody must have used /music to count as an active listener" default:"30m" env:"ACTIVE_WINDOW"`
+		} `prefix:"skip." embed:"" envprefix:"SKIP_"`
+		Announce struct {
+			ChannelID   string        `long:"channelID" help:"Channel ID to announce each new song in" default:"" env:"CHANNEL"`
+			MinInterval time.Duration `long:"minInterval" help:"Minimum time between song announcements (songs that start sooner aren't announced)" default:"2m" env:"MIN_INTERVAL"`
+		} `prefix:"announce." embed:"" envprefix:"ANNOUNCE_"`
+	} `prefix:"music." embed:"" envprefix:"MUSIC_"`
+	Storage struct {
+		Path string `long:"path" help:"File to persist BIGbot's state to (state is kept in memory only if empty)" default:"bigbot-storage.json" env:"PATH"`
+	} `prefix:"storage." embed:"" envprefix:"STORAGE_"`
 	Teams struct {
 		MaxUserTeams int `long:"maxUserRoles" default:"5" help:"Maximum number of teams a User can join" env:"MAX_USER_ROLES"`
+		Channels     struct {
+			Enabled      bool   `long:"enabled" help:"Create private text & voice channels for each new team" default:"false" env:"ENABLED"`
+			CategoryID   string `long:"categoryID" help:"Category ID to create team channels in" default:"" env:"CATEGORY"`
+			NameTemplate string `long:"nameTemplate" help:"Template for team channel names ({{.Name}} is replaced with the team name)" default:"{{.Name}}" env:"NAME_TEMPLATE"`
+		} `prefix:"channels." embed:"" envprefix:"TEAMS_CHANNELS_"`
+		Names struct {
+			MinLength       int      `long:"minLength" help:"Minimum length of a team name" default:"2" env:"MIN_LENGTH"`
+			MaxLength       int      `long:"maxLength" help:"Maximum length of a team name" default:"32" env:"MAX_LENGTH"`
+			DenyList        []string `long:"denyList" help:"Words that send a team name to Crew for review" default:"" env:"DENY_LIST"`
+			Reserved        []string `long:"reserved" help:"Team names that may only be used with Crew approval" default:"crew,admin,admins,staff,moderator,moderators,mod,mods,tbg,thebiggame,everyone,here" env:"RESERVED"`
+			ReviewChannelID string   `long:"reviewChannelID" help:"Channel ID to send team names for review to (names that need review are rejected if unset)" default:"" env:"REVIEW_CHANNEL"`
+		} `prefix:"names." embed:"" envprefix:"TEAMS_NAMES_"`
+		Style struct {
+			ProtectedRoles []string `long:"protectedRoles" help:"Role IDs whose colours teams can't imitate (the Crew role is always protected)" default:"" env:"PROTECTED_ROLES"`
+		} `prefix:"style." embed:"" envprefix:"TEAMS_STYLE_"`
+		Cleanup struct {
+			Interval        time.Duration `long:"interval" help:"How often to look for empty or stale teams (0 to disable)" default:"1h" env:"INTERVAL"`
+			StaleAfter      time.Duration `long:"staleAfter" help:"Also report teams with no activity for this long (0 to only report empty teams)" default:"0" env:"STALE_AFTER"`
+			ReportChannelID string        `long:"reportChannelID" help:"Channel ID to report empty or stale teams to" default:"" env:"REPORT_CHANNEL"`
+			AutoDelete      bool          `long:"autoDelete" help:"Delete empty teams automatically, rather than asking Crew to confirm (stale teams are always confirmed)" default:"false" env:"AUTO_DELETE"`
+		} `prefix:"cleanup." embed:"" envprefix:"TEAMS_CLEANUP_"`
 	} `prefix:"teams." embed:""`
+	Checkin struct {
+		AttendeeRoleID string `long:"attendeeRoleID" help:"Role ID given to attendees when they check in" default:"" env:"ATTENDEE_ROLE"`
+	} `prefix:"checkin." embed:"" envprefix:"CHECKIN_"`
+	Tickets struct {
+		ChannelID string `long:"channelID" help:"Channel ID to open help desk tickets in (as private threads)" default:"" env:"CHANNEL"`
+		MaxOpen   int    `long:"maxOpen" help:"Maximum number of open tickets an attendee can have at once (0 for no limit)" default:"3" env:"MAX_OPEN"`
+		Publish   bool   `long:"publish" help:"Send the number of open tickets to NodeCG for the crew dashboard" default:"false" env:"PUBLISH"`
+	} `prefix:"tickets." embed:"" envprefix:"TICKETS_"`
+	Polls struct {
+		DefaultDuration time.Duration `long:"defaultDuration" help:"How long polls stay open, unless a duration is given" default:"10m" env:"DEFAULT_DURATION"`
+		MaxDuration     time.Duration `long:"maxDuration" help:"The longest a poll can stay open" default:"24h" env:"MAX_DURATION"`
+		ResultsFor      time.Duration `long:"resultsFor" help:"How long a closed poll's results stay on the infoboard" default:"5m" env:"RESULTS_FOR"`
+	} `prefix:"polls." embed:"" envprefix:"POLLS_"`
 	RemoveCommands bool `long:"removeCommands" help:"Remove commands on shutdown" env:"COMMANDS_REMOVE"`
 }
 
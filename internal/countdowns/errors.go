@@ -0,0 +1,8 @@
+package countdowns
+
+import "errors"
+
+var ErrBadName = errors.New("Countdown names must be 1-32 characters")
+var ErrBadDuration = errors.New("That isn't a time BIGbot understands. Try a duration like 10m or 1h30m, or a time like 18:30")
+var ErrDurationOutOfRange = errors.New("Countdowns must end in the next 24 hours")
+var ErrUnknownCountdown = errors.New("There's no countdown with that name")
@@ -0,0 +1,365 @@
+package countdowns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often to check whether any countdowns have finished.
+const checkInterval = time.Second
+
+// logger stores the module's logger instance.
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+type Countdowns struct {
+	discord *discordgo.Session
+
+	// Persistent storage for running countdowns.
+	store *storage.Store
+
+	// Held while changing countdowns, so that a countdown can't be announced twice.
+	mtx sync.Mutex
+}
+
+var commands = []*discordgo.ApplicationCommand{
+	{
+		Name:                     "countdown",
+		Description:              "⏱️🛠️ Show countdown timers on the infoboard (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "start",
+				Description: "⏱️ Start a countdown (replacing any countdown with the same name).",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "What to call the countdown, e.g. tournament",
+						Required:    true,
+						MaxLength:   maxNameLength,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "time",
+						Description: "How long to count down for (e.g. 10m or 1h30m), or when to count down to (e.g. 18:30)",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "label",
+						Description: "What to show on the infoboard, e.g. Tournament starts in (default: the name)",
+						Required:    false,
+						MaxLength:   100,
+					},
+				},
+			},
+			{
+				Name:        "stop",
+				Description: "⏹️ Stop a countdown early (without announcing it).",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "name",
+						Description:  "The countdown to stop",
+						Required:     true,
+						Autocomplete: true,
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Description: "📋 List the running countdowns.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+		},
+	},
+}
+
+func New(discord *discordgo.Session, store *storage.Store) (mod *Countdowns, err error) {
+	return &Countdowns{
+		discord: discord,
+		store:   store,
+	}, nil
+}
+
+func (mod *Countdowns) SetLogger(log *slog.Logger) {
+	logger = log
+}
+
+func (mod *Countdowns) DiscordCommands() ([]*discordgo.ApplicationCommand, error) {
+	return commands, nil
+}
+
+func (mod *Countdowns) Start(ctx context.Context) (err error) {
+	// Make sure the infoboard has the running countdowns whenever the bridge (re)connects.
+	bridge_wan.OnConnect(mod.publish)
+	return mod.watch(ctx)
+}
+
+// watch announces countdowns as they finish (including any that finished while we were away).
+func (mod *Countdowns) watch(ctx context.Context) error {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		mod.finishDueCountdowns(mod.discord, time.Now())
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (mod *Countdowns) DiscordHandleMessage(_ *discordgo.Session, _ *discordgo.MessageCreate) (err error) {
+	return nil
+}
+
+func (mod *Countdowns) DiscordHandleMessageUpdate(_ *discordgo.Session, _ *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *Countdowns) DiscordHandleMessageDelete(_ *discordgo.Session, _ *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
+func (mod *Countdowns) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		if i.ApplicationCommandData().Name == "countdown" {
+			return true, mod.discordHandleCountdownCommand(s, i)
+		}
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		if i.ApplicationCommandData().Name == "countdown" {
+			return true, mod.discordHandleAutocomplete(s, i)
+		}
+	}
+	return false, nil
+}
+
+func (mod *Countdowns) discordHandleCountdownCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range options[0].Options {
+		values[option.Name] = option
+	}
+
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+	var content string
+	switch options[0].Name {
+	case "start":
+		c := countdown{
+			Name:      strings.TrimSpace(values["name"].StringValue()),
+			StartedBy: i.Member.User.ID,
+			ChannelID: i.ChannelID,
+		}
+		if option, ok := values["label"]; ok {
+			c.Label = strings.TrimSpace(option.StringValue())
+		}
+		content, err = mod.startCountdown(c, values["time"].StringValue(), time.Now())
+	case "stop":
+		content, err = mod.stopCountdown(values["name"].StringValue())
+	case "list":
+		content, err = mod.adminList()
+	default:
+		content = "😶 Please use a sub-command."
+	}
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		logger.Error("Countdown command failed", slog.String("action", options[0].Name), slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		content = helpers.DiscordErrorContent(s, i, err)
+	}
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logger.Error("Unable to send countdown command response", slog.Any("error", err))
+	}
+	return nil
+}
+
+// discordHandleAutocomplete suggests running countdowns to stop.
+func (mod *Countdowns) discordHandleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	countdowns, err := mod.listCountdowns()
+	if err != nil {
+		return err
+	}
+	var typed string
+	if options := i.ApplicationCommandData().Options; len(options) > 0 && len(options[0].Options) > 0 {
+		typed = countdownKey(options[0].Options[0].StringValue())
+	}
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, c := range countdowns {
+		// Discord allows at most 25 choices.
+		if len(choices) < 25 && strings.Contains(countdownKey(c.Name), typed) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: c.Name, Value: c.Name})
+		}
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+// startCountdown starts (or restarts) a countdown, ending at the given time.
+func (mod *Countdowns) startCountdown(c countdown, end string, now time.Time) (content string, err error) {
+	if !validName(c.Name) {
+		return fmt.Sprintf("⚠️ %s.", ErrBadName), nil
+	}
+	if c.Label == "" {
+		c.Label = c.Name
+	}
+	if c.EndsAt, err = parseEnd(end, now); err != nil {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	}
+
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	if err = mod.saveCountdown(c); err != nil {
+		return "", err
+	}
+	logger.Info("Countdown started", slog.String("name", c.Name), slog.Time("ends_at", c.EndsAt))
+	go mod.publish()
+	return fmt.Sprintf("⏱️ Started the **%s** countdown, ending <t:%d:R> (<t:%d:t>).", c.Name, c.EndsAt.Unix(), c.EndsAt.Unix()), nil
+}
+
+func (mod *Countdowns) stopCountdown(name string) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	c, err := mod.getCountdown(name)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Sprintf("⚠️ %s.", ErrUnknownCountdown), nil
+	} else if err != nil {
+		return "", err
+	}
+	if err = mod.deleteCountdown(c.Name); err != nil {
+		return "", err
+	}
+	logger.Info("Countdown stopped", slog.String("name", c.Name))
+	go mod.publish()
+	return fmt.Sprintf("⏹️ Stopped the **%s** countdown.", c.Name), nil
+}
+
+func (mod *Countdowns) adminList() (content string, err error) {
+	countdowns, err := mod.listCountdowns()
+	if err != nil {
+		return "", err
+	}
+	if len(countdowns) == 0 {
+		return "⏱️ There are no countdowns running.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("⏱️ Running countdowns:\n")
+	for _, c := range countdowns {
+		sb.WriteString(fmt.Sprintf("- **%s**: %s <t:%d:R> (<t:%d:t>)\n", c.Name, c.Label, c.EndsAt.Unix(), c.EndsAt.Unix()))
+	}
+	return sb.String(), nil
+}
+
+// finishDueCountdowns announces (and removes) any countdowns that have reached zero.
+func (mod *Countdowns) finishDueCountdowns(s *discordgo.Session, now time.Time) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	countdowns, err := mod.listCountdowns()
+	if err != nil {
+		logger.Error("Unable to list countdowns", slog.Any("error", err))
+		return
+	}
+	var finished bool
+	for _, c := range countdowns {
+		if now.Before(c.EndsAt) {
+			// The rest end later.
+			break
+		}
+		if err = mod.deleteCountdown(c.Name); err != nil {
+			logger.Error("Unable to remove finished countdown", slog.String("name", c.Name), slog.Any("error", err))
+			continue
+		}
+		finished = true
+		logger.Info("Countdown finished", slog.String("name", c.Name))
+		_, err = s.ChannelMessageSendComplex(c.ChannelID, &discordgo.MessageSend{
+			Content:         finishedContent(c, now),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			logger.Warn("Unable to announce finished countdown", slog.String("name", c.Name), slog.Any("error", err))
+		}
+	}
+	if finished {
+		go mod.publish()
+	}
+}
+
+// finishedContent announces that a countdown has finished.
+func finishedContent(c countdown, now time.Time) string {
+	content := fmt.Sprintf("⏰ **%s**: time's up!", c.Label)
+	if late := now.Sub(c.EndsAt); late > time.Minute {
+		// BIGbot wasn't running when it finished.
+		content += fmt.Sprintf(" (This countdown finished <t:%d:R>.)", c.EndsAt.Unix())
+	}
+	return content
+}
+
+// countdownsReplicant returns the running countdowns, as the infoboard expects them.
+func countdownsReplicant(countdowns []countdown) ngtbg.NodeCGReplicantDataCountdowns {
+	data := ngtbg.NodeCGReplicantDataCountdowns{
+		// NodeCG expects a list, even if it's empty.
+		Countdowns: []ngtbg.NodeCGReplicantDataCountdown{},
+	}
+	for _, c := range countdowns {
+		data.Countdowns = append(data.Countdowns, ngtbg.NodeCGReplicantDataCountdown{
+			Name:   c.Name,
+			Label:  c.Label,
+			EndsAt: c.EndsAt.Format(time.RFC3339),
+		})
+	}
+	return data
+}
+
+// publish sends the running countdowns to NodeCG for the infoboard. This is best-effort; if the event bridge isn't
+// available, they'll be sent when it reconnects.
+func (mod *Countdowns) publish() {
+	if !bridge_wan.BridgeIsAvailable() {
+		logger.Debug("Event Bridge not available, not publishing countdowns")
+		return
+	}
+	countdowns, err := mod.listCountdowns()
+	if err != nil {
+		logger.Error("Unable to list countdowns", slog.Any("error", err))
+		return
+	}
+	err = bridge_wan.ReplicantSet(ngtbg.ReplicantCountdowns, countdownsReplicant(countdowns))
+	if err != nil {
+		logger.Error("Unable to publish countdowns to NodeCG", slog.Any("error", err))
+	}
+}
+
+var defaultCrewCommandPermissions int64 = discordgo.PermissionAdministrator
+var defaultCrewCommandDMPermissions = false
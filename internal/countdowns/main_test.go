@@ -0,0 +1,140 @@
+package countdowns
+
+import (
+	"errors"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"github.com/thebiggame/bigbot/internal/storage/storagetest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 8, 23, 18, 0, 0, 0, time.UTC)
+
+func testModule(t *testing.T) *Countdowns {
+	store := storagetest.Open(t)
+	mod, err := New(nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mod
+}
+
+func TestParseEnd(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		err   error
+	}{
+		{value: "10m", want: testNow.Add(10 * time.Minute)},
+		{value: " 1h30m ", want: testNow.Add(90 * time.Minute)},
+		{value: "15", want: testNow.Add(15 * time.Minute)},
+		{value: "18:30", want: testNow.Add(30 * time.Minute)},
+		// Already passed today, so it's tomorrow.
+		{value: "09:00", want: testNow.Add(15 * time.Hour)},
+		{value: "18:00", want: testNow.Add(24 * time.Hour)},
+		{value: "24h", want: testNow.Add(24 * time.Hour)},
+		{value: "25h", err: ErrDurationOutOfRange},
+		{value: "0", err: ErrDurationOutOfRange},
+		{value: "-5m", err: ErrDurationOutOfRange},
+		{value: "soon", err: ErrBadDuration},
+		{value: "", err: ErrBadDuration},
+	}
+	for _, test := range tests {
+		got, err := parseEnd(test.value, testNow)
+		if !errors.Is(err, test.err) {
+			t.Errorf("parseEnd(%q) error = %v, want %v", test.value, err, test.err)
+		} else if test.err == nil && !got.Equal(test.want) {
+			t.Errorf("parseEnd(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestValidName(t *testing.T) {
+	if countdownKey(" Tournament ") != countdownKey("tournament") {
+		t.Error("Expected countdown names to be case-insensitive")
+	}
+	for name, want := range map[string]bool{
+		"Tournament":                  true,
+		"  ":                          false,
+		strings.Repeat("x", 32):       true,
+		strings.Repeat("x", 33):       false,
+		strings.Repeat("⏱", 32) + " ": true,
+	} {
+		if got := validName(name); got != want {
+			t.Errorf("validName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestStartStopCountdown(t *testing.T) {
+	mod := testModule(t)
+	content, err := mod.startCountdown(countdown{Name: "Tournament", ChannelID: "1"}, "30m", testNow)
+	if err != nil || !strings.HasPrefix(content, "⏱️") {
+		t.Fatalf("Unexpected start response %q (%v)", content, err)
+	}
+	if _, err = mod.startCountdown(countdown{Name: "Pizza", Label: "Pizza arrives in"}, "10m", testNow); err != nil {
+		t.Fatal(err)
+	}
+	content, err = mod.startCountdown(countdown{Name: "Later"}, "never", testNow)
+	if err != nil || !strings.Contains(content, ErrBadDuration.Error()) {
+		t.Errorf("Unexpected response %q (%v)", content, err)
+	}
+	content, err = mod.startCountdown(countdown{Name: " "}, "10m", testNow)
+	if err != nil || !strings.Contains(content, ErrBadName.Error()) {
+		t.Errorf("Unexpected response %q (%v)", content, err)
+	}
+
+	countdowns, err := mod.listCountdowns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(countdowns) != 2 || countdowns[0].Name != "Pizza" || countdowns[1].Name != "Tournament" {
+		t.Fatalf("Expected countdowns soonest first, got %+v", countdowns)
+	}
+	if countdowns[1].Label != "Tournament" {
+		t.Errorf("Expected the label to default to the name, got %q", countdowns[1].Label)
+	}
+
+	// Starting a countdown with the same name replaces it.
+	if _, err = mod.startCountdown(countdown{Name: "tournament"}, "5m", testNow); err != nil {
+		t.Fatal(err)
+	}
+	countdowns, _ = mod.listCountdowns()
+	if len(countdowns) != 2 || countdowns[0].Name != "tournament" {
+		t.Errorf("Expected the countdown to be replaced, got %+v", countdowns)
+	}
+
+	content, err = mod.stopCountdown("PIZZA")
+	if err != nil || !strings.HasPrefix(content, "⏹️") {
+		t.Errorf("Unexpected stop response %q (%v)", content, err)
+	}
+	content, err = mod.stopCountdown("Pizza")
+	if err != nil || !strings.Contains(content, ErrUnknownCountdown.Error()) {
+		t.Errorf("Unexpected response %q (%v)", content, err)
+	}
+	if _, err = mod.getCountdown("pizza"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected the countdown to be deleted, got %v", err)
+	}
+}
+
+func TestCountdownsReplicant(t *testing.T) {
+	data := countdownsReplicant(nil)
+	if data.Countdowns == nil || len(data.Countdowns) != 0 {
+		t.Errorf("Expected an empty (but not nil) list, got %#v", data.Countdowns)
+	}
+	data = countdownsReplicant([]countdown{{Name: "Pizza", Label: "Pizza arrives in", EndsAt: testNow}})
+	if len(data.Countdowns) != 1 || data.Countdowns[0].Label != "Pizza arrives in" || data.Countdowns[0].EndsAt != "2024-08-23T18:00:00Z" {
+		t.Errorf("Unexpected replicant %+v", data)
+	}
+}
+
+func TestFinishedContent(t *testing.T) {
+	c := countdown{Name: "pizza", Label: "Pizza", EndsAt: testNow}
+	if content := finishedContent(c, testNow.Add(time.Second)); content != "⏰ **Pizza**: time's up!" {
+		t.Errorf("Unexpected content %q", content)
+	}
+	if content := finishedContent(c, testNow.Add(time.Hour)); !strings.Contains(content, "finished <t:") {
+		t.Errorf("Expected a late countdown to say when it finished, got %q", content)
+	}
+}
@@ -0,0 +1,95 @@
+package countdowns
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// The storage bucket running countdowns are kept in, keyed on countdownKey(Name).
+	countdownsBucket = "countdowns"
+
+	// The longest a countdown name can be.
+	maxNameLength = 32
+	// The furthest ahead a countdown can end.
+	maxDuration = 24 * time.Hour
+)
+
+// countdown is a named timer shown on the infoboard.
+type countdown struct {
+	// What the countdown is called (used to stop it), and what's shown alongside it.
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// When the countdown reaches zero.
+	EndsAt time.Time `json:"ends_at"`
+	// Who started the countdown, and where (which is where it's announced when it finishes).
+	StartedBy string `json:"started_by"`
+	ChannelID string `json:"channel_id"`
+}
+
+// countdownKey normalises a countdown name, so that "Tournament" and "tournament " are the same countdown.
+func countdownKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// parseEnd works out when a countdown should end, from either a duration (e.g. "10m") or a time of day (e.g. "18:30",
+// which is tomorrow if it's already passed today).
+func parseEnd(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	var end time.Time
+	if clock, err := time.ParseInLocation("15:04", value, now.Location()); err == nil {
+		end = time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !end.After(now) {
+			end = end.AddDate(0, 0, 1)
+		}
+	} else if duration, err := time.ParseDuration(value); err == nil {
+		end = now.Add(duration)
+	} else if minutes, err := strconv.Atoi(value); err == nil {
+		// People will probably write "10" and mean minutes.
+		end = now.Add(time.Duration(minutes) * time.Minute)
+	} else {
+		return time.Time{}, ErrBadDuration
+	}
+	if !end.After(now) || end.Sub(now) > maxDuration {
+		return time.Time{}, ErrDurationOutOfRange
+	}
+	return end, nil
+}
+
+// validName returns whether a countdown name is acceptable.
+func validName(name string) bool {
+	length := utf8.RuneCountInString(strings.TrimSpace(name))
+	return length > 0 && length <= maxNameLength
+}
+
+// getCountdown fetches the countdown with the given name. Returns storage.ErrNotFound if there is no such countdown.
+func (mod *Countdowns) getCountdown(name string) (c countdown, err error) {
+	err = mod.store.Get(countdownsBucket, countdownKey(name), &c)
+	return c, err
+}
+
+func (mod *Countdowns) saveCountdown(c countdown) error {
+	return mod.store.Put(countdownsBucket, countdownKey(c.Name), c)
+}
+
+func (mod *Countdowns) deleteCountdown(name string) error {
+	return mod.store.Delete(countdownsBucket, countdownKey(name))
+}
+
+// listCountdowns returns the running countdowns, soonest first.
+func (mod *Countdowns) listCountdowns() (countdowns []countdown, err error) {
+	for _, key := range mod.store.Keys(countdownsBucket) {
+		var c countdown
+		if err = mod.store.Get(countdownsBucket, key, &c); err != nil {
+			return nil, err
+		}
+		countdowns = append(countdowns, c)
+	}
+	slices.SortStableFunc(countdowns, func(a, b countdown) int {
+		return a.EndsAt.Compare(b.EndsAt)
+	})
+	return countdowns, nil
+}
@@ -2,10 +2,13 @@ package helpers
 
 import (
 	"errors"
+	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/thebiggame/bigbot/internal/config"
 )
 
+var ErrNotCrew = errors.New("You must be a member of Crew to do that")
+
 func UserIsCrew(s *discordgo.Session, guild string, u *discordgo.User) (isCrew bool, err error) {
 	var crewRoleID = config.RuntimeConfig.Discord.Permissions.CrewRole
 	if crewRoleID == "" {
@@ -28,3 +31,13 @@ func UserIsCrew(s *discordgo.Session, guild string, u *discordgo.User) (isCrew b
 	// User not a member of the Crew guild
 	return false, nil
 }
+
+// DiscordRequireCrew checks that whoever triggered an interaction is Crew. If they aren't, it tells them so, and
+// returns false; the interaction has then been answered.
+func DiscordRequireCrew(s *discordgo.Session, i *discordgo.InteractionCreate) (isCrew bool, err error) {
+	isCrew, err = UserIsCrew(s, i.GuildID, DiscordInteractionUser(i))
+	if err != nil || isCrew {
+		return isCrew, err
+	}
+	return false, DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("🚫 %s.", ErrNotCrew))
+}
@@ -1,6 +1,9 @@
 package helpers
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+)
 
 func DiscordDeferEphemeralInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) error {
 	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -26,3 +29,58 @@ func DiscordInteractionFollowupMessage(s *discordgo.Session, i *discordgo.Intera
 		Content: content,
 	})
 }
+
+// DiscordErrorContent describes an error that occurred while handling an interaction. Only Crew are shown the details.
+func DiscordErrorContent(s *discordgo.Session, i *discordgo.InteractionCreate, err error) string {
+	if isCrew, crewErr := UserIsCrew(s, i.GuildID, DiscordInteractionUser(i)); crewErr == nil && isCrew {
+		return fmt.Sprintf("🚫 **An error occurred while processing your command:**\n```%s```", err)
+	}
+	return "🚫 **An error occurred while processing your command. Please contact a member of theBIGGAME Crew.**"
+}
+
+// DiscordInteractionFollowupError reports an error that occurred after an interaction was deferred. (Returning the
+// error to the bot won't do, as it can only report errors by responding to the interaction, which has been done.)
+func DiscordInteractionFollowupError(s *discordgo.Session, i *discordgo.InteractionCreate, err error) error {
+	_, err = DiscordInteractionFollowupMessage(s, i, DiscordErrorContent(s, i, err))
+	return err
+}
+
+// DiscordUpdateComponentMessage replaces the message a component (e.g. a button) belongs to with the given content,
+// removing its components.
+func DiscordUpdateComponentMessage(s *discordgo.Session, i *discordgo.InteractionCreate, content string) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Components:      []discordgo.MessageComponent{},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// DiscordInteractionUser returns the user who triggered an interaction, whether it happened in a guild or a DM.
+func DiscordInteractionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// DiscordMemberDisplayName returns the name a member is shown as in the guild.
+func DiscordMemberDisplayName(m *discordgo.Member) string {
+	if m.Nick != "" {
+		return m.Nick
+	}
+	if m.User.GlobalName != "" {
+		return m.User.GlobalName
+	}
+	return m.User.Username
+}
+
+// Pluralise returns singular if there is exactly one of something, or plural otherwise.
+func Pluralise(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
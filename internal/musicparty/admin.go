@@ -0,0 +1,202 @@
+package musicparty
+
+import (
+	"context"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+)
+
+// This file handles the Crew-only music commands, which override the usual limits and votes.
+
+var adminCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:                     "musicparty",
+		Description:              "🎵🛠️ Manage the music (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "admin",
+				Description: "🎵🛠️ Music administration.",
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "skip",
+						Description: "⏭️ Skip the song that's playing, without a vote.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+					},
+					{
+						Name:        "remove",
+						Description: "🗑️ Remove a song from the queue.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "position",
+								Description: "Where the song is in the queue (see /music queue).",
+								Required:    true,
+								MinValue:    &queuePositionMinValue,
+							},
+						},
+					},
+					{
+						Name:        "request",
+						Description: "🙋 Queue a song, ignoring the request limit.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "query",
+								Description: "The song you want (title and/or artist).",
+								Required:    true,
+								MaxLength:   100,
+							},
+						},
+					},
+					{
+						Name:        "top-tracks",
+						Description: "🏆 Summarise the most played songs.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "count",
+								Description: "How many songs to list. Defaults to 10.",
+								Required:    false,
+								MinValue:    &queuePositionMinValue,
+								MaxValue:    25,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionBoolean,
+								Name:        "post",
+								Description: "Post the summary in this channel for everybody to see, rather than just to you.",
+								Required:    false,
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func (mod *MusicParty) discordHandleAdminCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 || options[0].Name != "admin" || len(options[0].Options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+	subcommand := options[0].Options[0]
+	if subcommand.Name == "top-tracks" {
+		// The history is kept whether or not we're using MusicParty.
+		return mod.adminTopTracks(s, i, subcommand.Options)
+	}
+	if mod.client == nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", ErrMusicPartyUnavailable))
+	}
+
+	// Let the client know we're working on it.
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+	ctx := mod.context()
+	var content string
+	switch subcommand.Name {
+	case "skip":
+		content, err = mod.adminSkip(ctx, i.Member.User)
+	case "remove":
+		content, err = mod.adminRemove(ctx, i.Member.User, int(subcommand.Options[0].IntValue()))
+	case "request":
+		content, err = mod.request(ctx, i.Member.User, subcommand.Options[0].StringValue(), true)
+	default:
+		content = "😶 Please use a sub-command."
+	}
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		mod.logger.Error("MusicParty administration failed", slog.String("action", subcommand.Name), slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		content = helpers.DiscordErrorContent(s, i, err)
+	} else {
+		mod.refresh(ctx)
+	}
+	if _, err = helpers.DiscordInteractionFollowupMessage(s, i, content); err != nil {
+		mod.logger.Error("Unable to send MusicParty administration response", slog.Any("error", err))
+	}
+	return nil
+}
+
+func (mod *MusicParty) adminSkip(ctx context.Context, u *discordgo.User) (content string, err error) {
+	np, err := mod.client.NowPlaying(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !np.Playing || np.Track == nil {
+		return fmt.Sprintf("🔇 %s.", ErrNothingPlaying), nil
+	}
+	if err = mod.client.Skip(ctx); err != nil {
+		return "", err
+	}
+	mod.logger.Info("Song skipped by Crew", slog.String("user", u.Username), slog.String("title", np.Track.Title))
+	return fmt.Sprintf("⏭️ Skipped %s.", describeTrack(*np.Track)), nil
+}
+
+func (mod *MusicParty) adminRemove(ctx context.Context, u *discordgo.User, position int) (content string, err error) {
+	queue, err := mod.client.Queue(ctx)
+	if err != nil {
+		return "", err
+	}
+	if position < 1 || position > len(queue) {
+		return fmt.Sprintf("🤷 There are only %d songs in the queue.", len(queue)), nil
+	}
+	track := queue[position-1]
+	if err = mod.client.Remove(ctx, track.ID); err != nil {
+		return "", err
+	}
+	mod.logger.Info("Song removed from queue by Crew", slog.String("user", u.Username), slog.String("title", track.Title))
+	return fmt.Sprintf("🗑️ Removed %s from the queue.", describeTrack(track)), nil
+}
+
+// adminTopTracks summarises the most played songs, either just for the person who asked or for the whole channel.
+func (mod *MusicParty) adminTopTracks(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (err error) {
+	count, post := 10, false
+	for _, opt := range options {
+		switch opt.Name {
+		case "count":
+			count = int(opt.IntValue())
+		case "post":
+			post = opt.BoolValue()
+		}
+	}
+	history, err := mod.history()
+	if err != nil {
+		return err
+	}
+	embed := topTracksEmbed(history, count)
+	if !post {
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{embed},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			},
+		})
+	}
+	mod.logger.Info("Top tracks posted", slog.String("user", i.Member.User.Username), slog.String("channel", i.ChannelID))
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+}
+
+var queuePositionMinValue float64 = 1
+
+var defaultCrewCommandPermissions int64 = discordgo.PermissionAdministrator
+var defaultCrewCommandDMPermissions = false
@@ -1,18 +1,60 @@
 package musicparty
 
 import (
+	"context"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
 	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
-	"github.com/thebiggame/bigbot/internal/config"
 	"github.com/thebiggame/bigbot/internal/helpers"
+	mpclient "github.com/thebiggame/bigbot/pkg/musicparty"
+	"log/slog"
+	"strings"
 )
 
+// How many queued tracks /music queue shows.
+const queueListLength = 10
+
 var commands = []*discordgo.ApplicationCommand{
 	{
 		Name:        "music",
-		Description: "🎵 Find out what's playing.",
+		Description: "🎵 Find out what's playing, and ask for songs.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "now",
+				Description: "🎵 Find out what's playing.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "queue",
+				Description: "📋 Find out what's coming up next.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "request",
+				Description: "🙋 Ask for a song to be played.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "query",
+						Description: "The song you want (title and/or artist).",
+						Required:    true,
+						MaxLength:   100,
+					},
+				},
+			},
+			{
+				Name:        "history",
+				Description: "📜 Find out what's been played recently.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "vote-skip",
+				Description: "⏭️ Vote to skip the song that's playing.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+		},
 	},
 }
 
@@ -20,28 +62,137 @@ func (mod *MusicParty) DiscordHandleMessage(_ *discordgo.Session, _ *discordgo.M
 	return nil
 }
 
+func (mod *MusicParty) DiscordHandleMessageUpdate(_ *discordgo.Session, _ *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *MusicParty) DiscordHandleMessageDelete(_ *discordgo.Session, _ *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
 func (mod *MusicParty) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
 		// Handle normally.
-		if i.ApplicationCommandData().Name != "music" {
+		switch i.ApplicationCommandData().Name {
+		case "music":
+		case "musicparty":
+			return true, mod.discordHandleAdminCommand(s, i)
+		default:
 			return false, nil
 		}
+		options := i.ApplicationCommandData().Options
+		if len(options) == 0 {
+			return true, helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+		}
 
-		// Get music data (if available)
-		// Right now this just calls out to NodeCG. Going forward, we'll do this with MusicParty directly.
-		// Check the bridge is available.
-		if !bridge_wan.BridgeIsAvailable() {
-			return true, helpers.DiscordInteractionEphemeralResponse(s, i, "👻 **Event Bridge is not available**")
+		// Let the client know we're working on it; MusicParty or NodeCG may take a moment to answer.
+		if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+			return true, err
+		}
+		ctx := mod.context()
+		u := helpers.DiscordInteractionUser(i)
+		mod.markActive(u.ID)
+
+		var content string
+		switch options[0].Name {
+		case "now":
+			content, err = mod.nowPlaying(ctx)
+		case "queue":
+			content, err = mod.queue(ctx)
+		case "request":
+			content, err = mod.request(ctx, u, options[0].Options[0].StringValue(), false)
+		case "vote-skip":
+			content, err = mod.voteSkip(ctx, u)
+		case "history":
+			var history []playedTrack
+			history, err = mod.history()
+			content = historyContent(history)
+		default:
+			content = "😶 Unknown command..."
 		}
-		var data ngtbg.NodeCGReplicantDataMusicData
-		err = bridge_wan.EventBridge.BrReplicantGet(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGReplicantMusicData, &data)
 		if err != nil {
-			return true, err
+			// We've already acknowledged the interaction, so report the problem ourselves.
+			mod.logger.Error("Music command failed", slog.String("command", options[0].Name), slog.String("user", u.Username), slog.Any("error", err))
+			content = helpers.DiscordErrorContent(s, i, err)
+		} else if mod.client != nil && (options[0].Name == "request" || options[0].Name == "vote-skip") {
+			// Let the infoboard catch up with whatever just changed.
+			mod.refresh(ctx)
 		}
-		musicString := fmt.Sprintf("🎵 **%s** / %s", data.Title, data.Artist)
-		return true, helpers.DiscordInteractionEphemeralResponse(s, i, musicString)
+		if _, err = helpers.DiscordInteractionFollowupMessage(s, i, content); err != nil {
+			mod.logger.Error("Unable to send music command response", slog.Any("error", err))
+		}
+		return true, nil
 	default:
 		return false, nil
 	}
 }
+
+// nowPlaying describes what's playing.
+func (mod *MusicParty) nowPlaying(ctx context.Context) (content string, err error) {
+	if mod.client == nil {
+		// Without MusicParty, NodeCG is the only place that knows what's playing.
+		if !bridge_wan.BridgeIsAvailable() {
+			return "👻 **Event Bridge is not available**", nil
+		}
+		data, err := bridge_wan.ReplicantGet(ngtbg.ReplicantMusicData)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("🎵 **%s** / %s", data.Title, data.Artist), nil
+	}
+	np, err := mod.client.NowPlaying(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !np.Playing || np.Track == nil {
+		return "🔇 Nothing's playing right now.", nil
+	}
+	return "🎵 " + describeTrack(*np.Track), nil
+}
+
+// queue describes what's coming up next.
+func (mod *MusicParty) queue(ctx context.Context) (content string, err error) {
+	if mod.client == nil {
+		return fmt.Sprintf("⚠️ %s.", ErrMusicPartyUnavailable), nil
+	}
+	tracks, err := mod.client.Queue(ctx)
+	if err != nil {
+		return "", err
+	}
+	return queueContent(tracks), nil
+}
+
+// queueContent lists the first few queued tracks.
+func queueContent(tracks []mpclient.Track) string {
+	if len(tracks) == 0 {
+		return "📋 Nothing's queued up. Why not `/music request` something?"
+	}
+	var sb strings.Builder
+	sb.WriteString("📋 Coming up:\n")
+	for idx, track := range tracks {
+		if idx == queueListLength {
+			sb.WriteString(fmt.Sprintf("…and %d more.", len(tracks)-queueListLength))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s\n", idx+1, describeTrack(track)))
+	}
+	return sb.String()
+}
+
+// describeTrack returns the title and artist of a track (and who asked for it, if anybody did).
+func describeTrack(track mpclient.Track) string {
+	description := fmt.Sprintf("**%s** / %s", track.Title, track.Artist)
+	if track.RequestedBy != "" {
+		description += fmt.Sprintf(" (requested by %s)", track.RequestedBy)
+	}
+	return description
+}
+
+// context returns the context given to us by the main bot (or a background context, if we haven't been started).
+func (mod *MusicParty) context() context.Context {
+	if mod.ctx != nil {
+		return *mod.ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,11 @@
+package musicparty
+
+import "errors"
+
+var ErrMusicPartyUnavailable = errors.New("MusicParty isn't set up, so that isn't available")
+var ErrNoMatchingTracks = errors.New("Couldn't find any tracks matching that")
+var ErrTooManyRequests = errors.New("You've requested too many songs recently")
+var ErrAlreadyPlaying = errors.New("That's playing right now")
+var ErrAlreadyQueued = errors.New("That's already queued")
+var ErrNothingPlaying = errors.New("Nothing's playing right now")
+var ErrTooFewListeners = errors.New("Not enough people are listening to skip songs by vote. Please ask a member of Crew")
@@ -0,0 +1,227 @@
+package musicparty
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	mpclient "github.com/thebiggame/bigbot/pkg/musicparty"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// This file handles keeping track of what has been played, and announcing new songs in Discord.
+
+const (
+	// The storage bucket played tracks are kept in, keyed on historyKey(PlayedAt).
+	historyBucket = "music_history"
+	// How many tracks /music history shows.
+	historyListLength = 10
+)
+
+// playedTrack is a song that has been played.
+type playedTrack struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	// Who asked for the song, if anybody did (and we know).
+	RequestedBy string `json:"requested_by,omitempty"`
+	// Where the song's artwork can be found, if we know.
+	ArtURL string `json:"art_url,omitempty"`
+	// When the song started playing.
+	PlayedAt time.Time `json:"played_at"`
+}
+
+// trackKey identifies a song, so that we can tell when it changes and count how often it's been played.
+func (t playedTrack) trackKey() string {
+	return strings.ToLower(strings.TrimSpace(t.Title)) + "\x00" + strings.ToLower(strings.TrimSpace(t.Artist))
+}
+
+// historyKey returns the storage key for a track played at the given time. Keys sort in the order tracks were played.
+func historyKey(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
+
+// playedTrackFromNowPlaying converts what MusicParty is playing into a played track (with an empty title if nothing is).
+// Pausing a song doesn't count as it stopping.
+func playedTrackFromNowPlaying(np mpclient.NowPlaying) playedTrack {
+	if np.Track == nil {
+		return playedTrack{}
+	}
+	return playedTrack{
+		Title:       np.Track.Title,
+		Artist:      np.Track.Artist,
+		RequestedBy: np.Track.RequestedBy,
+		ArtURL:      np.Track.ArtURL,
+	}
+}
+
+// playedTrackFromMusicData converts what NodeCG says is playing into a played track.
+func playedTrackFromMusicData(data ngtbg.NodeCGReplicantDataMusicData) playedTrack {
+	return playedTrack{
+		Title:  data.Title,
+		Artist: data.Artist,
+	}
+}
+
+// observeTrack is called with whatever is playing each time we check. When the song changes, it's added to the history
+// (and announced in Discord, if configured).
+func (mod *MusicParty) observeTrack(track playedTrack) {
+	mod.mtx.Lock()
+	if track.Title == "" {
+		// Nothing's playing. Whatever plays next is new, even if it's the same song again.
+		mod.lastTrack = ""
+		mod.mtx.Unlock()
+		return
+	}
+	if mod.lastTrack == "" && !mod.historyLoaded {
+		// We've just started; don't record the song again if it was already playing when we stopped.
+		if history, err := mod.history(); err == nil && len(history) > 0 {
+			mod.lastTrack = history[len(history)-1].trackKey()
+		}
+		mod.historyLoaded = true
+	}
+	if track.trackKey() == mod.lastTrack {
+		mod.mtx.Unlock()
+		return
+	}
+	mod.lastTrack = track.trackKey()
+	track.PlayedAt = time.Now()
+	announce := mod.shouldAnnounce(track.PlayedAt)
+	mod.mtx.Unlock()
+
+	err := mod.store.Put(historyBucket, historyKey(track.PlayedAt), track)
+	if err != nil {
+		mod.logger.Error("Unable to record played track", slog.Any("error", err))
+	}
+	mod.logger.Info("Now playing", slog.String("title", track.Title), slog.String("artist", track.Artist))
+	if announce {
+		mod.announceTrack(track)
+	}
+}
+
+// shouldAnnounce returns whether a song that started at the given time should be announced in Discord.
+// Announcements are rate-limited, so that a run of short songs (or a lot of skipping) doesn't flood the channel.
+// mod.mtx must be held.
+func (mod *MusicParty) shouldAnnounce(at time.Time) bool {
+	if config.RuntimeConfig.Music.Announce.ChannelID == "" || mod.discord == nil {
+		return false
+	}
+	if at.Sub(mod.lastAnnounced) < config.RuntimeConfig.Music.Announce.MinInterval {
+		return false
+	}
+	mod.lastAnnounced = at
+	return true
+}
+
+// announceTrack posts a "now playing" embed to the announcement channel.
+func (mod *MusicParty) announceTrack(track playedTrack) {
+	_, err := mod.discord.ChannelMessageSendEmbed(config.RuntimeConfig.Music.Announce.ChannelID, nowPlayingEmbed(track))
+	if err != nil {
+		mod.logger.Error("Unable to announce now playing", slog.Any("error", err))
+	}
+}
+
+// nowPlayingEmbed describes a song that has just started playing.
+func nowPlayingEmbed(track playedTrack) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       "🎵 Now playing",
+		Description: fmt.Sprintf("**%s**\n%s", track.Title, track.Artist),
+		Color:       0x5C6BC0,
+		Timestamp:   track.PlayedAt.Format(time.RFC3339),
+	}
+	if track.ArtURL != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: track.ArtURL}
+	}
+	if track.RequestedBy != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "Requested by " + track.RequestedBy}
+	}
+	return embed
+}
+
+// history returns every song that has been played, oldest first.
+func (mod *MusicParty) history() (tracks []playedTrack, err error) {
+	for _, key := range mod.store.Keys(historyBucket) {
+		var track playedTrack
+		if err = mod.store.Get(historyBucket, key, &track); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, nil
+}
+
+// historyContent lists the last few songs played, newest first.
+func historyContent(history []playedTrack) string {
+	if len(history) == 0 {
+		return "📜 Nothing's been played yet."
+	}
+	var sb strings.Builder
+	sb.WriteString("📜 Recently played:\n")
+	for idx := len(history) - 1; idx >= 0 && idx >= len(history)-historyListLength; idx-- {
+		track := history[idx]
+		sb.WriteString(fmt.Sprintf("- <t:%d:t> **%s** / %s", track.PlayedAt.Unix(), track.Title, track.Artist))
+		if track.RequestedBy != "" {
+			sb.WriteString(fmt.Sprintf(" (requested by %s)", track.RequestedBy))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// trackPlays is how many times a song has been played.
+type trackPlays struct {
+	Title  string
+	Artist string
+	Plays  int
+}
+
+// topTracks returns the most played songs, most played first (ties go to whichever was first played first).
+func topTracks(history []playedTrack, count int) (top []trackPlays) {
+	indexes := make(map[string]int)
+	for _, track := range history {
+		idx, ok := indexes[track.trackKey()]
+		if !ok {
+			idx = len(top)
+			indexes[track.trackKey()] = idx
+			top = append(top, trackPlays{Title: track.Title, Artist: track.Artist})
+		}
+		top[idx].Plays++
+	}
+	sort.SliceStable(top, func(a, b int) bool {
+		return top[a].Plays > top[b].Plays
+	})
+	if len(top) > count {
+		top = top[:count]
+	}
+	return top
+}
+
+// topTracksEmbed summarises the most played songs (and how much was played overall).
+func topTracksEmbed(history []playedTrack, count int) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: "🏆 Top tracks",
+		Color: 0xFBC02D,
+	}
+	if len(history) == 0 {
+		embed.Description = "Nothing's been played yet."
+		return embed
+	}
+	var sb strings.Builder
+	for idx, track := range topTracks(history, count) {
+		sb.WriteString(fmt.Sprintf("%d. **%s** / %s (%d %s)\n", idx+1, track.Title, track.Artist, track.Plays, helpers.Pluralise(track.Plays, "play", "plays")))
+	}
+	embed.Description = sb.String()
+	requests := 0
+	for _, track := range history {
+		if track.RequestedBy != "" {
+			requests++
+		}
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("%d %s played, %d of them requested", len(history), helpers.Pluralise(len(history), "song", "songs"), requests),
+	}
+	return embed
+}
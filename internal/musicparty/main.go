@@ -2,8 +2,16 @@ package musicparty
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/storage"
+	mpclient "github.com/thebiggame/bigbot/pkg/musicparty"
 	"log/slog"
+	"sync"
+	"time"
 )
 
 type MusicParty struct {
@@ -13,12 +21,52 @@ type MusicParty struct {
 
 	// The context given to us by the main bot.
 	ctx *context.Context
+
+	// Persistent storage for the played-tracks history.
+	store *storage.Store
+
+	// The MusicParty server, or nil if we're reading what's playing from NodeCG instead.
+	client *mpclient.Client
+
+	// Held while looking at or changing anything below.
+	mtx sync.Mutex
+
+	// When each user requested songs, for rate limiting.
+	requests map[string][]time.Time
+
+	// When each user last used /music, to work out how many people are listening.
+	active map[string]time.Time
+
+	// The track being voted on, and who has voted to skip it.
+	voteTrack string
+	votes     map[string]bool
+
+	// The song that was playing last time we checked (see playedTrack.trackKey), and whether we've looked at the
+	// history to find out what was playing before we started.
+	lastTrack     string
+	historyLoaded bool
+
+	// When we last announced a song in Discord.
+	lastAnnounced time.Time
+
+	// What we last told NodeCG (as JSON, keyed on replicant), so that we only update replicants when they change.
+	published map[string]string
 }
 
-func New(discord *discordgo.Session) (mod *MusicParty, err error) {
-	return &MusicParty{
-		discord: discord,
-	}, nil
+func New(discord *discordgo.Session, store *storage.Store) (mod *MusicParty, err error) {
+	mod = &MusicParty{
+		discord:   discord,
+		store:     store,
+		logger:    slog.Default(),
+		requests:  make(map[string][]time.Time),
+		active:    make(map[string]time.Time),
+		votes:     make(map[string]bool),
+		published: make(map[string]string),
+	}
+	if url := config.RuntimeConfig.Music.URL; url != "" {
+		mod.client = mpclient.New(url).WithKey(string(config.RuntimeConfig.Music.Key))
+	}
+	return mod, nil
 }
 
 func (mod *MusicParty) SetLogger(logger *slog.Logger) {
@@ -26,11 +74,131 @@ func (mod *MusicParty) SetLogger(logger *slog.Logger) {
 }
 
 func (mod *MusicParty) DiscordCommands() ([]*discordgo.ApplicationCommand, error) {
-	return commands, nil
+	return append(commands, adminCommands...), nil
 }
 
 func (mod *MusicParty) Start(ctx context.Context) (err error) {
 	mod.ctx = &ctx
-	// This module simply registers handlers, and does not need to run continuously (so we don't need the context)
-	return ctx.Err()
+	// Keep the history (and the infoboard, if we're using MusicParty) up to date with what's playing.
+	return mod.watch(ctx)
+}
+
+// watch periodically checks what's playing. With MusicParty, it tells NodeCG whenever anything changes; without it,
+// it watches NodeCG's music:data replicant instead.
+func (mod *MusicParty) watch(ctx context.Context) error {
+	interval := config.RuntimeConfig.Music.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if mod.client == nil {
+		mod.logger.Debug("MusicParty not configured, reading music from NodeCG")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if mod.client != nil {
+				mod.refresh(ctx)
+			} else if err := mod.watchNodeCG(); err != nil {
+				mod.logger.Debug("Unable to check what NodeCG is playing", slog.Any("error", err))
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// watchNodeCG checks what NodeCG says is playing, for when we aren't talking to MusicParty directly.
+func (mod *MusicParty) watchNodeCG() error {
+	if !bridge_wan.BridgeIsAvailable() {
+		return nil
+	}
+	data, err := bridge_wan.ReplicantGet(ngtbg.ReplicantMusicData)
+	if err != nil {
+		return err
+	}
+	mod.observeTrack(playedTrackFromMusicData(data))
+	return nil
+}
+
+// refresh syncs with MusicParty, logging (rather than returning) any problems.
+// This is used after anything that changes the music, so that the infoboard catches up straight away.
+func (mod *MusicParty) refresh(ctx context.Context) {
+	if err := mod.sync(ctx); err != nil {
+		mod.logger.Debug("Unable to sync with MusicParty", slog.Any("error", err))
+	}
+}
+
+// sync fetches what's playing and queued from MusicParty, and tells NodeCG about anything that has changed.
+func (mod *MusicParty) sync(ctx context.Context) error {
+	np, err := mod.client.NowPlaying(ctx)
+	if err != nil {
+		return err
+	}
+	queue, err := mod.client.Queue(ctx)
+	if err != nil {
+		return err
+	}
+	mod.observeTrack(playedTrackFromNowPlaying(np))
+
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	// A new track means a new vote.
+	mod.resetVotes(np)
+	if !bridge_wan.BridgeIsAvailable() {
+		// We'll try again next time around.
+		return nil
+	}
+	if err = publish(mod, ngtbg.ReplicantMusicData, musicData(np)); err != nil {
+		return err
+	}
+	if err = publish(mod, ngtbg.ReplicantMusicQueue, queueData(queue)); err != nil {
+		return err
+	}
+	return publish(mod, ngtbg.ReplicantMusicSkip, mod.skipData())
+}
+
+// publish sets the given replicant, if it has changed since we last did. mod.mtx must be held.
+func publish[T any](mod *MusicParty, replicant ngtbg.Replicant[T], value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if mod.published[replicant.Name()] == string(data) {
+		return nil
+	}
+	err = bridge_wan.ReplicantSet(replicant, value)
+	if err != nil {
+		return err
+	}
+	mod.published[replicant.Name()] = string(data)
+	mod.logger.Debug("Updated music replicant", slog.String("replicant", replicant.Name()))
+	return nil
+}
+
+// musicData converts what MusicParty is playing into what the infoboard expects.
+func musicData(np mpclient.NowPlaying) ngtbg.NodeCGReplicantDataMusicData {
+	if !np.Playing || np.Track == nil {
+		return ngtbg.NodeCGReplicantDataMusicData{}
+	}
+	return ngtbg.NodeCGReplicantDataMusicData{
+		Title:  np.Track.Title,
+		Artist: np.Track.Artist,
+	}
+}
+
+// queueData converts MusicParty's queue into what the infoboard expects.
+func queueData(tracks []mpclient.Track) ngtbg.NodeCGReplicantDataMusicQueue {
+	data := ngtbg.NodeCGReplicantDataMusicQueue{
+		Tracks: make([]ngtbg.NodeCGReplicantDataMusicQueueEntry, 0, len(tracks)),
+	}
+	for _, track := range tracks {
+		data.Tracks = append(data.Tracks, ngtbg.NodeCGReplicantDataMusicQueueEntry{
+			Title:       track.Title,
+			Artist:      track.Artist,
+			RequestedBy: track.RequestedBy,
+		})
+	}
+	return data
 }
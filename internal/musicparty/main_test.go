@@ -0,0 +1,256 @@
+package musicparty
+
+import (
+	"context"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/storage/storagetest"
+	mpclient "github.com/thebiggame/bigbot/pkg/musicparty"
+	"github.com/thebiggame/bigbot/pkg/musicparty/musicpartytest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testCatalogue = []mpclient.Track{
+	{ID: "1", Title: "Never Gonna Give You Up", Artist: "Rick Astley"},
+	{ID: "2", Title: "Sandstorm", Artist: "Darude"},
+}
+
+func newTestMusicParty(t *testing.T) (*MusicParty, *musicpartytest.Server) {
+	t.Helper()
+	server := musicpartytest.NewServer("", testCatalogue...)
+	t.Cleanup(server.Close)
+	store := storagetest.Open(t)
+	mod, err := New(nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod.client = mpclient.New(server.URL)
+	return mod, server
+}
+
+func TestMusicData(t *testing.T) {
+	if data := musicData(mpclient.NowPlaying{}); data.Title != "" || data.Artist != "" {
+		t.Errorf("Nothing playing should clear the music data, got %+v", data)
+	}
+	data := musicData(mpclient.NowPlaying{Playing: true, Track: &testCatalogue[1]})
+	if data.Title != "Sandstorm" || data.Artist != "Darude" {
+		t.Errorf("Unexpected music data %+v", data)
+	}
+}
+
+func TestQueueContent(t *testing.T) {
+	if content := queueContent(nil); !strings.Contains(content, "Nothing's queued") {
+		t.Errorf("Empty queue should say so, got %q", content)
+	}
+	var tracks []mpclient.Track
+	for len(tracks) < queueListLength+3 {
+		tracks = append(tracks, testCatalogue[0])
+	}
+	content := queueContent(tracks)
+	if strings.Count(content, "Rick Astley") != queueListLength || !strings.Contains(content, "…and 3 more.") {
+		t.Errorf("Long queues should be cut short, got %q", content)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	mod, server := newTestMusicParty(t)
+	config.RuntimeConfig.Music.Requests.Limit = 2
+	config.RuntimeConfig.Music.Requests.Window = time.Hour
+	alice := &discordgo.User{ID: "1", Username: "alice"}
+
+	content, err := mod.request(context.Background(), alice, "sandstorm", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "Sandstorm") {
+		t.Errorf("Expected Sandstorm to be queued, got %q", content)
+	}
+	if queue := server.Queue(); len(queue) != 1 || queue[0].RequestedBy != "alice" {
+		t.Errorf("Expected Sandstorm to be queued for alice, got %+v", queue)
+	}
+
+	if content, _ = mod.request(context.Background(), alice, "crazy frog", false); !strings.Contains(content, ErrNoMatchingTracks.Error()) {
+		t.Errorf("Unknown songs should be reported, got %q", content)
+	}
+	if content, _ = mod.request(context.Background(), alice, "darude", false); !strings.Contains(content, ErrAlreadyQueued.Error()) {
+		t.Errorf("Queued songs shouldn't be queued again, got %q", content)
+	}
+
+	server.Next()
+	if content, _ = mod.nowPlaying(context.Background()); !strings.Contains(content, "Sandstorm") || !strings.Contains(content, "alice") {
+		t.Errorf("Expected Sandstorm to be playing, got %q", content)
+	}
+	if content, _ = mod.request(context.Background(), alice, "sandstorm", false); !strings.Contains(content, ErrAlreadyPlaying.Error()) {
+		t.Errorf("The song that's playing shouldn't be queued, got %q", content)
+	}
+
+	if content, _ = mod.request(context.Background(), alice, "rick", false); !strings.Contains(content, "Queued") {
+		t.Errorf("Expected Never Gonna Give You Up to be queued, got %q", content)
+	}
+	server.Next()
+	if content, _ = mod.request(context.Background(), alice, "sandstorm", false); !strings.Contains(content, ErrTooManyRequests.Error()) {
+		t.Errorf("Requests should be rate limited, got %q", content)
+	}
+	if content, _ = mod.request(context.Background(), alice, "sandstorm", true); !strings.Contains(content, "Queued") {
+		t.Errorf("Crew should be able to bypass the rate limit, got %q", content)
+	}
+}
+
+func TestSkipThreshold(t *testing.T) {
+	tests := []struct {
+		listeners, minVotes int
+		ratio               float64
+		want                int
+	}{
+		{listeners: 0, minVotes: 0, ratio: 0.5, want: 1},
+		{listeners: 1, minVotes: 2, ratio: 0.5, want: 2},
+		{listeners: 3, minVotes: 2, ratio: 0.5, want: 2},
+		{listeners: 10, minVotes: 2, ratio: 0.5, want: 5},
+		{listeners: 11, minVotes: 2, ratio: 0.5, want: 6},
+		{listeners: 10, minVotes: 8, ratio: 0.5, want: 8},
+		{listeners: 10, minVotes: 0, ratio: 1, want: 10},
+	}
+	for _, test := range tests {
+		if got := skipThreshold(test.listeners, test.ratio, test.minVotes); got != test.want {
+			t.Errorf("skipThreshold(%d, %v, %d) = %d, want %d", test.listeners, test.ratio, test.minVotes, got, test.want)
+		}
+	}
+}
+
+func TestVoteSkip(t *testing.T) {
+	mod, server := newTestMusicParty(t)
+	config.RuntimeConfig.Music.Skip.Ratio = 0.5
+	config.RuntimeConfig.Music.Skip.MinVotes = 2
+	config.RuntimeConfig.Music.Skip.ActiveWindow = time.Hour
+	users := []*discordgo.User{{ID: "1", Username: "alice"}, {ID: "2", Username: "bob"}, {ID: "3", Username: "carol"}}
+
+	// Somebody listening alone can't skip songs.
+	server.SetPlaying(&testCatalogue[0])
+	mod.markActive(users[0].ID)
+	if content, _ := mod.voteSkip(context.Background(), users[0]); !strings.Contains(content, ErrTooFewListeners.Error()) {
+		t.Errorf("A lone listener shouldn't be able to skip, got %q", content)
+	}
+	server.SetPlaying(nil)
+	for _, u := range users {
+		mod.markActive(u.ID)
+	}
+
+	if content, _ := mod.voteSkip(context.Background(), users[0]); !strings.Contains(content, ErrNothingPlaying.Error()) {
+		t.Errorf("Can't skip when nothing's playing, got %q", content)
+	}
+	server.SetPlaying(&testCatalogue[0])
+	if content, _ := mod.voteSkip(context.Background(), users[0]); !strings.Contains(content, "1 of 2") {
+		t.Errorf("First vote shouldn't skip, got %q", content)
+	}
+	if content, _ := mod.voteSkip(context.Background(), users[0]); !strings.Contains(content, "already voted") {
+		t.Errorf("Users should only be able to vote once, got %q", content)
+	}
+	if content, _ := mod.voteSkip(context.Background(), users[1]); !strings.Contains(content, "skipped") {
+		t.Errorf("Second vote should skip, got %q", content)
+	}
+	if content, _ := mod.nowPlaying(context.Background()); !strings.Contains(content, "Nothing's playing") {
+		t.Errorf("Song should have been skipped, got %q", content)
+	}
+
+	// Votes don't carry over to the next song.
+	server.SetPlaying(&testCatalogue[1])
+	if content, _ := mod.voteSkip(context.Background(), users[0]); !strings.Contains(content, "1 of 2") {
+		t.Errorf("Votes should reset for a new song, got %q", content)
+	}
+}
+
+func TestObserveTrack(t *testing.T) {
+	mod, _ := newTestMusicParty(t)
+	sandstorm := playedTrack{Title: "Sandstorm", Artist: "Darude"}
+	rick := playedTrack{Title: "Never Gonna Give You Up", Artist: "Rick Astley", RequestedBy: "alice"}
+
+	for _, track := range []playedTrack{sandstorm, sandstorm, rick, rick, {}, rick} {
+		mod.observeTrack(track)
+	}
+	history, err := mod.history()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Songs are only recorded when they change (or start again after a break).
+	if len(history) != 3 || history[0].Title != "Sandstorm" || history[1].Title != rick.Title || history[2].Title != rick.Title {
+		t.Fatalf("Unexpected history %+v", history)
+	}
+	if history[1].RequestedBy != "alice" || history[1].PlayedAt.IsZero() {
+		t.Errorf("Expected the request and time to be recorded, got %+v", history[1])
+	}
+
+	// Restarting shouldn't record the song that was playing when we stopped.
+	restarted, err := New(nil, mod.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.observeTrack(rick)
+	if history, _ = restarted.history(); len(history) != 3 {
+		t.Errorf("Song playing over a restart shouldn't be recorded twice, got %+v", history)
+	}
+}
+
+func TestHistoryContent(t *testing.T) {
+	if content := historyContent(nil); !strings.Contains(content, "Nothing's been played") {
+		t.Errorf("Empty history should say so, got %q", content)
+	}
+	var history []playedTrack
+	for len(history) < historyListLength+3 {
+		history = append(history, playedTrack{Title: "Sandstorm", Artist: "Darude"})
+	}
+	history = append(history, playedTrack{Title: "Never Gonna Give You Up", Artist: "Rick Astley", RequestedBy: "alice"})
+	content := historyContent(history)
+	if strings.Count(content, "\n- ") != historyListLength {
+		t.Errorf("Expected %d songs to be listed, got %q", historyListLength, content)
+	}
+	if strings.Index(content, "Rick Astley") > strings.Index(content, "Darude") || !strings.Contains(content, "requested by alice") {
+		t.Errorf("Expected the newest song first, got %q", content)
+	}
+}
+
+func TestTopTracks(t *testing.T) {
+	sandstorm := playedTrack{Title: "Sandstorm", Artist: "Darude"}
+	rick := playedTrack{Title: "Never Gonna Give You Up", Artist: "Rick Astley"}
+	frog := playedTrack{Title: "Axel F", Artist: "Crazy Frog"}
+	history := []playedTrack{frog, rick, sandstorm, rick, {Title: "sandstorm ", Artist: "DARUDE"}, frog, rick}
+
+	top := topTracks(history, 2)
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 top tracks, got %+v", top)
+	}
+	if top[0].Title != rick.Title || top[0].Plays != 3 {
+		t.Errorf("Expected Never Gonna Give You Up to be top, got %+v", top[0])
+	}
+	// Ties go to whichever song was played first.
+	if top[1].Title != frog.Title || top[1].Plays != 2 {
+		t.Errorf("Expected Axel F to be second, got %+v", top[1])
+	}
+	if embed := topTracksEmbed(history, 10); !strings.Contains(embed.Footer.Text, "7 songs played") {
+		t.Errorf("Unexpected summary %q", embed.Footer.Text)
+	}
+}
+
+func TestShouldAnnounce(t *testing.T) {
+	mod, _ := newTestMusicParty(t)
+	config.RuntimeConfig.Music.Announce.MinInterval = time.Minute
+	config.RuntimeConfig.Music.Announce.ChannelID = ""
+	now := time.Now()
+	if mod.shouldAnnounce(now) {
+		t.Error("Shouldn't announce without a channel")
+	}
+
+	config.RuntimeConfig.Music.Announce.ChannelID = "123"
+	t.Cleanup(func() { config.RuntimeConfig.Music.Announce.ChannelID = "" })
+	mod.discord = &discordgo.Session{}
+	if !mod.shouldAnnounce(now) {
+		t.Error("First song should be announced")
+	}
+	if mod.shouldAnnounce(now.Add(30 * time.Second)) {
+		t.Error("Announcements should be rate limited")
+	}
+	if !mod.shouldAnnounce(now.Add(time.Minute)) {
+		t.Error("Announcements should resume after the interval")
+	}
+}
@@ -0,0 +1,92 @@
+package musicparty
+
+import (
+	"context"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	mpclient "github.com/thebiggame/bigbot/pkg/musicparty"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// This file handles people asking for songs to be played.
+
+// recordRequest notes that the given user requested a song. mod.mtx must be held.
+func (mod *MusicParty) recordRequest(userID string) {
+	mod.requests[userID] = append(mod.requests[userID], time.Now())
+}
+
+// tooManyRequests returns whether the given user has requested too many songs recently. mod.mtx must be held.
+func (mod *MusicParty) tooManyRequests(userID string) bool {
+	limit := config.RuntimeConfig.Music.Requests.Limit
+	if limit <= 0 {
+		return false
+	}
+	var recent []time.Time
+	for _, t := range mod.requests[userID] {
+		if time.Since(t) < config.RuntimeConfig.Music.Requests.Window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		delete(mod.requests, userID)
+	} else {
+		mod.requests[userID] = recent
+	}
+	return len(recent) >= limit
+}
+
+// request adds the best match for the given query to the queue.
+// Crew can bypass the rate limit, but nobody can queue a song that's already playing or queued.
+func (mod *MusicParty) request(ctx context.Context, u *discordgo.User, query string, bypassLimit bool) (content string, err error) {
+	if mod.client == nil {
+		return fmt.Sprintf("⚠️ %s.", ErrMusicPartyUnavailable), nil
+	}
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	if !bypassLimit && mod.tooManyRequests(u.ID) {
+		return fmt.Sprintf("⏳ %s (you can request %d every %s).", ErrTooManyRequests, config.RuntimeConfig.Music.Requests.Limit, config.RuntimeConfig.Music.Requests.Window), nil
+	}
+
+	tracks, err := mod.client.Search(ctx, strings.TrimSpace(query))
+	if err != nil {
+		return "", err
+	}
+	if len(tracks) == 0 {
+		return fmt.Sprintf("🤷 %s.", ErrNoMatchingTracks), nil
+	}
+	track := tracks[0]
+
+	np, err := mod.client.NowPlaying(ctx)
+	if err != nil {
+		return "", err
+	}
+	if np.Track != nil && np.Track.ID == track.ID {
+		return fmt.Sprintf("🎵 %s: **%s** / %s.", ErrAlreadyPlaying, track.Title, track.Artist), nil
+	}
+	queue, err := mod.client.Queue(ctx)
+	if err != nil {
+		return "", err
+	}
+	if idx := queuePosition(queue, track.ID); idx != -1 {
+		return fmt.Sprintf("📋 %s: **%s** / %s is number %d.", ErrAlreadyQueued, track.Title, track.Artist, idx+1), nil
+	}
+
+	track, err = mod.client.Enqueue(ctx, track.ID, u.Username)
+	if err != nil {
+		return "", err
+	}
+	mod.recordRequest(u.ID)
+	mod.logger.Info("Song requested", slog.String("user", u.Username), slog.String("title", track.Title), slog.String("artist", track.Artist), slog.Bool("bypass_limit", bypassLimit))
+	return fmt.Sprintf("🙋 Queued %s at number %d.", describeTrack(track), len(queue)+1), nil
+}
+
+// queuePosition returns where the track with the given ID is in the queue, or -1 if it isn't queued.
+func queuePosition(queue []mpclient.Track, trackID string) int {
+	return slices.IndexFunc(queue, func(track mpclient.Track) bool {
+		return track.ID == trackID
+	})
+}
@@ -0,0 +1,105 @@
+package musicparty
+
+import (
+	"context"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	"github.com/thebiggame/bigbot/internal/config"
+	mpclient "github.com/thebiggame/bigbot/pkg/musicparty"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// This file handles voting to skip songs.
+
+// markActive notes that the given user is listening (i.e. they just used /music).
+func (mod *MusicParty) markActive(userID string) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	mod.active[userID] = time.Now()
+}
+
+// activeListeners returns how many people have used /music recently. mod.mtx must be held.
+func (mod *MusicParty) activeListeners() (count int) {
+	for userID, t := range mod.active {
+		if time.Since(t) >= config.RuntimeConfig.Music.Skip.ActiveWindow {
+			delete(mod.active, userID)
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// skipThreshold returns how many votes are needed to skip a song, given how many people are listening.
+// This is the configured fraction of listeners, but always at least minVotes (so that nobody can skip songs alone).
+func skipThreshold(listeners int, ratio float64, minVotes int) int {
+	needed := int(math.Ceil(ratio * float64(listeners)))
+	return max(needed, minVotes, 1)
+}
+
+// votesNeeded returns how many votes are needed to skip the current song. mod.mtx must be held.
+func (mod *MusicParty) votesNeeded() int {
+	return skipThreshold(mod.activeListeners(), config.RuntimeConfig.Music.Skip.Ratio, config.RuntimeConfig.Music.Skip.MinVotes)
+}
+
+// resetVotes starts a new vote if the track has changed since the last one. mod.mtx must be held.
+func (mod *MusicParty) resetVotes(np mpclient.NowPlaying) {
+	trackID := ""
+	if np.Track != nil {
+		trackID = np.Track.ID
+	}
+	if trackID != mod.voteTrack {
+		mod.voteTrack = trackID
+		clear(mod.votes)
+	}
+}
+
+// skipData returns the state of the vote, as the infoboard expects it. mod.mtx must be held.
+func (mod *MusicParty) skipData() ngtbg.NodeCGReplicantDataMusicSkip {
+	return ngtbg.NodeCGReplicantDataMusicSkip{
+		Votes:  len(mod.votes),
+		Needed: mod.votesNeeded(),
+	}
+}
+
+// voteSkip records the given user's vote to skip the current song, skipping it if enough people have voted.
+func (mod *MusicParty) voteSkip(ctx context.Context, u *discordgo.User) (content string, err error) {
+	if mod.client == nil {
+		return fmt.Sprintf("⚠️ %s.", ErrMusicPartyUnavailable), nil
+	}
+	np, err := mod.client.NowPlaying(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !np.Playing || np.Track == nil {
+		return fmt.Sprintf("🔇 %s.", ErrNothingPlaying), nil
+	}
+
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	mod.resetVotes(np)
+	if mod.activeListeners() < config.RuntimeConfig.Music.Skip.MinVotes {
+		// The vote could never pass.
+		return fmt.Sprintf("⚠️ %s.", ErrTooFewListeners), nil
+	}
+	needed := mod.votesNeeded()
+	if mod.votes[u.ID] {
+		return fmt.Sprintf("🗳️ You've already voted to skip **%s** (%d of %d votes).", np.Track.Title, len(mod.votes), needed), nil
+	}
+	mod.votes[u.ID] = true
+	if len(mod.votes) < needed {
+		mod.logger.Info("Vote to skip song", slog.String("user", u.Username), slog.String("title", np.Track.Title), slog.Int("votes", len(mod.votes)), slog.Int("needed", needed))
+		return fmt.Sprintf("🗳️ Voted to skip **%s** (%d of %d votes).", np.Track.Title, len(mod.votes), needed), nil
+	}
+
+	if err = mod.client.Skip(ctx); err != nil {
+		return "", err
+	}
+	mod.logger.Info("Song vote-skipped", slog.String("title", np.Track.Title), slog.Int("votes", len(mod.votes)))
+	mod.voteTrack = ""
+	clear(mod.votes)
+	return fmt.Sprintf("⏭️ Enough people voted, so **%s** was skipped.", np.Track.Title), nil
+}
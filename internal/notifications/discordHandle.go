@@ -4,7 +4,6 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
 	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
-	"github.com/thebiggame/bigbot/internal/config"
 	"github.com/thebiggame/bigbot/internal/helpers"
 	"log/slog"
 	"strings"
@@ -67,6 +66,14 @@ func (mod *Notifications) DiscordHandleMessage(session *discordgo.Session, messa
 	return nil
 }
 
+func (mod *Notifications) DiscordHandleMessageUpdate(session *discordgo.Session, message *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *Notifications) DiscordHandleMessageDelete(session *discordgo.Session, message *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
 func (mod *Notifications) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
@@ -104,7 +111,7 @@ func (mod *Notifications) DiscordHandleInteraction(s *discordgo.Session, i *disc
 			if optionMap["delay"] != nil {
 				delay = optionMap["delay"].UintValue()
 			}
-			err := bridge_wan.EventBridge.BrReplicantSet(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGReplicantNotificationAlertData, ngtbg.NodeCGReplicantDataAlertData{
+			err := bridge_wan.ReplicantSet(ngtbg.ReplicantNotificationAlertData, ngtbg.NodeCGReplicantDataAlertData{
 				Body:  name,
 				Flair: flair,
 				Delay: int(delay),
@@ -112,7 +119,7 @@ func (mod *Notifications) DiscordHandleInteraction(s *discordgo.Session, i *disc
 			if err != nil {
 				return true, err
 			}
-			err = bridge_wan.EventBridge.BrReplicantSet(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGReplicantNotificationAlertActive, true)
+			err = bridge_wan.ReplicantSet(ngtbg.ReplicantNotificationAlertActive, true)
 			if err != nil {
 				return true, err
 			}
@@ -123,7 +130,7 @@ func (mod *Notifications) DiscordHandleInteraction(s *discordgo.Session, i *disc
 			if helpers.DiscordDeferEphemeralInteraction(s, i) != nil {
 				return true, err
 			}
-			err = bridge_wan.EventBridge.BrReplicantSet(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGReplicantNotificationAlertActive, false)
+			err = bridge_wan.ReplicantSet(ngtbg.ReplicantNotificationAlertActive, false)
 			if err != nil {
 				return true, err
 			}
@@ -158,7 +165,7 @@ func (mod *Notifications) DiscordHandleInteraction(s *discordgo.Session, i *disc
 			if helpers.DiscordDeferEphemeralInteraction(s, i) != nil {
 				return true, err
 			}
-			err := bridge_wan.EventBridge.BrReplicantSet(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGReplicantEventInfoActive, false)
+			err := bridge_wan.ReplicantSet(ngtbg.ReplicantEventInfoActive, false)
 			if err != nil {
 				return true, err
 			}
@@ -183,13 +190,13 @@ func (mod *Notifications) DiscordHandleInteraction(s *discordgo.Session, i *disc
 			name := data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
 
 			// First attempt to set the information body.
-			err := bridge_wan.EventBridge.BrReplicantSet(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGReplicantEventInfoBody, name)
+			err := bridge_wan.ReplicantSet(ngtbg.ReplicantEventInfoBody, name)
 			if err != nil {
 				// NodeCG not available for some reason.
 				logger.Info("NodeCG not available", slog.Any("error", err))
 			} else {
 				// Then set it to active (plays the announcement chime & displays it)
-				err = bridge_wan.EventBridge.BrReplicantSet(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGReplicantEventInfoActive, true)
+				err = bridge_wan.ReplicantSet(ngtbg.ReplicantEventInfoActive, true)
 				if err != nil {
 					return true, err
 				}
@@ -0,0 +1,12 @@
+package polls
+
+import "errors"
+
+var ErrTooFewOptions = errors.New("Polls need at least 2 options, separated by commas")
+var ErrTooManyOptions = errors.New("Polls can have at most 10 options")
+var ErrOptionTooLong = errors.New("Poll options must be 80 characters or fewer")
+var ErrDuplicateOption = errors.New("Poll options must all be different")
+var ErrBadDuration = errors.New("That isn't a duration BIGbot understands. Try something like 10m or 1h30m")
+var ErrDurationOutOfRange = errors.New("Polls must stay open for at least a minute, and no longer than the maximum")
+var ErrUnknownPoll = errors.New("There's no poll with that number")
+var ErrPollClosed = errors.New("This poll has closed")
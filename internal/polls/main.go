@@ -0,0 +1,371 @@
+package polls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Vote buttons are followed by the poll number and the option's index, e.g. bigbot_poll_vote_3_1.
+	voteCustomIDPrefix = "bigbot_poll_vote_"
+
+	// How often to check whether any polls have finished.
+	checkInterval = 5 * time.Second
+)
+
+// logger stores the module's logger instance.
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+type Polls struct {
+	discord *discordgo.Session
+
+	// Persistent storage for polls (and their votes).
+	store *storage.Store
+
+	// Held while changing polls, so that votes aren't lost when several people vote at once.
+	mtx sync.Mutex
+
+	// When we last checked for polls that have finished (see closeDuePolls).
+	lastCheck time.Time
+}
+
+var commands = []*discordgo.ApplicationCommand{
+	{
+		Name:                     "poll",
+		Description:              "📊🛠️ Run polls for the attendees (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "create",
+				Description: "📊 Ask a question in this channel. Results are shown on the infoboard.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "question",
+						Description: "What to ask, e.g. Which game next?",
+						Required:    true,
+						MaxLength:   200,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "options",
+						Description: "The answers to choose from, separated by commas, e.g. CS2, Rocket League, Trackmania",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "duration",
+						Description: "How long voting stays open, e.g. 10m or 1h30m",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Name:        "close",
+				Description: "🔒 End a poll early, and announce the results.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "number",
+						Description: "The poll's number",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Description: "📋 List the open polls.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+		},
+	},
+}
+
+func New(discord *discordgo.Session, store *storage.Store) (mod *Polls, err error) {
+	return &Polls{
+		discord: discord,
+		store:   store,
+	}, nil
+}
+
+func (mod *Polls) SetLogger(log *slog.Logger) {
+	logger = log
+}
+
+func (mod *Polls) DiscordCommands() ([]*discordgo.ApplicationCommand, error) {
+	return commands, nil
+}
+
+func (mod *Polls) Start(ctx context.Context) (err error) {
+	// Make sure the infoboard has the latest results whenever the bridge (re)connects.
+	bridge_wan.OnConnect(mod.publish)
+	return mod.watch(ctx)
+}
+
+// watch closes polls as they finish (including any that finished while we were away).
+func (mod *Polls) watch(ctx context.Context) error {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		mod.closeDuePolls(mod.discord, time.Now())
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (mod *Polls) DiscordHandleMessage(_ *discordgo.Session, _ *discordgo.MessageCreate) (err error) {
+	return nil
+}
+
+func (mod *Polls) DiscordHandleMessageUpdate(_ *discordgo.Session, _ *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *Polls) DiscordHandleMessageDelete(_ *discordgo.Session, _ *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
+func (mod *Polls) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		if i.ApplicationCommandData().Name == "poll" {
+			return true, mod.discordHandlePollCommand(s, i)
+		}
+	case discordgo.InteractionMessageComponent:
+		if strings.HasPrefix(i.MessageComponentData().CustomID, voteCustomIDPrefix) {
+			return true, mod.discordHandleVote(s, i)
+		}
+	}
+	return false, nil
+}
+
+func (mod *Polls) discordHandlePollCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range options[0].Options {
+		values[option.Name] = option
+	}
+
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+	var content string
+	switch options[0].Name {
+	case "create":
+		var duration string
+		if option, ok := values["duration"]; ok {
+			duration = strings.TrimSpace(option.StringValue())
+		}
+		content, err = mod.createPoll(s, i.ChannelID, i.Member.User, values["question"].StringValue(), values["options"].StringValue(), duration, time.Now())
+	case "close":
+		content, err = mod.adminClose(s, int(values["number"].IntValue()), time.Now())
+	case "list":
+		content, err = mod.adminList(i.GuildID)
+	default:
+		content = "😶 Please use a sub-command."
+	}
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		logger.Error("Poll command failed", slog.String("action", options[0].Name), slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		content = helpers.DiscordErrorContent(s, i, err)
+	}
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logger.Error("Unable to send poll command response", slog.Any("error", err))
+	}
+	return nil
+}
+
+// createPoll posts a new poll in the given channel.
+func (mod *Polls) createPoll(s *discordgo.Session, channelID string, u *discordgo.User, question, optionList, durationValue string, now time.Time) (content string, err error) {
+	options, err := parseOptions(optionList)
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	}
+	duration, err := parseDuration(durationValue, config.RuntimeConfig.Polls.DefaultDuration, config.RuntimeConfig.Polls.MaxDuration)
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	}
+
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	p := poll{
+		Number:    len(mod.store.Keys(pollsBucket)) + 1,
+		Question:  strings.TrimSpace(question),
+		Options:   options,
+		CreatedBy: u.ID,
+		CreatedAt: now,
+		EndsAt:    now.Add(duration),
+		ChannelID: channelID,
+		Votes:     make(map[string]int),
+	}
+	msg, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{pollEmbed(p)},
+		Components: pollComponents(p),
+	})
+	if err != nil {
+		return "", err
+	}
+	p.MessageID = msg.ID
+	if err = mod.savePoll(p); err != nil {
+		return "", err
+	}
+	logger.Info("Poll created", slog.Int("number", p.Number), slog.String("question", p.Question), slog.String("user", u.Username))
+	go mod.publish()
+	return fmt.Sprintf("📊 Poll #%d is open until <t:%d:t>.", p.Number, p.EndsAt.Unix()), nil
+}
+
+// discordHandleVote records somebody's vote (replacing any vote they'd already made in that poll).
+func (mod *Polls) discordHandleVote(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.Member == nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 Polls can only be voted in from the server.")
+	}
+	number, option, err := parseVoteCustomID(i.MessageComponentData().CustomID)
+	if err != nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", ErrUnknownPoll))
+	}
+
+	p, content, err := mod.castVote(number, i.Member.User.ID, option)
+	if errors.Is(err, ErrUnknownPoll) || errors.Is(err, ErrPollClosed) {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", err))
+	} else if err != nil {
+		return err
+	}
+	go mod.publish()
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{pollEmbed(p)},
+			Components: pollComponents(p),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	return err
+}
+
+// castVote records the given user's vote in a stored poll, returning the updated poll and what to tell them.
+// The poll is only locked while it's being changed, so that a burst of votes isn't held up by Discord.
+func (mod *Polls) castVote(number int, userID string, option int) (p poll, content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	p, err = mod.getPoll(number)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && option >= len(p.Options)) {
+		return p, "", ErrUnknownPoll
+	} else if err != nil {
+		return p, "", err
+	}
+	if !p.Open() {
+		return p, "", ErrPollClosed
+	}
+	content = vote(&p, userID, option)
+	return p, content, mod.savePoll(p)
+}
+
+// vote records the given user's vote in a poll, returning what to tell them.
+func vote(p *poll, userID string, option int) string {
+	previous, voted := p.Votes[userID]
+	p.Votes[userID] = option
+	switch {
+	case !voted:
+		return fmt.Sprintf("🗳️ You voted for **%s**. You can change your vote until the poll closes.", p.Options[option])
+	case previous == option:
+		return fmt.Sprintf("🤷 You've already voted for **%s**.", p.Options[option])
+	default:
+		return fmt.Sprintf("🗳️ You changed your vote from **%s** to **%s**.", p.Options[previous], p.Options[option])
+	}
+}
+
+// parseVoteCustomID reads the poll number and option index from a vote button's custom ID.
+func parseVoteCustomID(customID string) (number, option int, err error) {
+	numberValue, optionValue, _ := strings.Cut(strings.TrimPrefix(customID, voteCustomIDPrefix), "_")
+	if number, err = strconv.Atoi(numberValue); err != nil {
+		return 0, 0, err
+	}
+	if option, err = strconv.Atoi(optionValue); err != nil || option < 0 {
+		return 0, 0, ErrUnknownPoll
+	}
+	return number, option, nil
+}
+
+func (mod *Polls) adminClose(s *discordgo.Session, number int, now time.Time) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	p, err := mod.getPoll(number)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Sprintf("⚠️ %s.", ErrUnknownPoll), nil
+	} else if err != nil {
+		return "", err
+	}
+	if !p.Open() {
+		return fmt.Sprintf("⚠️ %s.", ErrPollClosed), nil
+	}
+	if err = mod.closePoll(s, &p, now); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🔒 Closed poll #%d. %s", p.Number, resultSummary(p)), nil
+}
+
+func (mod *Polls) adminList(guildID string) (content string, err error) {
+	polls, err := mod.listPolls()
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, p := range polls {
+		if !p.Open() {
+			continue
+		}
+		line := fmt.Sprintf("- #%d [%s](https://discord.com/channels/%s/%s/%s) (%d %s, closes <t:%d:R>)\n",
+			p.Number, p.Question, guildID, p.ChannelID, p.MessageID, len(p.Votes), helpers.Pluralise(len(p.Votes), "vote", "votes"), p.EndsAt.Unix())
+		// Discord limits messages to 2000 characters.
+		if sb.Len()+len(line) > 1900 {
+			sb.WriteString("…and more.")
+			break
+		}
+		sb.WriteString(line)
+	}
+	if sb.Len() == 0 {
+		return "📊 There are no open polls.", nil
+	}
+	return "📊 Open polls:\n" + sb.String(), nil
+}
+
+var defaultCrewCommandPermissions int64 = discordgo.PermissionAdministrator
+var defaultCrewCommandDMPermissions = false
@@ -0,0 +1,206 @@
+package polls
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/storage/storagetest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testCreatedAt = time.Date(2024, 8, 23, 18, 0, 0, 0, time.UTC)
+
+func testPoll(votes map[string]int) poll {
+	return poll{
+		Number:    1,
+		Question:  "Which game next?",
+		Options:   []string{"CS2", "Rocket League", "Trackmania"},
+		CreatedAt: testCreatedAt,
+		EndsAt:    testCreatedAt.Add(10 * time.Minute),
+		Votes:     votes,
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	options, err := parseOptions(" CS2, Rocket League ,,Trackmania ")
+	if err != nil || len(options) != 3 || options[1] != "Rocket League" {
+		t.Errorf("Unexpected options %q (%v)", options, err)
+	}
+	tests := []struct {
+		list string
+		want error
+	}{
+		{list: "CS2", want: ErrTooFewOptions},
+		{list: "CS2, , ", want: ErrTooFewOptions},
+		{list: "CS2, cs2", want: ErrDuplicateOption},
+		{list: "a,b,c,d,e,f,g,h,i,j,k", want: ErrTooManyOptions},
+		{list: "CS2, " + strings.Repeat("x", 81), want: ErrOptionTooLong},
+	}
+	for _, test := range tests {
+		if _, err := parseOptions(test.list); !errors.Is(err, test.want) {
+			t.Errorf("parseOptions(%q) = %v, want %v", test.list, err, test.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		err   error
+	}{
+		{value: "", want: 10 * time.Minute},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "5", want: 5 * time.Minute},
+		{value: "30s", err: ErrDurationOutOfRange},
+		{value: "25h", err: ErrDurationOutOfRange},
+		{value: "soon", err: ErrBadDuration},
+	}
+	for _, test := range tests {
+		got, err := parseDuration(test.value, 10*time.Minute, 24*time.Hour)
+		if got != test.want || !errors.Is(err, test.err) {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v, %v", test.value, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestVote(t *testing.T) {
+	p := testPoll(make(map[string]int))
+	if content := vote(&p, "10", 0); !strings.Contains(content, "You voted for **CS2**") {
+		t.Errorf("Unexpected response to first vote: %q", content)
+	}
+	if content := vote(&p, "10", 0); !strings.Contains(content, "already voted") {
+		t.Errorf("Unexpected response to repeated vote: %q", content)
+	}
+	if content := vote(&p, "10", 2); !strings.Contains(content, "from **CS2** to **Trackmania**") {
+		t.Errorf("Unexpected response to changed vote: %q", content)
+	}
+	// Everybody only gets one vote.
+	if counts := p.Counts(); len(p.Votes) != 1 || counts[0] != 0 || counts[2] != 1 {
+		t.Errorf("Expected one vote for Trackmania, got %v", counts)
+	}
+}
+
+func TestParseVoteCustomID(t *testing.T) {
+	p := testPoll(nil)
+	p.Number = 12
+	buttons := pollComponents(p)[0].(discordgo.ActionsRow).Components
+	number, option, err := parseVoteCustomID(buttons[2].(discordgo.Button).CustomID)
+	if err != nil || number != 12 || option != 2 {
+		t.Errorf("Expected poll 12 option 2, got %d, %d (%v)", number, option, err)
+	}
+	if _, _, err = parseVoteCustomID(voteCustomIDPrefix + "12"); err == nil {
+		t.Error("Expected a custom ID without an option to be rejected")
+	}
+}
+
+func TestResults(t *testing.T) {
+	tests := []struct {
+		votes map[string]int
+		want  string
+	}{
+		{votes: map[string]int{}, want: "Nobody voted"},
+		{votes: map[string]int{"10": 1, "11": 1, "12": 0}, want: "**Rocket League** won, with 2 votes (67%)"},
+		{votes: map[string]int{"10": 1, "11": 2}, want: "tie between **Rocket League** and **Trackmania**"},
+	}
+	for _, test := range tests {
+		if summary := resultSummary(testPoll(test.votes)); !strings.Contains(summary, test.want) {
+			t.Errorf("Expected %q in %q", test.want, summary)
+		}
+	}
+}
+
+func TestPollMessage(t *testing.T) {
+	p := testPoll(map[string]int{"10": 1, "11": 1, "12": 0, "13": 2})
+	embed := pollEmbed(p)
+	if !strings.Contains(embed.Description, "`█████░░░░░` 50% (2)") || !strings.Contains(embed.Fields[0].Value, "Closes") {
+		t.Errorf("Unexpected embed for open poll: %q, %q", embed.Description, embed.Fields[0].Value)
+	}
+
+	p.Options = append(p.Options, "Tetris", "Worms", "Halo")
+	rows := pollComponents(p)
+	if len(rows) != 2 || len(rows[0].(discordgo.ActionsRow).Components) != 5 || len(rows[1].(discordgo.ActionsRow).Components) != 1 {
+		t.Errorf("Expected buttons five to a row, got %+v", rows)
+	}
+
+	p.ClosedAt = p.EndsAt
+	if embed = pollEmbed(p); !strings.Contains(embed.Description, "🏆 Rocket League") || !strings.Contains(embed.Fields[0].Value, "Closed") {
+		t.Errorf("Closed poll should show the winner, got %q", embed.Description)
+	}
+	if rows = pollComponents(p); len(rows) != 0 {
+		t.Errorf("Closed poll shouldn't have any buttons, got %d rows", len(rows))
+	}
+}
+
+func TestPollsReplicant(t *testing.T) {
+	config.RuntimeConfig.Polls.ResultsFor = 5 * time.Minute
+	open := testPoll(map[string]int{"10": 1})
+	recent := testPoll(map[string]int{"10": 0, "11": 0})
+	recent.Number, recent.ClosedAt = 2, testCreatedAt.Add(-time.Minute)
+	old := testPoll(nil)
+	old.Number, old.ClosedAt = 3, testCreatedAt.Add(-time.Hour)
+
+	data := pollsReplicant([]poll{open, recent, old}, testCreatedAt)
+	if len(data.Polls) != 2 || data.Polls[0].Closed || !data.Polls[1].Closed {
+		t.Fatalf("Expected the open poll and the recently closed poll, got %+v", data.Polls)
+	}
+	if options := data.Polls[0].Options; data.Polls[0].TotalVotes != 1 || options[1].Label != "Rocket League" || options[1].Votes != 1 {
+		t.Errorf("Unexpected results %+v", data.Polls[0])
+	}
+	if data.Polls[1].EndsAt != recent.ClosedAt.Format(time.RFC3339) {
+		t.Errorf("Closed polls should say when they closed, got %q", data.Polls[1].EndsAt)
+	}
+	if data = pollsReplicant(nil, testCreatedAt); data.Polls == nil {
+		t.Error("Expected an empty list when there are no polls")
+	}
+}
+
+func TestListPolls(t *testing.T) {
+	store := storagetest.Open(t)
+	mod, err := New(nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, number := range []int{10, 2, 1} {
+		p := testPoll(nil)
+		p.Number = number
+		if err = mod.savePoll(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	polls, err := mod.listPolls()
+	if err != nil || len(polls) != 3 || polls[0].Number != 1 || polls[2].Number != 10 {
+		t.Errorf("Expected polls in number order, got %+v (%v)", polls, err)
+	}
+}
+
+func TestCastVote(t *testing.T) {
+	mod, err := New(nil, storagetest.Open(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := testPoll(make(map[string]int))
+	closed := testPoll(make(map[string]int))
+	closed.Number, closed.ClosedAt = 2, testCreatedAt
+	for _, p := range []poll{open, closed} {
+		if err = mod.savePoll(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, content, err := mod.castVote(open.Number, "10", 1)
+	if err != nil || !strings.Contains(content, "You voted for **Rocket League**") || p.Votes["10"] != 1 {
+		t.Errorf("Expected the vote to be counted, got %q (%v)", content, err)
+	}
+	if stored, _ := mod.getPoll(open.Number); stored.Votes["10"] != 1 {
+		t.Errorf("Expected the vote to be saved, got %v", stored.Votes)
+	}
+	if _, _, err = mod.castVote(closed.Number, "10", 1); !errors.Is(err, ErrPollClosed) {
+		t.Errorf("Expected votes in closed polls to be refused, got %v", err)
+	}
+	if _, _, err = mod.castVote(open.Number, "10", 7); !errors.Is(err, ErrUnknownPoll) {
+		t.Errorf("Expected votes for options that don't exist to be refused, got %v", err)
+	}
+}
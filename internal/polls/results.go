@@ -0,0 +1,206 @@
+package polls
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file handles showing polls: the poll message in Discord, the results post when a poll closes, and the
+// polls:current replicant that the infoboard shows live results from.
+
+// How many characters wide the results bars are.
+const barWidth = 10
+
+// closeDuePolls closes any polls whose time is up, and takes results that have been shown for long enough off the
+// infoboard.
+func (mod *Polls) closeDuePolls(s *discordgo.Session, now time.Time) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	lastCheck := mod.lastCheck
+	mod.lastCheck = now
+	polls, err := mod.listPolls()
+	if err != nil {
+		logger.Error("Unable to list polls", slog.Any("error", err))
+		return
+	}
+	var expired bool
+	for _, p := range polls {
+		if p.Open() && !now.Before(p.EndsAt) {
+			if err = mod.closePoll(s, &p, now); err != nil {
+				logger.Error("Unable to close poll", slog.Int("number", p.Number), slog.Any("error", err))
+			}
+			continue
+		}
+		resultsUntil := p.ClosedAt.Add(config.RuntimeConfig.Polls.ResultsFor)
+		if !p.Open() && resultsUntil.After(lastCheck) && !resultsUntil.After(now) {
+			expired = true
+		}
+	}
+	if expired {
+		go mod.publish()
+	}
+}
+
+// closePoll stops a poll taking votes, then announces the results. mod.mtx must be held.
+func (mod *Polls) closePoll(s *discordgo.Session, p *poll, now time.Time) (err error) {
+	p.ClosedAt = now
+	if err = mod.savePoll(*p); err != nil {
+		return err
+	}
+	logger.Info("Poll closed", slog.Int("number", p.Number), slog.Int("votes", len(p.Votes)))
+	go mod.publish()
+
+	// Replace the buttons with the final results. This is best-effort; the poll may have been deleted.
+	components := pollComponents(*p)
+	_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    p.ChannelID,
+		ID:         p.MessageID,
+		Embeds:     &[]*discordgo.MessageEmbed{pollEmbed(*p)},
+		Components: &components,
+	})
+	if err != nil {
+		logger.Warn("Unable to update poll message", slog.Int("number", p.Number), slog.Any("error", err))
+	}
+	_, err = s.ChannelMessageSendComplex(p.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("📊 Poll #%d has closed: **%s**\n%s", p.Number, p.Question, resultSummary(*p)),
+		Reference: &discordgo.MessageReference{
+			MessageID:       p.MessageID,
+			ChannelID:       p.ChannelID,
+			FailIfNotExists: new(bool),
+		},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// resultSummary describes who won a poll.
+func resultSummary(p poll) string {
+	winners := p.Winners()
+	switch len(winners) {
+	case 0:
+		return "🦗 Nobody voted."
+	case 1:
+		votes := slices.Max(p.Counts())
+		return fmt.Sprintf("🏆 **%s** won, with %d %s (%d%%).", winners[0], votes, helpers.Pluralise(votes, "vote", "votes"), percentage(votes, len(p.Votes)))
+	default:
+		return fmt.Sprintf("🤝 It's a tie between **%s**.", strings.Join(winners, "** and **"))
+	}
+}
+
+// percentage returns n as a (rounded) percentage of total.
+func percentage(n, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return (n*100 + total/2) / total
+}
+
+// pollEmbed shows a poll, with the results so far.
+func pollEmbed(p poll) *discordgo.MessageEmbed {
+	counts := p.Counts()
+	winners := p.Winners()
+	var sb strings.Builder
+	for idx, option := range p.Options {
+		label := option
+		if !p.Open() && slices.Contains(winners, option) {
+			label = "🏆 " + option
+		}
+		filled := 0
+		if len(p.Votes) > 0 {
+			filled = counts[idx] * barWidth / len(p.Votes)
+		}
+		sb.WriteString(fmt.Sprintf("**%s**\n`%s%s` %d%% (%d)\n",
+			label, strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), percentage(counts[idx], len(p.Votes)), counts[idx]))
+	}
+	status, colour := fmt.Sprintf("🟢 Closes <t:%d:R>", p.EndsAt.Unix()), 0x2E7D32
+	if !p.Open() {
+		status, colour = fmt.Sprintf("🔒 Closed <t:%d:R>", p.ClosedAt.Unix()), 0x7E8186
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "📊 " + p.Question,
+		Description: sb.String(),
+		Color:       colour,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Status", Value: status, Inline: true},
+			{Name: "Votes", Value: strconv.Itoa(len(p.Votes)), Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Poll #%d · one vote each, and you can change it until the poll closes", p.Number)},
+	}
+}
+
+// pollComponents returns the voting buttons for a poll, five to a row. Closed polls have none.
+func pollComponents(p poll) []discordgo.MessageComponent {
+	rows := []discordgo.MessageComponent{}
+	if !p.Open() {
+		return rows
+	}
+	for idx, option := range p.Options {
+		if idx%5 == 0 {
+			rows = append(rows, discordgo.ActionsRow{})
+		}
+		row := rows[len(rows)-1].(discordgo.ActionsRow)
+		row.Components = append(row.Components, discordgo.Button{
+			Label:    option,
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("%s%d_%d", voteCustomIDPrefix, p.Number, idx),
+		})
+		rows[len(rows)-1] = row
+	}
+	return rows
+}
+
+// pollsReplicant returns what the infoboard should show: open polls, and polls that closed recently enough for their
+// results to still be shown.
+func pollsReplicant(polls []poll, now time.Time) ngtbg.NodeCGReplicantDataPolls {
+	data := ngtbg.NodeCGReplicantDataPolls{
+		// NodeCG expects a list, even if it's empty.
+		Polls: []ngtbg.NodeCGReplicantDataPoll{},
+	}
+	for _, p := range polls {
+		if !p.Open() && !now.Before(p.ClosedAt.Add(config.RuntimeConfig.Polls.ResultsFor)) {
+			continue
+		}
+		entry := ngtbg.NodeCGReplicantDataPoll{
+			Number:     p.Number,
+			Question:   p.Question,
+			TotalVotes: len(p.Votes),
+			EndsAt:     p.EndsAt.Format(time.RFC3339),
+			Closed:     !p.Open(),
+		}
+		if !p.Open() {
+			entry.EndsAt = p.ClosedAt.Format(time.RFC3339)
+		}
+		for idx, count := range p.Counts() {
+			entry.Options = append(entry.Options, ngtbg.NodeCGReplicantDataPollOption{Label: p.Options[idx], Votes: count})
+		}
+		data.Polls = append(data.Polls, entry)
+	}
+	return data
+}
+
+// publish sends the current polls to NodeCG for the infoboard. This is best-effort; if the event bridge isn't
+// available, they'll be sent when it reconnects.
+func (mod *Polls) publish() {
+	if !bridge_wan.BridgeIsAvailable() {
+		logger.Debug("Event Bridge not available, not publishing polls")
+		return
+	}
+	polls, err := mod.listPolls()
+	if err != nil {
+		logger.Error("Unable to list polls", slog.Any("error", err))
+		return
+	}
+	err = bridge_wan.ReplicantSet(ngtbg.ReplicantPolls, pollsReplicant(polls, time.Now()))
+	if err != nil {
+		logger.Error("Unable to publish polls to NodeCG", slog.Any("error", err))
+	}
+}
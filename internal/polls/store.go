@@ -0,0 +1,141 @@
+package polls
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// The storage bucket polls are kept in, keyed on the poll's number.
+	pollsBucket = "polls"
+
+	// Discord allows 5 rows of 5 buttons, but more than 10 options is a survey rather than a poll.
+	maxOptions = 10
+	// Discord limits button labels to 80 characters.
+	maxOptionLength = 80
+	// The shortest a poll can be open for.
+	minDuration = time.Minute
+)
+
+// poll is one question put to the attendees.
+type poll struct {
+	// The poll's number, as shown to people.
+	Number int `json:"number"`
+	// What's being asked, and the answers to choose from.
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	// Who asked, and when voting ends.
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	// Where the poll (with its buttons) was posted.
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+	// Each voter's choice (an index into Options), keyed on user ID. Everybody gets one vote.
+	Votes map[string]int `json:"votes"`
+	// When voting ended, if it has. (Crew can end a poll early.)
+	ClosedAt time.Time `json:"closed_at,omitempty"`
+}
+
+// Open returns whether the poll is still taking votes.
+func (p poll) Open() bool {
+	return p.ClosedAt.IsZero()
+}
+
+// Counts returns how many votes each option has, in the same order as the options.
+func (p poll) Counts() []int {
+	counts := make([]int, len(p.Options))
+	for _, option := range p.Votes {
+		if option >= 0 && option < len(counts) {
+			counts[option]++
+		}
+	}
+	return counts
+}
+
+// Winners returns the options with the most votes (more than one if it's a tie, and none if nobody voted).
+func (p poll) Winners() (winners []string) {
+	counts := p.Counts()
+	most := slices.Max(counts)
+	if most == 0 {
+		return nil
+	}
+	for idx, count := range counts {
+		if count == most {
+			winners = append(winners, p.Options[idx])
+		}
+	}
+	return winners
+}
+
+// parseOptions splits a comma-separated list of poll options, checking there's a sensible number of them.
+func parseOptions(list string) (options []string, err error) {
+	for _, option := range strings.Split(list, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		if utf8.RuneCountInString(option) > maxOptionLength {
+			return nil, ErrOptionTooLong
+		}
+		if slices.ContainsFunc(options, func(existing string) bool { return strings.EqualFold(existing, option) }) {
+			return nil, ErrDuplicateOption
+		}
+		options = append(options, option)
+	}
+	if len(options) < 2 {
+		return nil, ErrTooFewOptions
+	}
+	if len(options) > maxOptions {
+		return nil, ErrTooManyOptions
+	}
+	return options, nil
+}
+
+// parseDuration reads how long a poll should stay open (e.g. "10m"), using the default if none was given.
+func parseDuration(value string, defaultDuration, maxDuration time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultDuration, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		// People will probably write "10" and mean minutes.
+		minutes, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, ErrBadDuration
+		}
+		duration = time.Duration(minutes) * time.Minute
+	}
+	if duration < minDuration || (maxDuration > 0 && duration > maxDuration) {
+		return 0, ErrDurationOutOfRange
+	}
+	return duration, nil
+}
+
+// getPoll fetches the poll with the given number. Returns storage.ErrNotFound if there is no such poll.
+func (mod *Polls) getPoll(number int) (p poll, err error) {
+	err = mod.store.Get(pollsBucket, strconv.Itoa(number), &p)
+	return p, err
+}
+
+func (mod *Polls) savePoll(p poll) error {
+	return mod.store.Put(pollsBucket, strconv.Itoa(p.Number), p)
+}
+
+// listPolls returns every poll, oldest first.
+func (mod *Polls) listPolls() (polls []poll, err error) {
+	for _, key := range mod.store.Keys(pollsBucket) {
+		var p poll
+		if err = mod.store.Get(pollsBucket, key, &p); err != nil {
+			return nil, err
+		}
+		polls = append(polls, p)
+	}
+	slices.SortFunc(polls, func(a, b poll) int {
+		return a.Number - b.Number
+	})
+	return polls, nil
+}
@@ -0,0 +1,207 @@
+package raffles
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"io"
+	"log/slog"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// This file handles drawing raffles: working out who can win, picking winners fairly, and revealing them.
+
+// drawRaffle draws winners for the given raffle, closing entries if they're still open.
+func (mod *Raffles) drawRaffle(s *discordgo.Session, guildID string, number, count int, u *discordgo.User, now time.Time) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	r, err := mod.getRaffle(number)
+	if isNotFound(err) {
+		return fmt.Sprintf("⚠️ %s.", ErrUnknownRaffle), nil
+	} else if err != nil {
+		return "", err
+	}
+	members, err := guildMembers(s.State, guildID)
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	}
+	previous, err := mod.previousWinners()
+	if err != nil {
+		return "", err
+	}
+	eligible, excluded := eligibleEntrants(r, mod.candidates(r, members), members, previous)
+	if len(eligible) == 0 {
+		return fmt.Sprintf("⚠️ %s.", ErrNobodyEligible), nil
+	}
+	winners, err := drawWinners(eligible, min(count, len(eligible)), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	redraw := !r.Open()
+	r.Draws = append(r.Draws, draw{
+		DrawnBy:  u.ID,
+		DrawnAt:  now,
+		Eligible: eligible,
+		Excluded: excluded,
+		Winners:  winners,
+	})
+	if err = mod.saveRaffle(r); err != nil {
+		return "", err
+	}
+	logger.Info("Raffle drawn", slog.Int("number", r.Number), slog.Int("eligible", len(eligible)), slog.Int("excluded", len(excluded)), slog.Any("winners", winners), slog.String("user", u.Username))
+
+	// Close entries (and show the winners) on the raffle's message. This is best-effort; it may have been deleted.
+	components := raffleComponents(r)
+	_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    r.ChannelID,
+		ID:         r.MessageID,
+		Embeds:     &[]*discordgo.MessageEmbed{raffleEmbed(r)},
+		Components: &components,
+	})
+	if err != nil {
+		logger.Warn("Unable to update raffle message", slog.Int("number", r.Number), slog.Any("error", err))
+	}
+	_, err = s.ChannelMessageSendComplex(r.ChannelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("🎉 Congratulations %s! You won **%s** in **%s**. Please find a member of Crew to collect your prize.", mentions(winners), r.Prize, r.Name),
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: winners},
+	})
+	if err != nil {
+		logger.Warn("Unable to announce raffle winners", slog.Int("number", r.Number), slog.Any("error", err))
+	}
+
+	content = fmt.Sprintf("🎉 Drew %s from %d eligible %s (%d excluded).", mentions(winners), len(eligible), helpers.Pluralise(len(eligible), "entrant", "entrants"), len(excluded))
+	if !mod.reveal(r, winners, len(eligible), redraw, members) {
+		content += "\n👻 The Event Bridge isn't available, so the winners weren't revealed on the infoboard."
+	}
+	return content, nil
+}
+
+// guildMembers returns the guild's members (from the State, which is filled in when we connect), keyed on user ID.
+func guildMembers(state *discordgo.State, guildID string) (members map[string]*discordgo.Member, err error) {
+	g, err := state.Guild(guildID)
+	if err != nil || len(g.Members) == 0 {
+		return nil, ErrMembersUnavailable
+	}
+	members = make(map[string]*discordgo.Member, len(g.Members))
+	for _, member := range g.Members {
+		members[member.User.ID] = member
+	}
+	return members, nil
+}
+
+// candidates returns the user IDs of everybody entered into a raffle, before anybody is excluded.
+func (mod *Raffles) candidates(r raffle, members map[string]*discordgo.Member) (userIDs []string) {
+	switch r.Entry {
+	case entryCheckedIn:
+		return mod.attendees.CheckedInUsers()
+	case entryRole:
+		for userID, member := range members {
+			if slices.Contains(member.Roles, r.RoleID) {
+				userIDs = append(userIDs, userID)
+			}
+		}
+		slices.Sort(userIDs)
+		return userIDs
+	default:
+		for _, e := range r.Entries {
+			userIDs = append(userIDs, e.UserID)
+		}
+		return userIDs
+	}
+}
+
+// eligibleEntrants works out which of the candidates can win the raffle, and why the others can't. Eligible entrants
+// are sorted, so that the draw can be checked afterwards.
+func eligibleEntrants(r raffle, candidates []string, members map[string]*discordgo.Member, previousWinners map[string]bool) (eligible []string, excluded map[string]string) {
+	excluded = make(map[string]string)
+	alreadyWon := r.Winners()
+	for _, userID := range candidates {
+		member, ok := members[userID]
+		switch {
+		case slices.Contains(eligible, userID) || excluded[userID] != "":
+			// Entered twice (which shouldn't happen, but doesn't improve their chances if it does).
+			continue
+		case !ok:
+			excluded[userID] = excludedLeft
+		case member.User.Bot:
+			excluded[userID] = excludedBot
+		case r.ExcludeCrew && memberIsCrew(member):
+			excluded[userID] = excludedCrew
+		case slices.Contains(alreadyWon, userID) || (r.ExcludeWinners && previousWinners[userID]):
+			// Nobody wins the same raffle twice, even on a redraw.
+			excluded[userID] = excludedWinner
+		default:
+			eligible = append(eligible, userID)
+		}
+	}
+	slices.Sort(eligible)
+	return eligible, excluded
+}
+
+// drawWinners picks count different winners from the pool, each equally likely, using the given source of
+// (cryptographically secure) randomness.
+func drawWinners(pool []string, count int, random io.Reader) (winners []string, err error) {
+	pool = slices.Clone(pool)
+	for range min(count, len(pool)) {
+		n, err := rand.Int(random, big.NewInt(int64(len(pool))))
+		if err != nil {
+			return nil, err
+		}
+		idx := int(n.Int64())
+		winners = append(winners, pool[idx])
+		pool = slices.Delete(pool, idx, idx+1)
+	}
+	return winners, nil
+}
+
+// reveal tells NodeCG who won, so that the infoboard can reveal them. Returns whether it could.
+func (mod *Raffles) reveal(r raffle, winners []string, entrants int, redraw bool, members map[string]*discordgo.Member) bool {
+	if !bridge_wan.BridgeIsAvailable() {
+		return false
+	}
+	data := ngtbg.NodeCGMessageDataRaffleReveal{
+		Number:   r.Number,
+		Name:     r.Name,
+		Prize:    r.Prize,
+		Entrants: entrants,
+		Redraw:   redraw,
+	}
+	for _, userID := range winners {
+		member := members[userID]
+		data.Winners = append(data.Winners, ngtbg.NodeCGMessageDataRaffleWinner{
+			Name:   helpers.DiscordMemberDisplayName(member),
+			Avatar: member.AvatarURL("256"),
+		})
+	}
+	err := bridge_wan.EventBridge.BrMessageSend(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGMessageRaffleReveal, data)
+	if err != nil {
+		logger.Error("Unable to reveal raffle winners", slog.Int("number", r.Number), slog.Any("error", err))
+		return false
+	}
+	return true
+}
+
+// memberIsCrew returns whether the given member has the Crew role.
+func memberIsCrew(member *discordgo.Member) bool {
+	crewRoleID := config.RuntimeConfig.Discord.Permissions.CrewRole
+	return crewRoleID != "" && slices.Contains(member.Roles, crewRoleID)
+}
+
+// mentions lists the given users as mentions, e.g. "<@1>, <@2> and <@3>".
+func mentions(userIDs []string) string {
+	list := make([]string, len(userIDs))
+	for idx, userID := range userIDs {
+		list[idx] = "<@" + userID + ">"
+	}
+	if len(list) <= 1 {
+		return strings.Join(list, "")
+	}
+	return strings.Join(list[:len(list)-1], ", ") + " and " + list[len(list)-1]
+}
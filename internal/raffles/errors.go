@@ -0,0 +1,13 @@
+package raffles
+
+import "errors"
+
+var ErrUnknownRaffle = errors.New("There's no raffle with that number")
+var ErrRaffleClosed = errors.New("Entries for this raffle have closed")
+var ErrNotButtonRaffle = errors.New("This raffle can't be entered with a button")
+var ErrRoleRequired = errors.New("Please choose the role whose members can win")
+var ErrCrewExcluded = errors.New("Crew can't enter this raffle")
+var ErrAlreadyEntered = errors.New("You're already in this raffle")
+var ErrAlreadyWon = errors.New("You've already won a raffle, so can't enter this one. Give somebody else a chance")
+var ErrNobodyEligible = errors.New("Nobody is eligible to win this raffle")
+var ErrMembersUnavailable = errors.New("BIGbot hasn't loaded the server's members yet. Please try again shortly")
@@ -0,0 +1,506 @@
+package raffles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Enter buttons are followed by the raffle number, e.g. bigbot_raffle_enter_3.
+	enterCustomIDPrefix = "bigbot_raffle_enter_"
+)
+
+// logger stores the module's logger instance.
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+// Attendees is how we find out who has checked in (which is managed by the checkin module).
+type Attendees interface {
+	// CheckedInUsers returns the user IDs of everybody who has checked in.
+	CheckedInUsers() []string
+}
+
+type Raffles struct {
+	discord *discordgo.Session
+
+	// Persistent storage for raffles, their entries and their winners.
+	store *storage.Store
+
+	// The event's attendees.
+	attendees Attendees
+
+	// Held while changing raffles, so that entries aren't lost and nobody can enter during a draw.
+	mtx sync.Mutex
+}
+
+var commands = []*discordgo.ApplicationCommand{
+	{
+		Name:                     "raffle",
+		Description:              "🎟️🛠️ Run prize draws (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "create",
+				Description: "🎟️ Announce a raffle in this channel.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "What the raffle is called, e.g. Saturday night giveaway",
+						Required:    true,
+						MaxLength:   100,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "prize",
+						Description: "What's being won, e.g. A mechanical keyboard",
+						Required:    true,
+						MaxLength:   200,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "entry",
+						Description: "Who is entered (by default, people enter with a button)",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "🎟️ Anybody who clicks the Enter button", Value: entryButton},
+							{Name: "✅ Everybody who has checked in", Value: entryCheckedIn},
+							{Name: "🏷️ Everybody with a role", Value: entryRole},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "The role whose members are entered (if entering everybody with a role)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "exclude-crew",
+						Description: "Stop Crew winning (default: true)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "exclude-winners",
+						Description: "Stop people who have won a raffle before winning again (default: true)",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Name:        "draw",
+				Description: "🎉 Draw the winners of a raffle (drawing again picks new winners).",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "number",
+						Description: "The raffle's number",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "winners",
+						Description: "How many winners to draw (default: 1)",
+						Required:    false,
+						MinValue:    &minWinners,
+						MaxValue:    maxWinners,
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Description: "📋 List the raffles.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "audit",
+				Description: "🧾 Show who entered a raffle and who won.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "number",
+						Description: "The raffle's number",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+}
+
+// The number of winners that can be drawn at once.
+var minWinners float64 = 1
+
+const maxWinners = 10
+
+func New(discord *discordgo.Session, store *storage.Store, attendees Attendees) (mod *Raffles, err error) {
+	return &Raffles{
+		discord:   discord,
+		store:     store,
+		attendees: attendees,
+	}, nil
+}
+
+func (mod *Raffles) SetLogger(log *slog.Logger) {
+	logger = log
+}
+
+func (mod *Raffles) DiscordCommands() ([]*discordgo.ApplicationCommand, error) {
+	return commands, nil
+}
+
+func (mod *Raffles) Start(ctx context.Context) (err error) {
+	// This module simply registers handlers, and does not need to run continuously (so we don't need the context)
+	return ctx.Err()
+}
+
+func (mod *Raffles) DiscordHandleMessage(_ *discordgo.Session, _ *discordgo.MessageCreate) (err error) {
+	return nil
+}
+
+func (mod *Raffles) DiscordHandleMessageUpdate(_ *discordgo.Session, _ *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *Raffles) DiscordHandleMessageDelete(_ *discordgo.Session, _ *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
+func (mod *Raffles) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		if i.ApplicationCommandData().Name == "raffle" {
+			return true, mod.discordHandleRaffleCommand(s, i)
+		}
+	case discordgo.InteractionMessageComponent:
+		if strings.HasPrefix(i.MessageComponentData().CustomID, enterCustomIDPrefix) {
+			return true, mod.discordHandleEnter(s, i)
+		}
+	}
+	return false, nil
+}
+
+func (mod *Raffles) discordHandleRaffleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range options[0].Options {
+		values[option.Name] = option
+	}
+
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+	params := &discordgo.WebhookParams{
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+	switch options[0].Name {
+	case "create":
+		r := raffle{
+			Name:           strings.TrimSpace(values["name"].StringValue()),
+			Prize:          strings.TrimSpace(values["prize"].StringValue()),
+			Entry:          entryButton,
+			ExcludeCrew:    true,
+			ExcludeWinners: true,
+		}
+		if option, ok := values["entry"]; ok {
+			r.Entry = option.StringValue()
+		}
+		if option, ok := values["role"]; ok {
+			r.RoleID = option.RoleValue(s, i.GuildID).ID
+		}
+		if option, ok := values["exclude-crew"]; ok {
+			r.ExcludeCrew = option.BoolValue()
+		}
+		if option, ok := values["exclude-winners"]; ok {
+			r.ExcludeWinners = option.BoolValue()
+		}
+		params.Content, err = mod.createRaffle(s, i.ChannelID, i.Member.User, r, time.Now())
+	case "draw":
+		count := 1
+		if option, ok := values["winners"]; ok {
+			count = int(option.IntValue())
+		}
+		params.Content, err = mod.drawRaffle(s, i.GuildID, int(values["number"].IntValue()), count, i.Member.User, time.Now())
+	case "list":
+		params.Content, err = mod.adminList()
+	case "audit":
+		params.Content, params.Files, err = mod.adminAudit(int(values["number"].IntValue()))
+	default:
+		params.Content = "😶 Please use a sub-command."
+	}
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		logger.Error("Raffle command failed", slog.String("action", options[0].Name), slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		params.Content, params.Files = helpers.DiscordErrorContent(s, i, err), nil
+	}
+	if _, err = s.FollowupMessageCreate(i.Interaction, true, params); err != nil {
+		logger.Error("Unable to send raffle command response", slog.Any("error", err))
+	}
+	return nil
+}
+
+// createRaffle announces a new raffle in the given channel.
+func (mod *Raffles) createRaffle(s *discordgo.Session, channelID string, u *discordgo.User, r raffle, now time.Time) (content string, err error) {
+	if r.Entry == entryRole && r.RoleID == "" {
+		return fmt.Sprintf("⚠️ %s.", ErrRoleRequired), nil
+	}
+	if r.Entry != entryRole {
+		r.RoleID = ""
+	}
+
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	r.Number = len(mod.store.Keys(rafflesBucket)) + 1
+	r.CreatedBy = u.ID
+	r.CreatedAt = now
+	r.ChannelID = channelID
+	msg, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:          []*discordgo.MessageEmbed{raffleEmbed(r)},
+		Components:      raffleComponents(r),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		return "", err
+	}
+	r.MessageID = msg.ID
+	if err = mod.saveRaffle(r); err != nil {
+		return "", err
+	}
+	logger.Info("Raffle created", slog.Int("number", r.Number), slog.String("name", r.Name), slog.String("entry", r.Entry), slog.String("user", u.Username))
+	return fmt.Sprintf("🎟️ Created raffle #%d. Use `/raffle draw number:%d` when it's time to draw the winners.", r.Number, r.Number), nil
+}
+
+// discordHandleEnter enters somebody into a raffle with the button.
+func (mod *Raffles) discordHandleEnter(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.Member == nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 Raffles can only be entered from the server.")
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(i.MessageComponentData().CustomID, enterCustomIDPrefix))
+	if err != nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", ErrUnknownRaffle))
+	}
+
+	r, reason, err := mod.enterRaffle(number, i.Member, time.Now())
+	if err != nil {
+		return err
+	}
+	switch {
+	case errors.Is(reason, ErrAlreadyEntered):
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "🎟️ You're already in this raffle. Good luck!")
+	case reason != nil:
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", reason))
+	}
+	logger.Info("Raffle entered", slog.Int("number", r.Number), slog.String("user", i.Member.User.Username))
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{raffleEmbed(r)},
+			Components: raffleComponents(r),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: fmt.Sprintf("🎟️ You're in the draw for **%s**. Good luck!", r.Prize),
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	return err
+}
+
+// enterRaffle enters the given member into a stored raffle, returning the updated raffle, or why they can't enter it.
+// The raffle is only locked while it's being changed, so that a rush of entries isn't held up by Discord.
+func (mod *Raffles) enterRaffle(number int, member *discordgo.Member, now time.Time) (r raffle, reason error, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	r, err = mod.getRaffle(number)
+	if isNotFound(err) {
+		return r, ErrUnknownRaffle, nil
+	} else if err != nil {
+		return r, nil, err
+	}
+	previous, err := mod.previousWinners()
+	if err != nil {
+		return r, nil, err
+	}
+	if reason = canEnter(r, member, previous); reason != nil {
+		return r, reason, nil
+	}
+	if r.Entered(member.User.ID) {
+		return r, ErrAlreadyEntered, nil
+	}
+	r.Entries = append(r.Entries, entry{UserID: member.User.ID, EnteredAt: now})
+	return r, nil, mod.saveRaffle(r)
+}
+
+// canEnter checks whether a member can enter a raffle with the button. (They're checked again when it's drawn.)
+func canEnter(r raffle, member *discordgo.Member, previousWinners map[string]bool) error {
+	switch {
+	case r.Entry != entryButton:
+		return ErrNotButtonRaffle
+	case !r.Open():
+		return ErrRaffleClosed
+	case r.ExcludeCrew && memberIsCrew(member):
+		return ErrCrewExcluded
+	case r.ExcludeWinners && previousWinners[member.User.ID]:
+		return ErrAlreadyWon
+	}
+	return nil
+}
+
+func (mod *Raffles) adminList() (content string, err error) {
+	raffles, err := mod.listRaffles()
+	if err != nil {
+		return "", err
+	}
+	if len(raffles) == 0 {
+		return "🎟️ There haven't been any raffles yet.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("🎟️ Raffles:\n")
+	for _, r := range raffles {
+		status := "open"
+		if winners := r.Winners(); len(winners) > 0 {
+			status = "won by " + mentions(winners)
+		}
+		line := fmt.Sprintf("- #%d **%s** for %s (%s, %s)\n", r.Number, r.Name, r.Prize, entryDescription(r), status)
+		// Discord limits messages to 2000 characters.
+		if sb.Len()+len(line) > 1900 {
+			sb.WriteString("…and more.")
+			break
+		}
+		sb.WriteString(line)
+	}
+	return sb.String(), nil
+}
+
+// adminAudit summarises who entered a raffle and who won, attaching the full record (for anybody who wants to check
+// the draw was fair).
+func (mod *Raffles) adminAudit(number int) (content string, files []*discordgo.File, err error) {
+	r, err := mod.getRaffle(number)
+	if isNotFound(err) {
+		return fmt.Sprintf("⚠️ %s.", ErrUnknownRaffle), nil, nil
+	} else if err != nil {
+		return "", nil, err
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧾 Raffle #%d **%s** for %s\n", r.Number, r.Name, r.Prize))
+	sb.WriteString(fmt.Sprintf("Created by <@%s> <t:%d:f>. %s.\n", r.CreatedBy, r.CreatedAt.Unix(), entryDescription(r)))
+	if r.Entry == entryButton {
+		sb.WriteString(fmt.Sprintf("%d %s.\n", len(r.Entries), helpers.Pluralise(len(r.Entries), "entry", "entries")))
+	}
+	if len(r.Draws) == 0 {
+		sb.WriteString("Not drawn yet.\n")
+	}
+	for idx, d := range r.Draws {
+		sb.WriteString(fmt.Sprintf("Draw %d by <@%s> <t:%d:f>: %s, from %d eligible (%d excluded).\n",
+			idx+1, d.DrawnBy, d.DrawnAt.Unix(), mentions(d.Winners), len(d.Eligible), len(d.Excluded)))
+	}
+	record, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	files = []*discordgo.File{{
+		Name:        fmt.Sprintf("raffle-%d.json", r.Number),
+		ContentType: "application/json",
+		Reader:      bytes.NewReader(record),
+	}}
+	return sb.String(), files, nil
+}
+
+// raffleEmbed describes a raffle, and who has won it.
+func raffleEmbed(r raffle) *discordgo.MessageEmbed {
+	status, colour := "🟢 Open", 0x2E7D32
+	if winners := r.Winners(); len(winners) > 0 {
+		status, colour = "🎉 Won by "+mentions(winners), 0xF9A825
+	}
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Who's in", Value: entryDescription(r), Inline: true},
+	}
+	if r.Entry == entryButton {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Entries", Value: strconv.Itoa(len(r.Entries)), Inline: true})
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{Name: "Status", Value: status})
+	var rules []string
+	if r.ExcludeCrew {
+		rules = append(rules, "Crew can't win.")
+	}
+	if r.ExcludeWinners {
+		rules = append(rules, "People who have already won a raffle can't win again.")
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🎟️ Raffle #%d: %s", r.Number, r.Name),
+		Description: fmt.Sprintf("Up for grabs: **%s**", r.Prize),
+		Color:       colour,
+		Fields:      fields,
+	}
+	if len(rules) > 0 {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: strings.Join(rules, " ")}
+	}
+	return embed
+}
+
+// entryDescription says who is entered into a raffle.
+func entryDescription(r raffle) string {
+	switch r.Entry {
+	case entryCheckedIn:
+		return "Everybody who has checked in"
+	case entryRole:
+		return fmt.Sprintf("Everybody with <@&%s>", r.RoleID)
+	default:
+		return "Anybody who clicks Enter"
+	}
+}
+
+// raffleComponents returns the Enter button for a raffle, if people can enter it that way.
+func raffleComponents(r raffle) []discordgo.MessageComponent {
+	if r.Entry != entryButton || !r.Open() {
+		return []discordgo.MessageComponent{}
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Enter",
+					Emoji:    &discordgo.ComponentEmoji{Name: "🎟️"},
+					Style:    discordgo.PrimaryButton,
+					CustomID: enterCustomIDPrefix + strconv.Itoa(r.Number),
+				},
+			},
+		},
+	}
+}
+
+// isNotFound returns whether the given error is storage telling us something doesn't exist.
+func isNotFound(err error) bool {
+	return errors.Is(err, storage.ErrNotFound)
+}
+
+var defaultCrewCommandPermissions int64 = discordgo.PermissionAdministrator
+var defaultCrewCommandDMPermissions = false
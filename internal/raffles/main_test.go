@@ -0,0 +1,252 @@
+package raffles
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/storage/storagetest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testGuildID    = "100"
+	testCrewRoleID = "200"
+	testRoleID     = "300"
+)
+
+var testCreatedAt = time.Date(2024, 8, 23, 18, 0, 0, 0, time.UTC)
+
+// testAttendees pretends to be the checkin module.
+type testAttendees []string
+
+func (a testAttendees) CheckedInUsers() []string {
+	return a
+}
+
+func newTestRaffles(t *testing.T, attendees Attendees) *Raffles {
+	t.Helper()
+	store := storagetest.Open(t)
+	mod, err := New(nil, store, attendees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.RuntimeConfig.Discord.Permissions.CrewRole
+	t.Cleanup(func() { config.RuntimeConfig.Discord.Permissions.CrewRole = previous })
+	config.RuntimeConfig.Discord.Permissions.CrewRole = testCrewRoleID
+	return mod
+}
+
+func testMember(userID string, roles ...string) *discordgo.Member {
+	return &discordgo.Member{
+		GuildID: testGuildID,
+		User:    &discordgo.User{ID: userID, Username: "user" + userID},
+		Roles:   roles,
+	}
+}
+
+func testMembers() map[string]*discordgo.Member {
+	bot := testMember("5")
+	bot.User.Bot = true
+	return map[string]*discordgo.Member{
+		"1": testMember("1", testRoleID),
+		"2": testMember("2", testRoleID, testCrewRoleID),
+		"3": testMember("3"),
+		"4": testMember("4", testRoleID),
+		"5": bot,
+	}
+}
+
+func TestEligibleEntrants(t *testing.T) {
+	newTestRaffles(t, nil)
+	r := raffle{ExcludeCrew: true, ExcludeWinners: true}
+	candidates := []string{"4", "1", "2", "3", "5", "6", "1"}
+	eligible, excluded := eligibleEntrants(r, candidates, testMembers(), map[string]bool{"3": true})
+	if !slices.Equal(eligible, []string{"1", "4"}) {
+		t.Errorf("Expected 1 and 4 to be eligible (in order), got %v", eligible)
+	}
+	want := map[string]string{"2": excludedCrew, "3": excludedWinner, "5": excludedBot, "6": excludedLeft}
+	if len(excluded) != len(want) {
+		t.Errorf("Expected %v to be excluded, got %v", want, excluded)
+	}
+	for userID, reason := range want {
+		if excluded[userID] != reason {
+			t.Errorf("Expected %s to be excluded as %q, got %q", userID, reason, excluded[userID])
+		}
+	}
+
+	// Without exclusions, only people who can't win at all are left out...
+	r = raffle{}
+	eligible, _ = eligibleEntrants(r, candidates, testMembers(), map[string]bool{"3": true})
+	if !slices.Equal(eligible, []string{"1", "2", "3", "4"}) {
+		t.Errorf("Expected Crew and previous winners to be eligible, got %v", eligible)
+	}
+	// ...but nobody can win the same raffle twice.
+	r.Draws = []draw{{Winners: []string{"4"}}}
+	if eligible, excluded = eligibleEntrants(r, candidates, testMembers(), nil); slices.Contains(eligible, "4") || excluded["4"] != excludedWinner {
+		t.Errorf("Expected the raffle's winner to be excluded from a redraw, got %v", eligible)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	mod := newTestRaffles(t, testAttendees{"3", "9"})
+	tests := []struct {
+		raffle raffle
+		want   []string
+	}{
+		{raffle: raffle{Entry: entryButton, Entries: []entry{{UserID: "4"}, {UserID: "1"}}}, want: []string{"4", "1"}},
+		{raffle: raffle{Entry: entryCheckedIn}, want: []string{"3", "9"}},
+		{raffle: raffle{Entry: entryRole, RoleID: testRoleID}, want: []string{"1", "2", "4"}},
+	}
+	for _, test := range tests {
+		if got := mod.candidates(test.raffle, testMembers()); !slices.Equal(got, test.want) {
+			t.Errorf("Expected %s candidates %v, got %v", test.raffle.Entry, test.want, got)
+		}
+	}
+}
+
+func TestDrawWinners(t *testing.T) {
+	pool := []string{"1", "2", "3", "4", "5"}
+	winners, err := drawWinners(pool, 3, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(winners) != 3 || len(slices.Compact(slices.Sorted(slices.Values(winners)))) != 3 {
+		t.Errorf("Expected 3 different winners, got %v", winners)
+	}
+	if len(pool) != 5 {
+		t.Errorf("Drawing shouldn't change the pool, got %v", pool)
+	}
+	if winners, _ = drawWinners(pool, 10, rand.Reader); len(winners) != 5 {
+		t.Errorf("Can't draw more winners than there are entrants, got %v", winners)
+	}
+	// Without any randomness, nobody can be drawn.
+	if _, err = drawWinners(pool, 1, bytes.NewReader(nil)); err == nil {
+		t.Error("Expected an error when the source of randomness fails")
+	}
+
+	// Everybody should have a fair chance.
+	wins := make(map[string]int)
+	for range 5000 {
+		winners, err = drawWinners(pool, 1, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wins[winners[0]]++
+	}
+	for _, userID := range pool {
+		if wins[userID] < 800 || wins[userID] > 1200 {
+			t.Errorf("Expected each entrant to win about 1000 times, got %v", wins)
+			break
+		}
+	}
+}
+
+func TestCanEnter(t *testing.T) {
+	newTestRaffles(t, nil)
+	r := raffle{Entry: entryButton, ExcludeCrew: true, ExcludeWinners: true}
+	previous := map[string]bool{"3": true}
+	tests := []struct {
+		raffle raffle
+		member *discordgo.Member
+		want   error
+	}{
+		{raffle: r, member: testMember("1"), want: nil},
+		{raffle: r, member: testMember("2", testCrewRoleID), want: ErrCrewExcluded},
+		{raffle: r, member: testMember("3"), want: ErrAlreadyWon},
+		{raffle: raffle{Entry: entryButton}, member: testMember("3", testCrewRoleID), want: nil},
+		{raffle: raffle{Entry: entryCheckedIn}, member: testMember("1"), want: ErrNotButtonRaffle},
+		{raffle: raffle{Entry: entryButton, Draws: []draw{{}}}, member: testMember("1"), want: ErrRaffleClosed},
+	}
+	for _, test := range tests {
+		if err := canEnter(test.raffle, test.member, previous); !errors.Is(err, test.want) {
+			t.Errorf("canEnter(%s) = %v, want %v", test.member.User.ID, err, test.want)
+		}
+	}
+}
+
+func TestEnterRaffle(t *testing.T) {
+	mod := newTestRaffles(t, nil)
+	if err := mod.saveRaffle(raffle{Number: 1, Entry: entryButton, ExcludeCrew: true}); err != nil {
+		t.Fatal(err)
+	}
+	r, reason, err := mod.enterRaffle(1, testMember("1"), testCreatedAt)
+	if err != nil || reason != nil || !r.Entered("1") {
+		t.Fatalf("Expected to be entered, got %v (%v)", reason, err)
+	}
+	if stored, _ := mod.getRaffle(1); !stored.Entered("1") {
+		t.Error("Expected the entry to be saved")
+	}
+	tests := []struct {
+		number int
+		member *discordgo.Member
+		want   error
+	}{
+		{number: 1, member: testMember("1"), want: ErrAlreadyEntered},
+		{number: 1, member: testMember("2", testCrewRoleID), want: ErrCrewExcluded},
+		{number: 2, member: testMember("1"), want: ErrUnknownRaffle},
+	}
+	for _, test := range tests {
+		if _, reason, err = mod.enterRaffle(test.number, test.member, testCreatedAt); err != nil || !errors.Is(reason, test.want) {
+			t.Errorf("enterRaffle(%d, %s) = %v (%v), want %v", test.number, test.member.User.ID, reason, err, test.want)
+		}
+	}
+}
+
+func TestGuildMembers(t *testing.T) {
+	state := discordgo.NewState()
+	if _, err := guildMembers(state, testGuildID); !errors.Is(err, ErrMembersUnavailable) {
+		t.Errorf("Expected members to be unavailable before the guild has loaded, got %v", err)
+	}
+	if err := state.GuildAdd(&discordgo.Guild{ID: testGuildID, Members: []*discordgo.Member{testMember("1"), testMember("2")}}); err != nil {
+		t.Fatal(err)
+	}
+	members, err := guildMembers(state, testGuildID)
+	if err != nil || len(members) != 2 || members["2"].User.ID != "2" {
+		t.Errorf("Expected both members, got %v (%v)", members, err)
+	}
+}
+
+func TestPreviousWinners(t *testing.T) {
+	mod := newTestRaffles(t, nil)
+	for _, r := range []raffle{
+		{Number: 1, Draws: []draw{{Winners: []string{"1"}}, {Winners: []string{"2", "3"}}}},
+		{Number: 2},
+		{Number: 3, Draws: []draw{{Winners: []string{"4"}}}},
+	} {
+		if err := mod.saveRaffle(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	winners, err := mod.previousWinners()
+	if err != nil || len(winners) != 4 || !winners["3"] || winners["5"] {
+		t.Errorf("Expected 4 previous winners, got %v (%v)", winners, err)
+	}
+}
+
+func TestRaffleMessage(t *testing.T) {
+	r := raffle{Number: 3, Name: "Giveaway", Prize: "A keyboard", Entry: entryButton, ExcludeCrew: true, CreatedAt: testCreatedAt}
+	embed := raffleEmbed(r)
+	if embed.Title != "🎟️ Raffle #3: Giveaway" || !strings.Contains(embed.Fields[2].Value, "Open") || embed.Footer.Text != "Crew can't win." {
+		t.Errorf("Unexpected embed for open raffle: %+v", embed)
+	}
+	button := raffleComponents(r)[0].(discordgo.ActionsRow).Components[0].(discordgo.Button)
+	if button.CustomID != "bigbot_raffle_enter_3" {
+		t.Errorf("Unexpected enter button %q", button.CustomID)
+	}
+
+	r.Draws = []draw{{Winners: []string{"1"}}, {Winners: []string{"2", "3"}}}
+	if status := raffleEmbed(r).Fields[2].Value; status != "🎉 Won by <@1>, <@2> and <@3>" {
+		t.Errorf("Drawn raffle should list the winners, got %q", status)
+	}
+	if components := raffleComponents(r); len(components) != 0 {
+		t.Errorf("Drawn raffle shouldn't have an enter button, got %d", len(components))
+	}
+	if components := raffleComponents(raffle{Entry: entryRole}); len(components) != 0 {
+		t.Errorf("Role raffle shouldn't have an enter button, got %d", len(components))
+	}
+}
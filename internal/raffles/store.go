@@ -0,0 +1,133 @@
+package raffles
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// The storage bucket raffles are kept in, keyed on the raffle's number. Raffles are never deleted, so that there's
+	// a record of who entered and who won.
+	rafflesBucket = "raffles"
+)
+
+// How people get into a raffle.
+const (
+	// People enter by clicking a button.
+	entryButton = "button"
+	// Everybody who has checked in is entered.
+	entryCheckedIn = "checked-in"
+	// Everybody with a role is entered.
+	entryRole = "role"
+)
+
+// Why somebody who entered couldn't win.
+const (
+	excludedCrew   = "crew"
+	excludedWinner = "previous winner"
+	excludedLeft   = "not in the server"
+	excludedBot    = "bot"
+)
+
+// raffle is one prize draw.
+type raffle struct {
+	// The raffle's number, as shown to people.
+	Number int `json:"number"`
+	// What the raffle is called, and what's being won.
+	Name  string `json:"name"`
+	Prize string `json:"prize"`
+	// How people get into the raffle (entryButton, entryCheckedIn or entryRole), and the role for entryRole.
+	Entry  string `json:"entry"`
+	RoleID string `json:"role_id,omitempty"`
+	// Who can't win: Crew, and people who have won a raffle before.
+	ExcludeCrew    bool `json:"exclude_crew"`
+	ExcludeWinners bool `json:"exclude_winners"`
+	// Who set the raffle up, and when.
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	// Where the raffle was announced (with its button, for entryButton).
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+	// Everybody who entered with the button, in the order they entered.
+	Entries []entry `json:"entries,omitempty"`
+	// Every draw made, in order. There can be more than one (e.g. if a winner wasn't around to collect their prize).
+	Draws []draw `json:"draws,omitempty"`
+}
+
+// entry is somebody entering a raffle with the button.
+type entry struct {
+	UserID    string    `json:"user_id"`
+	EnteredAt time.Time `json:"entered_at"`
+}
+
+// draw is one drawing of winners, with enough detail to show it was fair.
+type draw struct {
+	// Who made the draw, and when.
+	DrawnBy string    `json:"drawn_by"`
+	DrawnAt time.Time `json:"drawn_at"`
+	// The user IDs of everybody who could have won, in the order they were drawn from.
+	Eligible []string `json:"eligible"`
+	// Everybody who was entered but couldn't win, and why, keyed on user ID.
+	Excluded map[string]string `json:"excluded,omitempty"`
+	// The user IDs of the winners, in the order they were drawn.
+	Winners []string `json:"winners"`
+}
+
+// Open returns whether people can still enter the raffle. Entries close when the first draw is made.
+func (r raffle) Open() bool {
+	return len(r.Draws) == 0
+}
+
+// Entered returns whether the given user has entered the raffle with the button.
+func (r raffle) Entered(userID string) bool {
+	return slices.ContainsFunc(r.Entries, func(e entry) bool { return e.UserID == userID })
+}
+
+// Winners returns everybody who has won the raffle, across all its draws.
+func (r raffle) Winners() (winners []string) {
+	for _, d := range r.Draws {
+		winners = append(winners, d.Winners...)
+	}
+	return winners
+}
+
+// getRaffle fetches the raffle with the given number. Returns storage.ErrNotFound if there is no such raffle.
+func (mod *Raffles) getRaffle(number int) (r raffle, err error) {
+	err = mod.store.Get(rafflesBucket, strconv.Itoa(number), &r)
+	return r, err
+}
+
+func (mod *Raffles) saveRaffle(r raffle) error {
+	return mod.store.Put(rafflesBucket, strconv.Itoa(r.Number), r)
+}
+
+// listRaffles returns every raffle, oldest first.
+func (mod *Raffles) listRaffles() (raffles []raffle, err error) {
+	for _, key := range mod.store.Keys(rafflesBucket) {
+		var r raffle
+		if err = mod.store.Get(rafflesBucket, key, &r); err != nil {
+			return nil, err
+		}
+		raffles = append(raffles, r)
+	}
+	slices.SortFunc(raffles, func(a, b raffle) int {
+		return a.Number - b.Number
+	})
+	return raffles, nil
+}
+
+// previousWinners returns everybody who has won any raffle.
+func (mod *Raffles) previousWinners() (winners map[string]bool, err error) {
+	raffles, err := mod.listRaffles()
+	if err != nil {
+		return nil, err
+	}
+	winners = make(map[string]bool)
+	for _, r := range raffles {
+		for _, userID := range r.Winners() {
+			winners[userID] = true
+		}
+	}
+	return winners, nil
+}
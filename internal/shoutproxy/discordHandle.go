@@ -1,58 +1,158 @@
 package shoutproxy
 
 import (
+	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
 	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
 	"github.com/thebiggame/bigbot/internal/config"
+	"log/slog"
+	"strings"
 	"time"
 )
 
-// Forward messages in the configured channel to NodeCG.
+// Forward messages in the configured channel to NodeCG (once they've passed moderation).
 func (mod *ShoutProxy) DiscordHandleMessage(s *discordgo.Session, m *discordgo.MessageCreate) (err error) {
+	if !isShout(m.Message) {
+		return nil
+	}
+	// This message is a shout! Check it's fit for the projector before making it known to NodeCG.
+	shoutEntry := newShoutEntry(s.State, m.Message)
+	if shoutEntry.Message == "" && len(shoutEntry.Media) == 0 {
+		// Nothing we can show (e.g. a file that isn't an image).
+		return nil
+	}
+	if memberIsCrew(m.Message.Member) {
+		// Crew are trusted to shout responsibly.
+		return mod.showShout(shoutEntry)
+	}
+	if err = mod.moderateShout(m.Message, time.Now()); err != nil {
+		logger.Info("Shout rejected", slog.String("user", m.Message.Author.Username), slog.String("reason", err.Error()))
+		rejectShout(s, m.Message)
+		return nil
+	}
+	if config.RuntimeConfig.Discord.Shoutbox.ReviewChannelID != "" {
+		review := shoutReview{ChannelID: m.Message.ChannelID, MessageID: m.Message.ID, Entry: shoutEntry}
+		return mod.submitShoutForReview(s, m.Message.ID, review, m.Message.Author.Mention())
+	}
+	return mod.showShout(shoutEntry)
+}
+
+// Keep NodeCG in step with shouts that are edited after they were sent.
+// NodeCG ignores edits (and removals) of shouts that it isn't showing, so we don't need to keep track of them.
+func (mod *ShoutProxy) DiscordHandleMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) (err error) {
+	if !isShout(m.Message) || m.Message.Author == nil {
+		return nil
+	}
+	if m.BeforeUpdate != nil && m.BeforeUpdate.Content == m.Message.Content {
+		// Nothing we show has changed (e.g. an embed has loaded).
+		return nil
+	}
+	if !memberIsCrew(m.Message.Member) {
+		// Edited shouts have to pass the content filters again (but don't count towards the rate limit).
+		if err = filterShout(m.Message); err != nil {
+			logger.Info("Edited shout rejected", slog.String("user", m.Message.Author.Username), slog.String("reason", err.Error()))
+			rejectShout(s, m.Message)
+			if err = mod.withdrawShoutReview(s, m.Message.ID, fmt.Sprintf("🚫 The shout was edited, and rejected: %s.", err)); err != nil {
+				return err
+			}
+			return mod.hideShout(shoutID(m.Message.ID))
+		}
+	}
+	shoutEntry := newShoutEntry(s.State, m.Message)
+	if reviewing, err := mod.updateShoutReview(s, m.Message, shoutEntry); err != nil || reviewing {
+		// Shouts waiting for Crew aren't being shown yet.
+		return err
+	}
+	return mod.editShout(shoutEntry)
+}
+
+// Remove shouts from NodeCG when they're deleted (by their sender or a moderator).
+func (mod *ShoutProxy) DiscordHandleMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) (err error) {
+	if !isShout(m.Message) {
+		return nil
+	}
+	if err = mod.withdrawShoutReview(s, m.Message.ID, "🗑️ The shout was deleted before it was reviewed."); err != nil {
+		return err
+	}
+	return mod.hideShout(shoutID(m.Message.ID))
+}
+
+func (mod *ShoutProxy) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return false, nil
+	}
+	customID := i.MessageComponentData().CustomID
+	if strings.HasPrefix(customID, shoutApproveCustomIDPrefix) || strings.HasPrefix(customID, shoutRejectCustomIDPrefix) {
+		return true, mod.discordHandleShoutReview(s, i)
+	}
+	return false, nil
+}
+
+// isShout returns whether a message was sent in the shoutbox channel.
+func isShout(m *discordgo.Message) bool {
 	// Ensure the shout happened in the expected target guild and channel.
 	var channelID = config.RuntimeConfig.Discord.Shoutbox.ChannelID
 	if channelID == "" {
 		logger.Debug("No Shoutbox Channel ID set, not dispatching")
-		return
+		return false
 	}
+	return m.ChannelID == channelID && m.GuildID == config.RuntimeConfig.Discord.GuildID
+}
+
+// shoutID returns the ID NodeCG knows a Discord shout by, given its message ID.
+func shoutID(messageID string) string {
+	return discordShoutIDPrefix + messageID
+}
 
-	if m.Message.ChannelID == channelID && m.Message.GuildID == config.RuntimeConfig.Discord.GuildID {
-		// This message is a shout! Let's make it known to NodeCG.
+// rejectShout reacts to a shout that won't be shown, so the sender knows. This is best-effort.
+func rejectShout(s *discordgo.Session, m *discordgo.Message) {
+	if err := s.MessageReactionAdd(m.ChannelID, m.ID, rejectedReaction); err != nil {
+		logger.Debug("Unable to react to shout", slog.Any("error", err))
+	}
+}
 
-		var userName = m.Message.Author.GlobalName
-		if m.Message.Member.Nick != "" {
+// newShoutEntry converts a Discord message into a Shout, as NodeCG expects it.
+func newShoutEntry(state *discordgo.State, m *discordgo.Message) ngtbg.NodeCGReplicantDataShoutboxEntry {
+	var userName = m.Author.GlobalName
+	var userAvatar = m.Author.AvatarURL("128x128")
+	if m.Member != nil {
+		if m.Member.Nick != "" {
 			// The user has a server-specific display name set. Use that.
-			userName = m.Message.Member.Nick
+			userName = m.Member.Nick
 		}
-		var userAvatar = m.Message.Author.AvatarURL("128x128")
-		if m.Message.Member.Avatar != "" {
+		if m.Member.Avatar != "" {
 			// The user has a server-specific avatar set. Use that.
-			userAvatar = m.Message.Member.AvatarURL("128x128")
-		}
-
-		var shoutEntry = ngtbg.NodeCGReplicantDataShoutboxEntry{
-			ID: "DISC-" + m.Message.ID,
-			User: struct {
-				Name   string `json:"name"`
-				Avatar string `json:"avatar_url"`
-			}{
-				Name:   userName,
-				Avatar: userAvatar,
-			},
-			Timestamp: m.Message.Timestamp.Format(time.RFC3339),
-			Message:   m.Message.Content,
-		}
-		if bridge_wan.BridgeIsAvailable() {
-			err = bridge_wan.EventBridge.BrMessageSend(config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGMessageShoutboxNew, shoutEntry)
+			// (Members attached to messages don't carry their user or guild, which the avatar URL needs.)
+			member := *m.Member
+			member.User, member.GuildID = m.Author, m.GuildID
+			userAvatar = member.AvatarURL("128x128")
 		}
-		// err = avcomms.NodeCG.ReplicantSet(*mod.ctx, config.RuntimeConfig.AV.NodeCG.BundleName, ngtbg.NodeCGReplicantShoutbox, shoutboxEntries)
-		return err
+	}
+	if userName == "" {
+		userName = m.Author.Username
 	}
 
-	return nil
+	content, media := renderShout(state, m)
+	return ngtbg.NodeCGReplicantDataShoutboxEntry{
+		ID: shoutID(m.ID),
+		User: struct {
+			Name   string `json:"name"`
+			Avatar string `json:"avatar_url"`
+		}{
+			Name:   userName,
+			Avatar: userAvatar,
+		},
+		Timestamp: m.Timestamp.Format(time.RFC3339),
+		Message:   content,
+		Media:     media,
+		Source:    ngtbg.ShoutboxSourceDiscord,
+	}
 }
 
-func (mod *ShoutProxy) DiscordHandleInteraction(_ *discordgo.Session, _ *discordgo.InteractionCreate) (handled bool, err error) {
-	return false, nil
+// forwardShout sends a shoutbox message (see ngtbg.NodeCGMessageShoutboxNew etc.) to NodeCG.
+// If the bridge isn't available, the message is held until it is.
+func (mod *ShoutProxy) forwardShout(messageName string, data any) (err error) {
+	err = bridge_wan.MessageSendQueued(config.RuntimeConfig.AV.NodeCG.BundleName, messageName, data)
+	return err
 }
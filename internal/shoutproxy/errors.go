@@ -0,0 +1,14 @@
+package shoutproxy
+
+import (
+	"errors"
+)
+
+var ErrShoutTooLong = errors.New("Shouts must be a sensible length")
+var ErrShoutLink = errors.New("Shouts can't contain links")
+var ErrShoutMention = errors.New("Shouts can't mention people, roles or channels")
+var ErrShoutDenied = errors.New("Shout contains a word that isn't allowed")
+var ErrShoutRateLimited = errors.New("Too many shouts. Please slow down")
+var ErrShoutAccountTooNew = errors.New("Your Discord account is too new to shout")
+var ErrShoutEmpty = errors.New("Shouts need a message")
+var ErrShoutName = errors.New("Please give a name of up to 32 characters")
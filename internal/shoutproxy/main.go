@@ -3,8 +3,13 @@ package shoutproxy
 import (
 	"context"
 	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/storage"
 	"log/slog"
 	"os"
+	"sync"
+	"time"
 )
 
 type ShoutProxy struct {
@@ -12,14 +17,29 @@ type ShoutProxy struct {
 
 	// The context given to us by the main bot.
 	ctx *context.Context
+
+	// Persistent storage for shouts awaiting Crew approval.
+	store *storage.Store
+
+	// Held while looking at or changing shouts.
+	mtx sync.Mutex
+
+	// When each user recently shouted, for rate limiting.
+	shouts map[string][]time.Time
+
+	// The shouts being shown, oldest first (see recent.go). The mutex MUST be held to interact with them.
+	recent    []ngtbg.NodeCGReplicantDataShoutboxEntry
+	recentMtx sync.Mutex
 }
 
 // logger stores the module's logger instance.
 var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-func New(discord *discordgo.Session) (mod *ShoutProxy, err error) {
+func New(discord *discordgo.Session, store *storage.Store) (mod *ShoutProxy, err error) {
 	return &ShoutProxy{
 		discord: discord,
+		store:   store,
+		shouts:  make(map[string][]time.Time),
 	}, nil
 }
 
@@ -33,6 +53,18 @@ func (mod *ShoutProxy) DiscordCommands() ([]*discordgo.ApplicationCommand, error
 
 func (mod *ShoutProxy) Start(ctx context.Context) (err error) {
 	mod.ctx = &ctx
-	// This module simply registers handlers, and does not need to run continuously (so we don't need the context)
+	// Work out what should be on the projector, and make sure NodeCG knows whenever the bridge (re)connects.
+	if err = mod.rebuildRecentShouts(mod.discord); err != nil {
+		logger.Error("Unable to rebuild shoutbox from channel history", slog.Any("error", err))
+	}
+	bridge_wan.OnConnect(func() {
+		mod.recentMtx.Lock()
+		defer mod.recentMtx.Unlock()
+		mod.publishRecentShouts()
+	})
+	if sources := shoutSources(); len(sources) > 0 {
+		return mod.runShoutSources(ctx, sources)
+	}
+	// Otherwise, this module simply registers handlers, and does not need to run continuously (so we don't need the context)
 	return ctx.Err()
 }
@@ -0,0 +1,447 @@
+package shoutproxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"github.com/thebiggame/bigbot/internal/storage/storagetest"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// An account created in 2016 (well before any test runs).
+const testUserID = "80351110224678912"
+
+func newTestShoutProxy(t *testing.T) *ShoutProxy {
+	t.Helper()
+	store := storagetest.Open(t)
+	mod, err := New(nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.RuntimeConfig.Discord.Shoutbox
+	t.Cleanup(func() { config.RuntimeConfig.Discord.Shoutbox = previous })
+	config.RuntimeConfig.Discord.Shoutbox.MaxLength = 20
+	config.RuntimeConfig.Discord.Shoutbox.DenyList = []string{"heck"}
+	config.RuntimeConfig.Discord.Shoutbox.AllowLinks = false
+	config.RuntimeConfig.Discord.Shoutbox.AllowMentions = false
+	config.RuntimeConfig.Discord.Shoutbox.RateLimit = 0
+	config.RuntimeConfig.Discord.Shoutbox.MinAccountAge = 0
+	return mod
+}
+
+func testShout(content string) *discordgo.Message {
+	return &discordgo.Message{
+		ID:      "1",
+		Content: content,
+		Author:  &discordgo.User{ID: testUserID, Username: "alice"},
+	}
+}
+
+func TestFilterShout(t *testing.T) {
+	newTestShoutProxy(t)
+	tests := []struct {
+		content string
+		want    error
+	}{
+		{content: "GG everybody!", want: nil},
+		{content: "This shout is far too long to show", want: ErrShoutTooLong},
+		{content: "oh H.E.C.K", want: ErrShoutDenied},
+		{content: "Héck yes", want: ErrShoutDenied},
+		{content: "h e c k", want: ErrShoutDenied},
+		// Denied words inside other words are fine.
+		{content: "check the cklist", want: nil},
+		{content: "see example.com", want: ErrShoutLink},
+		{content: "https://x.y/z", want: ErrShoutLink},
+		{content: "hi <@1234>", want: ErrShoutMention},
+		{content: "@everyone look", want: ErrShoutMention},
+		{content: "check <#5678>", want: ErrShoutMention},
+	}
+	for _, test := range tests {
+		if got := filterShout(testShout(test.content)); !errors.Is(got, test.want) {
+			t.Errorf("filterShout(%q) = %v, want %v", test.content, got, test.want)
+		}
+	}
+
+	config.RuntimeConfig.Discord.Shoutbox.AllowLinks = true
+	config.RuntimeConfig.Discord.Shoutbox.AllowMentions = true
+	for _, content := range []string{"see example.com", "hi <@1234>"} {
+		if err := filterShout(testShout(content)); err != nil {
+			t.Errorf("filterShout(%q) should be allowed, got %v", content, err)
+		}
+	}
+}
+
+func TestModerateShoutRateLimit(t *testing.T) {
+	mod := newTestShoutProxy(t)
+	config.RuntimeConfig.Discord.Shoutbox.RateLimit = 2
+	config.RuntimeConfig.Discord.Shoutbox.RateWindow = time.Minute
+	now := time.Now()
+
+	for idx := 0; idx < 2; idx++ {
+		if err := mod.moderateShout(testShout("hello"), now); err != nil {
+			t.Fatalf("Shout %d should be allowed, got %v", idx+1, err)
+		}
+	}
+	if err := mod.moderateShout(testShout("hello"), now.Add(time.Second)); !errors.Is(err, ErrShoutRateLimited) {
+		t.Errorf("Third shout should be rate limited, got %v", err)
+	}
+	if err := mod.moderateShout(testShout("hello"), now.Add(time.Minute)); err != nil {
+		t.Errorf("Shouts should be allowed again after the window, got %v", err)
+	}
+	// Rejected shouts don't count towards the limit.
+	if err := mod.moderateShout(testShout("heck"), now.Add(time.Minute)); !errors.Is(err, ErrShoutDenied) {
+		t.Errorf("Expected shout to be denied, got %v", err)
+	}
+	if err := mod.moderateShout(testShout("hello"), now.Add(time.Minute)); err != nil {
+		t.Errorf("Denied shouts shouldn't count towards the limit, got %v", err)
+	}
+}
+
+func TestModerateShoutAccountAge(t *testing.T) {
+	mod := newTestShoutProxy(t)
+	config.RuntimeConfig.Discord.Shoutbox.MinAccountAge = 24 * time.Hour
+	created, err := discordgo.SnowflakeTimestamp(testUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mod.moderateShout(testShout("hello"), created.Add(time.Hour)); !errors.Is(err, ErrShoutAccountTooNew) {
+		t.Errorf("New accounts shouldn't be able to shout, got %v", err)
+	}
+	if err = mod.moderateShout(testShout("hello"), created.Add(48*time.Hour)); err != nil {
+		t.Errorf("Older accounts should be able to shout, got %v", err)
+	}
+}
+
+func TestMemberIsCrew(t *testing.T) {
+	config.RuntimeConfig.Discord.Permissions.CrewRole = "42"
+	t.Cleanup(func() { config.RuntimeConfig.Discord.Permissions.CrewRole = "" })
+	if memberIsCrew(nil) || memberIsCrew(&discordgo.Member{Roles: []string{"1"}}) {
+		t.Error("Non-crew members shouldn't be treated as Crew")
+	}
+	if !memberIsCrew(&discordgo.Member{Roles: []string{"1", "42"}}) {
+		t.Error("Crew members should be treated as Crew")
+	}
+}
+
+func TestNewShoutEntry(t *testing.T) {
+	m := testShout("hello")
+	m.GuildID = "99"
+	m.Member = &discordgo.Member{Nick: "Ally", Avatar: "abc"}
+	entry := newShoutEntry(nil, m)
+	if entry.ID != "DISC-1" || entry.User.Name != "Ally" || entry.Message != "hello" {
+		t.Errorf("Unexpected shout entry %+v", entry)
+	}
+	if entry.User.Avatar == "" {
+		t.Error("Expected the server avatar to be used")
+	}
+	m.Member = nil
+	if entry = newShoutEntry(nil, m); entry.User.Name != "alice" {
+		t.Errorf("Expected to fall back to the username, got %q", entry.User.Name)
+	}
+}
+
+func TestIsShout(t *testing.T) {
+	config.RuntimeConfig.Discord.GuildID = "99"
+	config.RuntimeConfig.Discord.Shoutbox.ChannelID = ""
+	t.Cleanup(func() {
+		config.RuntimeConfig.Discord.GuildID = ""
+		config.RuntimeConfig.Discord.Shoutbox.ChannelID = ""
+	})
+	m := &discordgo.Message{ID: "1", ChannelID: "5", GuildID: "99"}
+	if isShout(m) {
+		t.Error("Nothing should be a shout without a shoutbox channel")
+	}
+	config.RuntimeConfig.Discord.Shoutbox.ChannelID = "5"
+	if !isShout(m) {
+		t.Error("Messages in the shoutbox channel should be shouts")
+	}
+	if isShout(&discordgo.Message{ID: "1", ChannelID: "6", GuildID: "99"}) || isShout(&discordgo.Message{ID: "1", ChannelID: "5", GuildID: "100"}) {
+		t.Error("Messages elsewhere shouldn't be shouts")
+	}
+	if id := shoutID(m.ID); id != "DISC-1" {
+		t.Errorf("Unexpected shout ID %q", id)
+	}
+}
+
+func TestRenderShout(t *testing.T) {
+	state := discordgo.NewState()
+	err := state.GuildAdd(&discordgo.Guild{
+		ID:       "99",
+		Roles:    []*discordgo.Role{{ID: "7", Name: "Crew"}},
+		Channels: []*discordgo.Channel{{ID: "8", Name: "general", GuildID: "99"}},
+		Members:  []*discordgo.Member{{GuildID: "99", Nick: "Bobby", User: &discordgo.User{ID: "2", Username: "bob"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &discordgo.Message{
+		GuildID: "99",
+		Content: "hi <@2> and <@!3>, ask <@&7> in <#8> <:partyparrot:123> <a:dance:456> ||it was him|| at <t:0:t>",
+		Mentions: []*discordgo.User{
+			{ID: "2", Username: "bob"},
+			{ID: "3", Username: "carol", GlobalName: "Carol"},
+		},
+		Attachments: []*discordgo.MessageAttachment{
+			{URL: "https://cdn.example/cat.png", Filename: "cat.png", ContentType: "image/png", Width: 640, Height: 480},
+			{URL: "https://cdn.example/dog.png", Filename: "SPOILER_dog.png", ContentType: "image/png"},
+			{URL: "https://cdn.example/notes.txt", Filename: "notes.txt", ContentType: "text/plain"},
+		},
+		StickerItems: []*discordgo.StickerItem{
+			{ID: "11", Name: "wave", FormatType: discordgo.StickerFormatTypePNG},
+			{ID: "12", Name: "lottie", FormatType: discordgo.StickerFormatTypeLottie},
+		},
+	}
+
+	content, media := renderShout(state, m)
+	for _, want := range []string{
+		"hi @Bobby and @Carol",
+		"ask @Crew in #general",
+		"![:partyparrot:](" + discordgo.EndpointEmoji("123") + ")",
+		"![:dance:](" + discordgo.EndpointEmojiAnimated("456") + ")",
+		spoilerPlaceholder,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %q in rendered shout %q", want, content)
+		}
+	}
+	if strings.Contains(content, "it was him") || strings.Contains(content, "<t:") {
+		t.Errorf("Spoilers and timestamps should be rendered, got %q", content)
+	}
+
+	if len(media) != 2 {
+		t.Fatalf("Expected an image and a sticker, got %+v", media)
+	}
+	if media[0].Type != ngtbg.ShoutboxMediaImage || media[0].URL != "https://cdn.example/cat.png" || media[0].Width != 640 {
+		t.Errorf("Unexpected image %+v", media[0])
+	}
+	if media[1].Type != ngtbg.ShoutboxMediaSticker || media[1].Name != "wave" || !strings.HasSuffix(media[1].URL, "/stickers/11.png") {
+		t.Errorf("Unexpected sticker %+v", media[1])
+	}
+
+	// Without the state cache, mentions fall back to what the message tells us.
+	if content, _ = renderShout(nil, m); !strings.Contains(content, "hi @bob and @Carol, ask @role in #channel") {
+		t.Errorf("Unexpected fallback rendering %q", content)
+	}
+}
+
+func TestRecentShouts(t *testing.T) {
+	mod := newTestShoutProxy(t)
+	config.RuntimeConfig.Discord.Shoutbox.RecentLength = 2
+	entry := func(messageID, message string) ngtbg.NodeCGReplicantDataShoutboxEntry {
+		return ngtbg.NodeCGReplicantDataShoutboxEntry{ID: shoutID(messageID), Message: message}
+	}
+
+	// The event bridge isn't running, so sending to NodeCG fails; we only care about the list here.
+	_ = mod.showShout(entry("1", "one"))
+	_ = mod.showShout(entry("2", "two"))
+	_ = mod.showShout(entry("3", "three"))
+	if recent := mod.recentShouts(); len(recent) != 2 || recent[0].Message != "two" || recent[1].Message != "three" {
+		t.Fatalf("Expected only the most recent shouts to be kept, got %+v", recent)
+	}
+
+	_ = mod.editShout(entry("2", "two (edited)"))
+	_ = mod.editShout(entry("1", "one (edited)"))
+	if recent := mod.recentShouts(); len(recent) != 2 || recent[0].Message != "two (edited)" {
+		t.Errorf("Expected the shout to be edited (and old shouts not to come back), got %+v", recent)
+	}
+
+	_ = mod.hideShout(shoutID("3"))
+	if recent := mod.recentShouts(); len(recent) != 1 || recent[0].ID != "DISC-2" {
+		t.Errorf("Expected the shout to be removed, got %+v", recent)
+	}
+}
+
+func TestWasShown(t *testing.T) {
+	newTestShoutProxy(t)
+	if !wasShown(testShout("hello")) {
+		t.Error("Ordinary shouts should have been shown")
+	}
+	if wasShown(testShout("heck")) {
+		t.Error("Shouts that fail the filters shouldn't have been shown")
+	}
+	for _, reaction := range []string{rejectedReaction, pendingReaction} {
+		m := testShout("hello")
+		m.Reactions = []*discordgo.MessageReactions{{Me: true, Emoji: &discordgo.Emoji{Name: reaction}}}
+		if wasShown(m) {
+			t.Errorf("Shouts we reacted to with %s shouldn't have been shown", reaction)
+		}
+		// Other people's reactions don't count.
+		m.Reactions[0].Me = false
+		if !wasShown(m) {
+			t.Errorf("Shouts other people reacted to with %s should have been shown", reaction)
+		}
+	}
+}
+
+func TestSubmitExternalShout(t *testing.T) {
+	mod := newTestShoutProxy(t)
+	config.RuntimeConfig.Discord.Shoutbox.RateLimit = 1
+	config.RuntimeConfig.Discord.Shoutbox.RateWindow = time.Minute
+	config.RuntimeConfig.Discord.Shoutbox.RecentLength = 5
+	source := &webSource{mod: mod}
+	entry := func(id, name, message string) ngtbg.NodeCGReplicantDataShoutboxEntry {
+		entry := ngtbg.NodeCGReplicantDataShoutboxEntry{ID: id, Message: message, Source: ngtbg.ShoutboxSourceWeb}
+		entry.User.Name = name
+		return entry
+	}
+	now := time.Now()
+
+	tests := []struct {
+		userKey string
+		entry   ngtbg.NodeCGReplicantDataShoutboxEntry
+		want    error
+	}{
+		{userKey: "web:a", entry: entry("WEB-1", "Alice", "   "), want: ErrShoutEmpty},
+		{userKey: "web:a", entry: entry("WEB-2", "Heck Yeah", "hello"), want: ErrShoutDenied},
+		{userKey: "web:a", entry: entry("WEB-3", "Alice", "see example.com"), want: ErrShoutLink},
+		{userKey: "web:a", entry: entry("WEB-4", "Alice", "hello"), want: nil},
+		{userKey: "web:a", entry: entry("WEB-5", "Alice", "hello again"), want: ErrShoutRateLimited},
+		// The same ID from a different source is somebody else.
+		{userKey: "twitch:a", entry: entry("TWCH-6", "Alice", "hi from Twitch"), want: nil},
+	}
+	for _, test := range tests {
+		// The event bridge isn't running, so sending to NodeCG fails; we only care about moderation here.
+		_, reason, _ := mod.submitExternalShout(source, test.userKey, test.entry, now)
+		if !errors.Is(reason, test.want) {
+			t.Errorf("submitExternalShout(%q, %q) = %v, want %v", test.userKey, test.entry.Message, reason, test.want)
+		}
+	}
+	if recent := mod.recentShouts(); len(recent) != 2 || recent[0].ID != "WEB-4" || recent[1].ID != "TWCH-6" {
+		t.Errorf("Expected only the accepted shouts to be shown, got %+v", recent)
+	}
+}
+
+func TestWebSource(t *testing.T) {
+	mod := newTestShoutProxy(t)
+	if _, err := bridge_wan.New(); err != nil {
+		t.Fatal(err)
+	}
+	config.RuntimeConfig.Discord.Shoutbox.RecentLength = 5
+	source := &webSource{mod: mod}
+	post := func(name, message string) *httptest.ResponseRecorder {
+		form := url.Values{"name": {name}, "message": {message}}
+		r := httptest.NewRequest(http.MethodPost, "/shout", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		source.handle(w, r)
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	source.handle(w, httptest.NewRequest(http.MethodGet, "/shout", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<form method="post"`) {
+		t.Fatalf("Expected the form, got %d: %s", w.Code, w.Body)
+	}
+
+	if w = post("", "hello"); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), ErrShoutName.Error()) {
+		t.Errorf("Expected a shout without a name to be rejected, got %d", w.Code)
+	}
+	if w = post("Alice", "oh heck"); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "oh heck") {
+		t.Errorf("Expected the rejected shout to be kept in the form, got %d: %s", w.Code, w.Body)
+	}
+	if w = post(" Alice ", "hello <b>"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "on its way") {
+		t.Errorf("Expected the shout to be accepted, got %d: %s", w.Code, w.Body)
+	}
+	recent := mod.recentShouts()
+	if len(recent) != 1 || recent[0].User.Name != "Alice" || recent[0].Message != "hello <b>" ||
+		recent[0].Source != ngtbg.ShoutboxSourceWeb || !strings.HasPrefix(recent[0].ID, "WEB-") {
+		t.Errorf("Expected the shout to be shown, got %+v", recent)
+	}
+
+	w = httptest.NewRecorder()
+	source.handle(w, httptest.NewRequest(http.MethodDelete, "/shout", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected other methods to be refused, got %d", w.Code)
+	}
+}
+
+func TestParseIRCMessage(t *testing.T) {
+	msg := parseIRCMessage(`@display-name=Alice;id=abc-123;msg\s=semi\:colon\sand\\slash :alice!alice@alice.tmi.twitch.tv PRIVMSG #thebiggame :hello there :)` + "\r\n")
+	if msg.command != "PRIVMSG" || msg.nick() != "alice" || msg.param(0) != "#thebiggame" || msg.param(1) != "hello there :)" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if msg.tags["display-name"] != "Alice" || msg.tags["id"] != "abc-123" || msg.tags[`msg\s`] != `semi;colon and\slash` {
+		t.Errorf("Unexpected tags %+v", msg.tags)
+	}
+
+	msg = parseIRCMessage("PING :tmi.twitch.tv")
+	if msg.command != "PING" || msg.param(0) != "tmi.twitch.tv" || msg.param(1) != "" || msg.tags != nil {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+}
+
+func TestTwitchSource(t *testing.T) {
+	mod := newTestShoutProxy(t)
+	config.RuntimeConfig.Discord.Shoutbox.RecentLength = 5
+	client, server := net.Pipe()
+	source := newTwitchSource("#TheBIGGAME", "")
+	source.dial = func(context.Context, string) (net.Conn, error) {
+		return client, nil
+	}
+	done := make(chan error)
+	go func() {
+		done <- source.read(context.Background(), mod)
+	}()
+
+	lines := bufio.NewScanner(server)
+	var joined bool
+	for !joined && lines.Scan() {
+		joined = lines.Text() == "JOIN #thebiggame"
+	}
+	if !joined {
+		t.Fatal("Expected to join the channel")
+	}
+	for _, line := range []string{
+		"@display-name=Alice;id=1;user-id=10;tmi-sent-ts=1700000000000 :alice!alice@alice.tmi.twitch.tv PRIVMSG #thebiggame :GG!",
+		"@display-name=Bob;id=2;user-id=11 :bob!bob@bob.tmi.twitch.tv PRIVMSG #thebiggame :!uptime",
+		"@display-name=Bob;id=3;user-id=11 :bob!bob@bob.tmi.twitch.tv PRIVMSG #thebiggame :\x01ACTION waves\x01",
+		"@id=4;user-id=12 :carol!carol@carol.tmi.twitch.tv PRIVMSG #thebiggame :oh heck",
+		"@login=bob;target-msg-id=3 :tmi.twitch.tv CLEARMSG #thebiggame :waves",
+		"PING :tmi.twitch.tv",
+	} {
+		if _, err := server.Write([]byte(line + "\r\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !lines.Scan() || lines.Text() != "PONG :tmi.twitch.tv" {
+		t.Errorf("Expected a PONG, got %q", lines.Text())
+	}
+	_ = server.Close()
+	<-done
+
+	recent := mod.recentShouts()
+	if len(recent) != 1 {
+		t.Fatalf("Expected only Alice's shout to be left, got %+v", recent)
+	}
+	if shout := recent[0]; shout.ID != "TWCH-1" || shout.User.Name != "Alice" || shout.Message != "GG!" ||
+		shout.Source != ngtbg.ShoutboxSourceTwitch || shout.Timestamp != time.UnixMilli(1700000000000).Format(time.RFC3339) {
+		t.Errorf("Unexpected shout %+v", shout)
+	}
+}
+
+func TestClaimShoutReview(t *testing.T) {
+	mod := newTestShoutProxy(t)
+	review := shoutReview{Entry: ngtbg.NodeCGReplicantDataShoutboxEntry{ID: "DSCD-1", Message: "hello"}}
+	if err := mod.store.Put(shoutReviewsBucket, "1", review); err != nil {
+		t.Fatal(err)
+	}
+	claimed, err := mod.claimShoutReview("1")
+	if err != nil || claimed.Entry.ID != "DSCD-1" {
+		t.Fatalf("claimShoutReview() = %+v, %v", claimed, err)
+	}
+	// Whoever clicks second finds it already reviewed.
+	if _, err = mod.claimShoutReview("1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected a claimed shout to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package shoutproxy
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file handles the moderation of shouts, before they go anywhere near the projector.
+// Each shout is checked against the content filters, then the sender's account age and rate limit.
+// Shouts that pass are shown straight away, or sent to Crew for approval if a review channel is set.
+
+// The reaction added to shouts that weren't shown, so that the sender knows.
+const rejectedReaction = "🚫"
+
+// linkPattern matches anything that looks like a link (with or without a scheme).
+var linkPattern = regexp.MustCompile(`(?i)(?:[a-z][a-z0-9+.-]*://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|io|gg|co|uk|tv|me|ly|xyz|app|dev)\b`)
+
+// mentionPattern matches user, role and channel mentions, plus @everyone and @here.
+var mentionPattern = regexp.MustCompile(`<@[!&]?\d+>|<#\d+>|@everyone|@here`)
+
+// moderateShout checks a shout against the moderation rules, returning why it can't be shown (or nil if it can).
+// Shouts that pass count towards the sender's rate limit.
+func (mod *ShoutProxy) moderateShout(m *discordgo.Message, now time.Time) error {
+	if err := filterShout(m); err != nil {
+		return err
+	}
+	minAge := config.RuntimeConfig.Discord.Shoutbox.MinAccountAge
+	if created, err := discordgo.SnowflakeTimestamp(m.Author.ID); err == nil && minAge > 0 && now.Sub(created) < minAge {
+		return ErrShoutAccountTooNew
+	}
+	return mod.recordShout(m.Author.ID, now)
+}
+
+// filterShout checks a Discord shout's content against the configured filters.
+func filterShout(m *discordgo.Message) error {
+	if !config.RuntimeConfig.Discord.Shoutbox.AllowMentions && (m.MentionEveryone || len(m.Mentions) > 0 || len(m.MentionRoles) > 0) {
+		return ErrShoutMention
+	}
+	return filterContent(m.Content)
+}
+
+// filterContent checks a shout's text (from any source) against the configured filters.
+func filterContent(content string) error {
+	shoutbox := config.RuntimeConfig.Discord.Shoutbox
+	if shoutbox.MaxLength > 0 && utf8.RuneCountInString(content) > shoutbox.MaxLength {
+		return ErrShoutTooLong
+	}
+	if !shoutbox.AllowMentions && mentionPattern.MatchString(content) {
+		return ErrShoutMention
+	}
+	if !shoutbox.AllowLinks && linkPattern.MatchString(content) {
+		return ErrShoutLink
+	}
+	folded := foldShout(content)
+	for _, denied := range shoutbox.DenyList {
+		// Only whole words count, so that innocent words which happen to contain a denied one get through.
+		if words := foldShout(denied); strings.TrimSpace(words) != "" && strings.Contains(folded, words) {
+			return ErrShoutDenied
+		}
+	}
+	return nil
+}
+
+// foldShout reduces text to its words in lower-case letters and digits (without accents), so that denied words can't
+// be slipped past the filter with a little punctuation or capitalisation. Runs of single letters are joined up, so
+// that spelling a word out doesn't work either. The words are separated (and surrounded) by single spaces.
+func foldShout(text string) string {
+	var words []string
+	var spelled string
+	for _, field := range strings.Fields(norm.NFKD.String(text)) {
+		var sb strings.Builder
+		for _, r := range field {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				sb.WriteRune(unicode.ToLower(r))
+			}
+		}
+		word := sb.String()
+		switch {
+		case word == "":
+			continue
+		case utf8.RuneCountInString(word) == 1:
+			spelled += word
+			continue
+		case spelled != "":
+			words = append(words, spelled)
+			spelled = ""
+		}
+		words = append(words, word)
+	}
+	if spelled != "" {
+		words = append(words, spelled)
+	}
+	return " " + strings.Join(words, " ") + " "
+}
+
+// recordShout notes that the given user has shouted, unless they've already shouted too much recently.
+// Users from sources other than Discord are identified by a prefix (see shoutSource).
+func (mod *ShoutProxy) recordShout(userID string, now time.Time) error {
+	limit, window := config.RuntimeConfig.Discord.Shoutbox.RateLimit, config.RuntimeConfig.Discord.Shoutbox.RateWindow
+	if limit <= 0 {
+		return nil
+	}
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	recent := mod.shouts[userID][:0]
+	for _, t := range mod.shouts[userID] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit {
+		mod.shouts[userID] = recent
+		return ErrShoutRateLimited
+	}
+	mod.shouts[userID] = append(recent, now)
+	return nil
+}
+
+// memberIsCrew returns whether the given member has the Crew role. Crew shouts skip moderation.
+func memberIsCrew(member *discordgo.Member) bool {
+	crewRoleID := config.RuntimeConfig.Discord.Permissions.CrewRole
+	if member == nil || crewRoleID == "" {
+		return false
+	}
+	for _, roleID := range member.Roles {
+		if roleID == crewRoleID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package shoutproxy
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/config"
+	"log/slog"
+	"slices"
+)
+
+// This file handles the shoutbox:messages replicant, which holds the most recent shouts being shown.
+// BIGbot is the authority on what's in it: the list is rebuilt from the shoutbox channel's history when we start, and
+// sent to NodeCG whenever it changes (or the event bridge reconnects), so the infoboard recovers after a restart.
+
+// showShout shows a new shout on the projector.
+func (mod *ShoutProxy) showShout(entry ngtbg.NodeCGReplicantDataShoutboxEntry) error {
+	mod.updateRecentShouts(func(shouts []ngtbg.NodeCGReplicantDataShoutboxEntry) []ngtbg.NodeCGReplicantDataShoutboxEntry {
+		return append(shouts, entry)
+	})
+	return mod.forwardShout(ngtbg.NodeCGMessageShoutboxNew, entry)
+}
+
+// editShout updates a shout that may be on the projector.
+func (mod *ShoutProxy) editShout(entry ngtbg.NodeCGReplicantDataShoutboxEntry) error {
+	mod.updateRecentShouts(func(shouts []ngtbg.NodeCGReplicantDataShoutboxEntry) []ngtbg.NodeCGReplicantDataShoutboxEntry {
+		if idx := slices.IndexFunc(shouts, func(shout ngtbg.NodeCGReplicantDataShoutboxEntry) bool { return shout.ID == entry.ID }); idx >= 0 {
+			shouts[idx] = entry
+		}
+		return shouts
+	})
+	return mod.forwardShout(ngtbg.NodeCGMessageShoutboxEdit, entry)
+}
+
+// hideShout removes a shout from the projector (if it's there), given its ID.
+func (mod *ShoutProxy) hideShout(id string) error {
+	mod.updateRecentShouts(func(shouts []ngtbg.NodeCGReplicantDataShoutboxEntry) []ngtbg.NodeCGReplicantDataShoutboxEntry {
+		return slices.DeleteFunc(shouts, func(shout ngtbg.NodeCGReplicantDataShoutboxEntry) bool { return shout.ID == id })
+	})
+	return mod.forwardShout(ngtbg.NodeCGMessageShoutboxRemove, ngtbg.NodeCGMessageDataShoutboxRemove{ID: id})
+}
+
+// updateRecentShouts changes the list of recent shouts (keeping only the most recent), then tells NodeCG.
+func (mod *ShoutProxy) updateRecentShouts(update func([]ngtbg.NodeCGReplicantDataShoutboxEntry) []ngtbg.NodeCGReplicantDataShoutboxEntry) {
+	length := config.RuntimeConfig.Discord.Shoutbox.RecentLength
+	if length <= 0 {
+		return
+	}
+	mod.recentMtx.Lock()
+	defer mod.recentMtx.Unlock()
+	mod.recent = update(mod.recent)
+	if excess := len(mod.recent) - length; excess > 0 {
+		mod.recent = slices.Delete(mod.recent, 0, excess)
+	}
+	mod.publishRecentShouts()
+}
+
+// publishRecentShouts sends the list of recent shouts to NodeCG. This is best-effort; if the event bridge isn't
+// available, it'll be sent when it reconnects. mod.recentMtx must be held.
+func (mod *ShoutProxy) publishRecentShouts() {
+	if config.RuntimeConfig.Discord.Shoutbox.RecentLength <= 0 || !bridge_wan.BridgeIsAvailable() {
+		return
+	}
+	data := ngtbg.NodeCGReplicantDataShoutboxEntries{
+		Shouts: slices.Clone(mod.recent),
+	}
+	if data.Shouts == nil {
+		// NodeCG expects a list, even if it's empty.
+		data.Shouts = []ngtbg.NodeCGReplicantDataShoutboxEntry{}
+	}
+	err := bridge_wan.ReplicantSet(ngtbg.ReplicantShoutbox, data)
+	if err != nil {
+		logger.Warn("Unable to update shoutbox replicant", slog.Any("error", err))
+	}
+}
+
+// rebuildRecentShouts reads the shoutbox channel's history to find the shouts that were being shown, for when
+// we start.
+func (mod *ShoutProxy) rebuildRecentShouts(s *discordgo.Session) error {
+	channelID, length := config.RuntimeConfig.Discord.Shoutbox.ChannelID, config.RuntimeConfig.Discord.Shoutbox.RecentLength
+	if channelID == "" || length <= 0 {
+		return nil
+	}
+	// Fetch a few more than we need, as some won't have been shown.
+	messages, err := s.ChannelMessages(channelID, min(length*2, 100), "", "", "")
+	if err != nil {
+		return err
+	}
+	members := make(map[string]*discordgo.Member)
+	var shouts []ngtbg.NodeCGReplicantDataShoutboxEntry
+	// Messages come newest first.
+	for _, m := range slices.Backward(messages) {
+		if m.Author == nil || m.Author.ID == s.State.User.ID {
+			continue
+		}
+		if m.GuildID == "" {
+			// Messages fetched from the API don't say which guild they're in.
+			m.GuildID = config.RuntimeConfig.Discord.GuildID
+		}
+		member, ok := members[m.Author.ID]
+		if !ok {
+			// The member list may not have arrived yet, so ask Discord. (They may also have left since.)
+			if member, err = s.GuildMember(m.GuildID, m.Author.ID); err != nil {
+				logger.Debug("Unable to look up shout author", slog.String("user", m.Author.Username), slog.Any("error", err))
+				member = nil
+			}
+			members[m.Author.ID] = member
+		}
+		m.Member = member
+		if !wasShown(m) {
+			continue
+		}
+		entry := newShoutEntry(s.State, m)
+		if entry.Message == "" && len(entry.Media) == 0 {
+			continue
+		}
+		shouts = append(shouts, entry)
+	}
+
+	mod.updateRecentShouts(func([]ngtbg.NodeCGReplicantDataShoutboxEntry) []ngtbg.NodeCGReplicantDataShoutboxEntry {
+		return shouts
+	})
+	logger.Info("Rebuilt shoutbox from channel history", slog.Int("shouts", len(mod.recentShouts())))
+	return nil
+}
+
+// wasShown returns whether a shout from the channel's history was (probably) shown on the projector. Shouts that were
+// rejected or are waiting for Crew have our reaction on them; others must (still) pass the content filters.
+func wasShown(m *discordgo.Message) bool {
+	for _, reaction := range m.Reactions {
+		if reaction.Me && reaction.Emoji != nil && (reaction.Emoji.Name == rejectedReaction || reaction.Emoji.Name == pendingReaction) {
+			return false
+		}
+	}
+	return memberIsCrew(m.Member) || filterShout(m) == nil
+}
+
+// recentShouts returns the shouts currently being shown, oldest first.
+func (mod *ShoutProxy) recentShouts() []ngtbg.NodeCGReplicantDataShoutboxEntry {
+	mod.recentMtx.Lock()
+	defer mod.recentMtx.Unlock()
+	return slices.Clone(mod.recent)
+}
@@ -0,0 +1,117 @@
+package shoutproxy
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file handles turning Discord's message format into something the projector can show.
+// Mentions become names, custom emoji become images, and spoilers are hidden.
+
+// What spoilers are replaced with. (Spoilers are for people who opted in to seeing them, and the projector can't ask.)
+const spoilerPlaceholder = "[spoiler]"
+
+var (
+	spoilerPattern     = regexp.MustCompile(`(?s)\|\|.+?\|\|`)
+	userMentionPattern = regexp.MustCompile(`<@!?(\d+)>`)
+	roleMentionPattern = regexp.MustCompile(`<@&(\d+)>`)
+	channelPattern     = regexp.MustCompile(`<#(\d+)>`)
+	customEmojiPattern = regexp.MustCompile(`<(a?):(\w+):(\d+)>`)
+	timestampPattern   = regexp.MustCompile(`<t:(-?\d+)(?::[tTdDfFR])?>`)
+)
+
+// renderShout converts a shout's content into display-safe markdown, and collects any images to show alongside it.
+// state is used to look up display names, roles and channels; without it, mentions fall back to what the message
+// itself tells us.
+func renderShout(state *discordgo.State, m *discordgo.Message) (content string, media []ngtbg.NodeCGReplicantDataShoutboxMedia) {
+	content = spoilerPattern.ReplaceAllString(m.Content, spoilerPlaceholder)
+	content = userMentionPattern.ReplaceAllStringFunc(content, func(mention string) string {
+		return "@" + mentionedUserName(state, m, userMentionPattern.FindStringSubmatch(mention)[1])
+	})
+	content = roleMentionPattern.ReplaceAllStringFunc(content, func(mention string) string {
+		roleID := roleMentionPattern.FindStringSubmatch(mention)[1]
+		if state != nil {
+			if role, err := state.Role(m.GuildID, roleID); err == nil {
+				return "@" + role.Name
+			}
+		}
+		return "@role"
+	})
+	content = channelPattern.ReplaceAllStringFunc(content, func(mention string) string {
+		channelID := channelPattern.FindStringSubmatch(mention)[1]
+		if state != nil {
+			if channel, err := state.Channel(channelID); err == nil {
+				return "#" + channel.Name
+			}
+		}
+		return "#channel"
+	})
+	content = customEmojiPattern.ReplaceAllStringFunc(content, func(emoji string) string {
+		match := customEmojiPattern.FindStringSubmatch(emoji)
+		url := discordgo.EndpointEmoji(match[3])
+		if match[1] == "a" {
+			url = discordgo.EndpointEmojiAnimated(match[3])
+		}
+		return "![:" + match[2] + ":](" + url + ")"
+	})
+	content = timestampPattern.ReplaceAllStringFunc(content, func(timestamp string) string {
+		seconds, err := strconv.ParseInt(timestampPattern.FindStringSubmatch(timestamp)[1], 10, 64)
+		if err != nil {
+			return timestamp
+		}
+		return time.Unix(seconds, 0).Format("Mon 15:04")
+	})
+
+	for _, attachment := range m.Attachments {
+		if !strings.HasPrefix(attachment.ContentType, "image/") || strings.HasPrefix(attachment.Filename, "SPOILER_") {
+			// Only images can be shown, and spoilers stay hidden.
+			continue
+		}
+		media = append(media, ngtbg.NodeCGReplicantDataShoutboxMedia{
+			Type:   ngtbg.ShoutboxMediaImage,
+			URL:    attachment.URL,
+			Width:  attachment.Width,
+			Height: attachment.Height,
+		})
+	}
+	for _, sticker := range m.StickerItems {
+		var extension string
+		switch sticker.FormatType {
+		case discordgo.StickerFormatTypePNG, discordgo.StickerFormatTypeAPNG:
+			extension = ".png"
+		case discordgo.StickerFormatTypeGIF:
+			extension = ".gif"
+		default:
+			// Lottie stickers need a special player, so can't be shown as an image.
+			continue
+		}
+		media = append(media, ngtbg.NodeCGReplicantDataShoutboxMedia{
+			Type: ngtbg.ShoutboxMediaSticker,
+			URL:  discordgo.EndpointCDN + "stickers/" + sticker.ID + extension,
+			Name: sticker.Name,
+		})
+	}
+	return strings.TrimSpace(content), media
+}
+
+// mentionedUserName returns the display name of a user mentioned in a message.
+func mentionedUserName(state *discordgo.State, m *discordgo.Message, userID string) string {
+	if state != nil {
+		if member, err := state.Member(m.GuildID, userID); err == nil && member.Nick != "" {
+			return member.Nick
+		}
+	}
+	for _, user := range m.Mentions {
+		if user.ID == userID {
+			if user.GlobalName != "" {
+				return user.GlobalName
+			}
+			return user.Username
+		}
+	}
+	return "someone"
+}
@@ -0,0 +1,208 @@
+package shoutproxy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"strings"
+)
+
+// This file handles Crew pre-approval of shouts, for when a review channel is set.
+
+const (
+	// The storage bucket that shouts awaiting approval are kept in, keyed on the shout's message ID.
+	shoutReviewsBucket = "shoutbox_reviews"
+
+	shoutApproveCustomIDPrefix = "bigbot_shout_approve_"
+	shoutRejectCustomIDPrefix  = "bigbot_shout_reject_"
+
+	// The reaction added to shouts while they're waiting for Crew.
+	pendingReaction = "🔎"
+)
+
+// shoutReview is a shout waiting for Crew's approval.
+type shoutReview struct {
+	// Where the shout was sent, so that we can react to it. (Empty for shouts from outside Discord.)
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+	// Where the message asking Crew to review the shout is, so that it can be updated if the shout changes.
+	ReviewChannelID string `json:"review_channel_id"`
+	ReviewMessageID string `json:"review_message_id"`
+	// What will be sent to NodeCG if the shout is approved.
+	Entry ngtbg.NodeCGReplicantDataShoutboxEntry `json:"entry"`
+}
+
+// submitShoutForReview sends a shout to Crew for approval. The shout is known by key until it's reviewed: the message
+// ID for Discord shouts, or the shout's ID for shouts from elsewhere. sender describes who sent it (or where from).
+func (mod *ShoutProxy) submitShoutForReview(s *discordgo.Session, key string, review shoutReview, sender string) (err error) {
+	review.ReviewChannelID = config.RuntimeConfig.Discord.Shoutbox.ReviewChannelID
+	reviewMessage, err := s.ChannelMessageSendComplex(review.ReviewChannelID, &discordgo.MessageSend{
+		Content: reviewContent(sender, review.Entry),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Approve",
+						Style:    discordgo.SuccessButton,
+						CustomID: shoutApproveCustomIDPrefix + key,
+					},
+					discordgo.Button{
+						Label:    "Reject",
+						Style:    discordgo.DangerButton,
+						CustomID: shoutRejectCustomIDPrefix + key,
+					},
+				},
+			},
+		},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		return err
+	}
+	review.ReviewMessageID = reviewMessage.ID
+	if err = mod.store.Put(shoutReviewsBucket, key, review); err != nil {
+		return err
+	}
+	// Let the sender know their shout has been seen. This is best-effort (and only possible in Discord).
+	if review.ChannelID != "" {
+		if err = s.MessageReactionAdd(review.ChannelID, review.MessageID, pendingReaction); err != nil {
+			logger.Debug("Unable to react to shout", slog.Any("error", err))
+		}
+	}
+	return nil
+}
+
+// reviewContent describes a shout for Crew to review.
+func reviewContent(sender string, entry ngtbg.NodeCGReplicantDataShoutboxEntry) string {
+	content := fmt.Sprintf("🔎 **%s** (%s) shouted:\n%s", entry.User.Name, sender, quote(entry.Message))
+	for _, media := range entry.Media {
+		// Discord will show the images themselves.
+		content += "\n" + media.URL
+	}
+	return content
+}
+
+// updateShoutReview updates a shout that's waiting for Crew's approval after it has been edited, so that Crew approve
+// what will actually be shown. It returns whether the shout was waiting for approval.
+func (mod *ShoutProxy) updateShoutReview(s *discordgo.Session, m *discordgo.Message, entry ngtbg.NodeCGReplicantDataShoutboxEntry) (reviewing bool, err error) {
+	var review shoutReview
+	err = mod.store.Get(shoutReviewsBucket, m.ID, &review)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	review.Entry = entry
+	if err = mod.store.Put(shoutReviewsBucket, m.ID, review); err != nil {
+		return true, err
+	}
+	if review.ReviewMessageID != "" {
+		content := reviewContent(m.Author.Mention(), entry) + "\n-# ✏️ Edited since it was sent"
+		_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:              review.ReviewMessageID,
+			Channel:         review.ReviewChannelID,
+			Content:         &content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+	}
+	return true, err
+}
+
+// withdrawShoutReview removes a shout from Crew's review queue (if it's waiting there), explaining why in the review
+// message instead of the buttons. key is as for submitShoutForReview.
+func (mod *ShoutProxy) withdrawShoutReview(s *discordgo.Session, key, reason string) (err error) {
+	var review shoutReview
+	err = mod.store.Get(shoutReviewsBucket, key, &review)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err = mod.store.Delete(shoutReviewsBucket, key); err != nil {
+		return err
+	}
+	logger.Info("Shout withdrawn from review", slog.String("shout", review.Entry.ID), slog.String("reason", reason))
+	if review.ReviewMessageID == "" {
+		return nil
+	}
+	content := fmt.Sprintf("%s\n**%s**'s shout was:\n%s", reason, review.Entry.User.Name, quote(review.Entry.Message))
+	_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:              review.ReviewMessageID,
+		Channel:         review.ReviewChannelID,
+		Content:         &content,
+		Components:      &[]discordgo.MessageComponent{},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// discordHandleShoutReview handles Crew approving or rejecting a shout.
+func (mod *ShoutProxy) discordHandleShoutReview(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+
+	customID := i.MessageComponentData().CustomID
+	approve := strings.HasPrefix(customID, shoutApproveCustomIDPrefix)
+	key := strings.TrimPrefix(strings.TrimPrefix(customID, shoutApproveCustomIDPrefix), shoutRejectCustomIDPrefix)
+
+	// Claim the shout before showing it, so that it can't be shown twice if two Crew approve it at once.
+	review, err := mod.claimShoutReview(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return helpers.DiscordUpdateComponentMessage(s, i, "🤷 That shout has already been reviewed.")
+	} else if err != nil {
+		return err
+	}
+	if approve {
+		if err = mod.showShout(review.Entry); err != nil {
+			// Put it back, so that Crew can try again.
+			mod.mtx.Lock()
+			defer mod.mtx.Unlock()
+			if putErr := mod.store.Put(shoutReviewsBucket, key, review); putErr != nil {
+				logger.Error("Unable to return shout to review", slog.String("shout", review.Entry.ID), slog.Any("error", putErr))
+			}
+			return err
+		}
+	}
+	logger.Info("Shout reviewed", slog.String("shout", review.Entry.ID), slog.Bool("approved", approve), slog.String("reviewer", helpers.DiscordInteractionUser(i).Username))
+
+	var content string
+	if approve {
+		content = fmt.Sprintf("✅ %s approved **%s**'s shout:\n%s", helpers.DiscordInteractionUser(i).Mention(), review.Entry.User.Name, quote(review.Entry.Message))
+	} else {
+		content = fmt.Sprintf("❌ %s rejected **%s**'s shout:\n%s", helpers.DiscordInteractionUser(i).Mention(), review.Entry.User.Name, quote(review.Entry.Message))
+	}
+	if review.ChannelID != "" {
+		// Let the sender know how it went. This is best-effort; the shout may have been deleted since.
+		if err = s.MessageReactionRemove(review.ChannelID, review.MessageID, pendingReaction, "@me"); err != nil {
+			logger.Debug("Unable to remove reaction from shout", slog.Any("error", err))
+		}
+		if !approve {
+			if err = s.MessageReactionAdd(review.ChannelID, review.MessageID, rejectedReaction); err != nil {
+				logger.Debug("Unable to react to shout", slog.Any("error", err))
+			}
+		}
+	}
+	return helpers.DiscordUpdateComponentMessage(s, i, content)
+}
+
+// claimShoutReview takes a shout out of Crew's review queue, returning storage.ErrNotFound if it isn't there (e.g.
+// because someone else has already reviewed it).
+func (mod *ShoutProxy) claimShoutReview(key string) (review shoutReview, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	if err = mod.store.Get(shoutReviewsBucket, key, &review); err != nil {
+		return review, err
+	}
+	return review, mod.store.Delete(shoutReviewsBucket, key)
+}
+
+// quote formats text as a Discord block quote.
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ")
+}
@@ -0,0 +1,77 @@
+package shoutproxy
+
+import (
+	"context"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	"github.com/thebiggame/bigbot/internal/config"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// This file handles shouts from outside Discord (see web.go and twitch.go). Each source turns what it receives into a
+// shout, which goes through the same moderation (and Crew review) as shouts from the shoutbox channel.
+
+// What shout IDs start with, so that NodeCG can tell shouts from different sources apart.
+const (
+	discordShoutIDPrefix = "DISC-"
+	webShoutIDPrefix     = "WEB-"
+	twitchShoutIDPrefix  = "TWCH-"
+)
+
+// shoutSource is somewhere other than Discord that shouts come from.
+type shoutSource interface {
+	// Name describes the source, for logs and Crew.
+	Name() string
+	// Run receives shouts (passing them to mod.submitExternalShout) until the context is cancelled.
+	Run(ctx context.Context, mod *ShoutProxy) error
+}
+
+// shoutSources returns the sources configured to send shouts, besides the shoutbox channel.
+func shoutSources() (sources []shoutSource) {
+	shoutbox := config.RuntimeConfig.Discord.Shoutbox
+	if shoutbox.Web.Enabled {
+		sources = append(sources, &webSource{})
+	}
+	if shoutbox.Twitch.Channel != "" {
+		sources = append(sources, newTwitchSource(shoutbox.Twitch.Channel, shoutbox.Twitch.Address))
+	}
+	return sources
+}
+
+// runShoutSources runs each source until the context is cancelled.
+func (mod *ShoutProxy) runShoutSources(ctx context.Context, sources []shoutSource) error {
+	group, ctx := errgroup.WithContext(ctx)
+	for _, source := range sources {
+		group.Go(func() error {
+			logger.Info("Accepting shouts", slog.String("source", source.Name()))
+			return source.Run(ctx, mod)
+		})
+	}
+	return group.Wait()
+}
+
+// submitExternalShout moderates a shout from outside Discord, then shows it (or sends it to Crew for approval).
+// userKey identifies the sender for the rate limit, and must be prefixed with the source so that it can't clash with a
+// Discord user ID (e.g. "web:192.0.2.1"). It returns why the shout can't be shown (if it can't), and whether it's
+// waiting for Crew.
+func (mod *ShoutProxy) submitExternalShout(source shoutSource, userKey string, entry ngtbg.NodeCGReplicantDataShoutboxEntry, now time.Time) (pending bool, reason error, err error) {
+	if strings.TrimSpace(entry.Message) == "" {
+		return false, ErrShoutEmpty, nil
+	}
+	// Names are shown too, so they have to pass the filters as well.
+	if reason = filterContent(entry.User.Name); reason != nil {
+		return false, reason, nil
+	}
+	if reason = filterContent(entry.Message); reason != nil {
+		return false, reason, nil
+	}
+	if reason = mod.recordShout(userKey, now); reason != nil {
+		return false, reason, nil
+	}
+	if config.RuntimeConfig.Discord.Shoutbox.ReviewChannelID != "" {
+		return true, nil, mod.submitShoutForReview(mod.discord, entry.ID, shoutReview{Entry: entry}, "via "+source.Name())
+	}
+	return false, nil, mod.showShout(entry)
+}
@@ -0,0 +1,227 @@
+package shoutproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file handles reading shouts from a Twitch channel's chat, which speaks (a dialect of) IRC.
+// We log in anonymously, so can only read chat; every chat message is treated as a shout.
+
+// How long to wait before reconnecting to Twitch chat, at first and at most.
+const (
+	twitchMinBackoff = time.Second
+	twitchMaxBackoff = 5 * time.Minute
+)
+
+// errTwitchReconnect is returned when Twitch asks us to reconnect (e.g. for maintenance).
+var errTwitchReconnect = errors.New("Twitch asked us to reconnect")
+
+// twitchSource reads shouts from a Twitch channel's chat.
+type twitchSource struct {
+	channel string
+	address string
+
+	// dial connects to Twitch chat. (Tests replace it.)
+	dial func(ctx context.Context, address string) (net.Conn, error)
+}
+
+func newTwitchSource(channel, address string) *twitchSource {
+	return &twitchSource{
+		channel: strings.ToLower(strings.TrimPrefix(channel, "#")),
+		address: address,
+		dial: func(ctx context.Context, address string) (net.Conn, error) {
+			dialer := &tls.Dialer{}
+			return dialer.DialContext(ctx, "tcp", address)
+		},
+	}
+}
+
+func (source *twitchSource) Name() string {
+	return "Twitch"
+}
+
+// Run reads chat until the context is cancelled, reconnecting (with backoff) whenever the connection drops.
+func (source *twitchSource) Run(ctx context.Context, mod *ShoutProxy) error {
+	backoff := twitchMinBackoff
+	for {
+		connected := time.Now()
+		err := source.read(ctx, mod)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Since(connected) > twitchMaxBackoff {
+			// The connection was fine for a while, so this is probably a one-off.
+			backoff = twitchMinBackoff
+		}
+		logger.Warn("Lost connection to Twitch chat, reconnecting", slog.Duration("backoff", backoff), slog.Any("error", err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, twitchMaxBackoff)
+	}
+}
+
+// read connects to Twitch chat and handles what it sends, until the connection drops or the context is cancelled.
+func (source *twitchSource) read(ctx context.Context, mod *ShoutProxy) (err error) {
+	conn, err := source.dial(ctx, source.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Reads can't be cancelled, but closing the connection stops them.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	// Anonymous users are called justinfan, followed by any number. Tags give us display names and message IDs, and
+	// commands tell us when messages are deleted.
+	_, err = fmt.Fprintf(conn, "CAP REQ :twitch.tv/tags twitch.tv/commands\r\nPASS SCHMOOPIIE\r\nNICK justinfan%d\r\nJOIN #%s\r\n", rand.IntN(90000)+10000, source.channel)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		msg := parseIRCMessage(scanner.Text())
+		switch msg.command {
+		case "PING":
+			if _, err = fmt.Fprintf(conn, "PONG :%s\r\n", msg.param(0)); err != nil {
+				return err
+			}
+		case "JOIN":
+			logger.Info("Reading shouts from Twitch chat", slog.String("channel", source.channel))
+		case "PRIVMSG":
+			source.handleMessage(mod, msg, time.Now())
+		case "CLEARMSG":
+			// A moderator deleted a message.
+			source.handleDelete(mod, msg)
+		case "RECONNECT":
+			return errTwitchReconnect
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// handleMessage treats a chat message as a shout.
+func (source *twitchSource) handleMessage(mod *ShoutProxy, msg ircMessage, now time.Time) {
+	text := msg.param(1)
+	if action, ok := strings.CutPrefix(text, "\x01ACTION "); ok {
+		// Sent with /me.
+		text = strings.TrimSuffix(action, "\x01")
+	}
+	if strings.HasPrefix(text, "!") {
+		// Chat commands (e.g. !uptime) are for other bots.
+		return
+	}
+	userID := msg.tags["user-id"]
+	if userID == "" {
+		userID = msg.nick()
+	}
+	entry := ngtbg.NodeCGReplicantDataShoutboxEntry{
+		ID:        twitchShoutIDPrefix + msg.tags["id"],
+		Timestamp: now.Format(time.RFC3339),
+		Message:   strings.TrimSpace(text),
+		Source:    ngtbg.ShoutboxSourceTwitch,
+	}
+	if sent, err := strconv.ParseInt(msg.tags["tmi-sent-ts"], 10, 64); err == nil {
+		entry.Timestamp = time.UnixMilli(sent).Format(time.RFC3339)
+	}
+	entry.User.Name = msg.tags["display-name"]
+	if entry.User.Name == "" {
+		entry.User.Name = msg.nick()
+	}
+
+	_, reason, err := mod.submitExternalShout(source, "twitch:"+userID, entry, now)
+	if err != nil {
+		logger.Error("Unable to accept shout", slog.String("source", source.Name()), slog.Any("error", err))
+	} else if reason != nil {
+		logger.Info("Shout rejected", slog.String("source", source.Name()), slog.String("user", entry.User.Name), slog.String("reason", reason.Error()))
+	}
+}
+
+// handleDelete removes a deleted chat message from the shoutbox (or Crew's review queue).
+func (source *twitchSource) handleDelete(mod *ShoutProxy, msg ircMessage) {
+	messageID := msg.tags["target-msg-id"]
+	if messageID == "" {
+		return
+	}
+	id := twitchShoutIDPrefix + messageID
+	if err := mod.withdrawShoutReview(mod.discord, id, "🗑️ The shout was deleted on Twitch before it was reviewed."); err != nil {
+		logger.Error("Unable to withdraw shout from review", slog.String("shout", id), slog.Any("error", err))
+	}
+	if err := mod.hideShout(id); err != nil {
+		logger.Error("Unable to remove shout", slog.String("shout", id), slog.Any("error", err))
+	}
+}
+
+// ircMessage is one line received from an IRC server, with IRCv3 tags.
+type ircMessage struct {
+	tags    map[string]string
+	prefix  string
+	command string
+	params  []string
+}
+
+// ircTagUnescaper undoes the escaping of IRCv3 tag values.
+var ircTagUnescaper = strings.NewReplacer(`\:`, ";", `\s`, " ", `\\`, `\`, `\r`, "\r", `\n`, "\n")
+
+// parseIRCMessage parses a line in the form "@tags :prefix COMMAND params :trailing param".
+func parseIRCMessage(line string) (msg ircMessage) {
+	line = strings.TrimRight(line, "\r\n")
+	if rest, ok := strings.CutPrefix(line, "@"); ok {
+		var tags string
+		tags, line, _ = strings.Cut(rest, " ")
+		msg.tags = make(map[string]string)
+		for _, tag := range strings.Split(tags, ";") {
+			key, value, _ := strings.Cut(tag, "=")
+			msg.tags[key] = ircTagUnescaper.Replace(value)
+		}
+	}
+	line = strings.TrimLeft(line, " ")
+	if rest, ok := strings.CutPrefix(line, ":"); ok {
+		msg.prefix, line, _ = strings.Cut(rest, " ")
+	}
+	line = strings.TrimLeft(line, " ")
+	msg.command, line, _ = strings.Cut(line, " ")
+	for line != "" {
+		if trailing, ok := strings.CutPrefix(line, ":"); ok {
+			msg.params = append(msg.params, trailing)
+			break
+		}
+		var param string
+		param, line, _ = strings.Cut(line, " ")
+		if param != "" {
+			msg.params = append(msg.params, param)
+		}
+	}
+	return msg
+}
+
+// param returns the message's nth parameter, or "" if it doesn't have one.
+func (msg ircMessage) param(n int) string {
+	if n < len(msg.params) {
+		return msg.params[n]
+	}
+	return ""
+}
+
+// nick returns the nickname of whoever sent the message.
+func (msg ircMessage) nick() string {
+	nick, _, _ := strings.Cut(msg.prefix, "!")
+	return nick
+}
@@ -0,0 +1,102 @@
+package shoutproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/shoutproxy/web"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// This file handles shouts sent from a web form on the BIGbridge server, for attendees who aren't on Discord.
+
+// The longest name that can be given on the web form.
+const maxWebNameLength = 32
+
+var shoutPage = template.Must(template.ParseFS(web.Templates, "shout.html"))
+
+// webSource accepts shouts from the web form at /shout.
+type webSource struct {
+	mod *ShoutProxy
+}
+
+func (source *webSource) Name() string {
+	return "the web form"
+}
+
+func (source *webSource) Run(ctx context.Context, mod *ShoutProxy) error {
+	source.mod = mod
+	bridge_wan.HandleFunc("/shout", source.handle)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// handle shows the web form (GET), or accepts a shout from it (POST).
+func (source *webSource) handle(w http.ResponseWriter, r *http.Request) {
+	data := web.ShoutPageData{
+		MaxNameLength:    maxWebNameLength,
+		MaxMessageLength: config.RuntimeConfig.Discord.Shoutbox.MaxLength,
+	}
+	status := http.StatusOK
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		data.Name = strings.TrimSpace(r.PostFormValue("name"))
+		data.Message = strings.TrimSpace(r.PostFormValue("message"))
+		pending, reason, err := source.submit(r, data.Name, data.Message, time.Now())
+		switch {
+		case err != nil:
+			logger.Error("Unable to accept shout", slog.String("source", source.Name()), slog.Any("error", err))
+			status = http.StatusInternalServerError
+			data.Error = "⚠️ Something went wrong. Please try again."
+		case reason != nil:
+			logger.Info("Shout rejected", slog.String("source", source.Name()), slog.String("user", data.Name), slog.String("reason", reason.Error()))
+			status = http.StatusUnprocessableEntity
+			data.Error = fmt.Sprintf("⚠️ %s.", reason)
+		case pending:
+			data.Message = ""
+			data.Notice = "🔎 Thanks! Your shout will be shown once Crew have checked it."
+		default:
+			data.Message = ""
+			data.Notice = "✅ Thanks! Your shout is on its way to the big screen."
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := shoutPage.Execute(w, data); err != nil {
+		logger.Error("error rendering template", slog.Any("err", err))
+	}
+}
+
+// submit turns a shout from the web form into a shout entry, and sends it on (see submitExternalShout).
+func (source *webSource) submit(r *http.Request, name, message string, now time.Time) (pending bool, reason error, err error) {
+	if name == "" || utf8.RuneCountInString(name) > maxWebNameLength {
+		return false, ErrShoutName, nil
+	}
+	// Web shouts don't have an account behind them, so the rate limit applies to where they come from.
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	entry := ngtbg.NodeCGReplicantDataShoutboxEntry{
+		ID:        webShoutIDPrefix + strconv.FormatInt(now.UnixNano(), 36),
+		Timestamp: now.Format(time.RFC3339),
+		Message:   message,
+		Source:    ngtbg.ShoutboxSourceWeb,
+	}
+	entry.User.Name = name
+	return source.mod.submitExternalShout(source, "web:"+host, entry, now)
+}
@@ -0,0 +1,18 @@
+package web
+
+import "embed"
+
+type ShoutPageData struct {
+	// What was entered, so that it doesn't have to be typed again if the shout is rejected.
+	Name    string
+	Message string
+	// The longest a name or message can be.
+	MaxNameLength    int
+	MaxMessageLength int
+	// How the last shout went, if one was sent.
+	Notice string
+	Error  string
+}
+
+//go:embed *.html
+var Templates embed.FS
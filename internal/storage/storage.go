@@ -0,0 +1,142 @@
+// Package storage provides a small persistent key/value store that BIGbot's modules can use to hold state
+// between restarts.
+// Values are grouped into named buckets and serialised as JSON to a single file on disk.
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var ErrNotFound = errors.New("key not found")
+
+type Store struct {
+	// The file the store is persisted to. If empty, the store only lives in memory.
+	path string
+
+	// Bucket -> key -> value. (the mutex MUST be held to interact with this map)
+	buckets map[string]map[string]json.RawMessage
+	mtx     sync.RWMutex
+}
+
+// Open loads the store held at path, creating it if it does not exist yet.
+// An empty path returns a store that is never written to disk.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		buckets: make(map[string]map[string]json.RawMessage),
+	}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading storage file: %w", err)
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+	err = json.Unmarshal(data, &s.buckets)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing storage file: %w", err)
+	}
+	return s, nil
+}
+
+// Persistent returns whether the store is backed by a file.
+func (s *Store) Persistent() bool {
+	return s.path != ""
+}
+
+// Get decodes the value stored under key in bucket into target.
+// Returns ErrNotFound if no such value exists.
+func (s *Store) Get(bucket, key string, target any) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	value, ok := s.buckets[bucket][key]
+	if !ok {
+		return ErrNotFound
+	}
+	return json.Unmarshal(value, target)
+}
+
+// Has returns whether a value is stored under key in bucket.
+func (s *Store) Has(bucket, key string) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	_, ok := s.buckets[bucket][key]
+	return ok
+}
+
+// Put stores value under key in bucket, then persists the store.
+// value MUST be serialisable as JSON.
+func (s *Store) Put(bucket, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string]json.RawMessage)
+	}
+	s.buckets[bucket][key] = data
+	return s.flush()
+}
+
+// Delete removes key from bucket, then persists the store.
+// Deleting a key that does not exist is not an error.
+func (s *Store) Delete(bucket, key string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.buckets[bucket][key]; !ok {
+		return nil
+	}
+	delete(s.buckets[bucket], key)
+	return s.flush()
+}
+
+// Keys returns every key currently held in bucket, in sorted order.
+func (s *Store) Keys(bucket string) []string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for k := range s.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// flush writes the store out to disk. The mutex MUST be held (for writing) when calling this.
+func (s *Store) flush() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.buckets)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash mid-write doesn't leave us with a truncated store.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing storage file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing storage file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error writing storage file: %w", err)
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
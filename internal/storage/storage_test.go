@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+type testRecord struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open err: %v", err)
+	}
+	err = s.Put("things", "a", testRecord{Name: "Test", Count: 3})
+	if err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+
+	// Re-open the store from disk and make sure our value survived.
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open err: %v", err)
+	}
+	var rec testRecord
+	err = s.Get("things", "a", &rec)
+	if err != nil {
+		t.Fatalf("Get err: %v", err)
+	}
+	if rec.Name != "Test" || rec.Count != 3 {
+		t.Errorf("Record should be {Test 3}, got %v", rec)
+	}
+
+	err = s.Delete("things", "a")
+	if err != nil {
+		t.Fatalf("Delete err: %v", err)
+	}
+	err = s.Get("things", "a", &rec)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Deleted key should return ErrNotFound, got %v", err)
+	}
+}
+
+func TestStoreKeys(t *testing.T) {
+	s, err := Open("")
+	if err != nil {
+		t.Fatalf("Open err: %v", err)
+	}
+	for _, k := range []string{"c", "a", "b"} {
+		if err := s.Put("things", k, true); err != nil {
+			t.Fatalf("Put err: %v", err)
+		}
+	}
+	keys := s.Keys("things")
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("Keys should be [a b c], got %v", keys)
+	}
+	if !s.Has("things", "a") || s.Has("things", "d") {
+		t.Errorf("Has should only report stored keys")
+	}
+	if len(s.Keys("empty")) != 0 {
+		t.Errorf("Unknown bucket should have no keys")
+	}
+}
@@ -0,0 +1,17 @@
+// Package storagetest provides throwaway stores, for testing modules that keep state in storage.
+package storagetest
+
+import (
+	"github.com/thebiggame/bigbot/internal/storage"
+	"testing"
+)
+
+// Open returns an empty store that only lives in memory, failing the test if it can't be opened.
+func Open(t testing.TB) *storage.Store {
+	t.Helper()
+	store, err := storage.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
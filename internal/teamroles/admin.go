@@ -0,0 +1,379 @@
+package teamroles
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// This file handles the Crew-only team administration commands.
+
+var adminCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:                     "teams",
+		Description:              "🧑‍🤝‍🧑🛠️ Administer LAN teams (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "admin",
+				Description: "🧑‍🤝‍🧑🛠️ Team administration.",
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "rename",
+						Description: "✏️ Rename a team.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionRole,
+								Name:        "team",
+								Description: "The team to rename.",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "team-name",
+								Description: "The new name of the team.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "delete",
+						Description: "🗑️ Delete a team.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionRole,
+								Name:        "team",
+								Description: "The team to delete.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "merge",
+						Description: "🔀 Merge one team into another, moving all of its members.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionRole,
+								Name:        "from",
+								Description: "The team to merge (this team will be deleted).",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionRole,
+								Name:        "into",
+								Description: "The team to move members into.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "kick",
+						Description: "👢 Remove a member from a team.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionRole,
+								Name:        "team",
+								Description: "The team to remove the member from.",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "member",
+								Description: "The member to remove.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "lock",
+						Description: "🔒 Lock or unlock a team (locked teams cannot be joined).",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionRole,
+								Name:        "team",
+								Description: "The team to lock or unlock.",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionBoolean,
+								Name:        "locked",
+								Description: "Whether the team should be locked.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "archive-all",
+						Description: "📦 Archive and delete every team (for use at the end of an event).",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionBoolean,
+								Name:        "confirm",
+								Description: "Set to confirm that you really want to delete every team.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "colour",
+						Description: "🎨 Set the colour of a team.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionRole,
+								Name:        "team",
+								Description: "The team to recolour.",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "colour",
+								Description: "The new colour, as a hex code (e.g. #7E8186).",
+								Required:    true,
+								MinLength:   &hexColourMinLength,
+								MaxLength:   7,
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func (mod *TeamRoles) discordHandleAdminCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.Interaction.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 || options[0].Name != "admin" || len(options[0].Options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+	subcommand := options[0].Options[0]
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subcommand.Options))
+	for _, opt := range subcommand.Options {
+		optionMap[opt.Name] = opt
+	}
+
+	// Some of these operations touch a lot of members, so let the client know we're working on it.
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+
+	var content string
+	switch subcommand.Name {
+	case "rename":
+		content, err = mod.adminRename(s, i.GuildID, optionMap["team"].RoleValue(s, i.GuildID), optionMap["team-name"].StringValue())
+	case "delete":
+		content, err = mod.adminDelete(s, i.GuildID, optionMap["team"].RoleValue(s, i.GuildID))
+	case "merge":
+		content, err = mod.adminMerge(s, i.GuildID, optionMap["from"].RoleValue(s, i.GuildID), optionMap["into"].RoleValue(s, i.GuildID))
+	case "kick":
+		content, err = mod.adminKick(s, i.GuildID, optionMap["team"].RoleValue(s, i.GuildID), optionMap["member"].UserValue(s))
+	case "lock":
+		content, err = mod.adminLock(optionMap["team"].RoleValue(s, i.GuildID), optionMap["locked"].BoolValue())
+	case "archive-all":
+		content, err = mod.adminArchiveAll(s, i.GuildID, optionMap["confirm"].BoolValue())
+	case "colour":
+		content, err = mod.adminColour(s, i.GuildID, optionMap["team"].RoleValue(s, i.GuildID), optionMap["colour"].StringValue())
+	default:
+		content = "😶 Please use a sub-command."
+	}
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		logger.Error("Team administration failed", slog.String("action", subcommand.Name), slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		content = helpers.DiscordErrorContent(s, i, err)
+	} else {
+		logger.Info("Team administration performed", slog.String("action", subcommand.Name), slog.String("user", i.Member.User.Username))
+	}
+	if _, err = helpers.DiscordInteractionFollowupMessage(s, i, content); err != nil {
+		logger.Error("Unable to send team administration response", slog.Any("error", err))
+	}
+	return nil
+}
+
+func (mod *TeamRoles) adminRename(s *discordgo.Session, guild string, role *discordgo.Role, name string) (content string, err error) {
+	if !mod.isTeam(role.ID) {
+		return fmt.Sprintf("⚠️ %s.", ErrNotTeam), nil
+	}
+	// Crew can pick names that would otherwise need reviewing, but not ones that break the hard rules.
+	name, err = validateTeamName(name)
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	}
+	existing := mod.findTeamRoleByName(s, guild, name)
+	if existing != nil && existing.ID != role.ID {
+		return fmt.Sprintf("⚠️ %s.", ErrTeamExists), nil
+	}
+	oldName := role.Name
+	role, err = s.GuildRoleEdit(guild, role.ID, &discordgo.RoleParams{
+		Name: teamRoleName(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	err = mod.renameTeamChannels(s, role)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("✏️ Renamed %s to %s", oldName, role.Name), nil
+}
+
+func (mod *TeamRoles) adminDelete(s *discordgo.Session, guild string, role *discordgo.Role) (content string, err error) {
+	if !mod.isTeam(role.ID) {
+		return fmt.Sprintf("⚠️ %s.", ErrNotTeam), nil
+	}
+	err = mod.removeTeam(s, guild, role.ID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🗑️ Deleted %s", role.Name), nil
+}
+
+func (mod *TeamRoles) adminMerge(s *discordgo.Session, guild string, from, into *discordgo.Role) (content string, err error) {
+	if !mod.isTeam(from.ID) {
+		return fmt.Sprintf("⚠️ %s: %s.", from.Name, ErrNotTeam), nil
+	}
+	if !mod.isTeam(into.ID) {
+		return fmt.Sprintf("⚠️ %s: %s.", into.Name, ErrNotTeam), nil
+	}
+	if from.ID == into.ID {
+		return "🤔 You can't merge a team into itself.", nil
+	}
+	members, err := teamMembers(s, guild, from.ID)
+	if err != nil {
+		return "", err
+	}
+	var moved int
+	var failed []string
+	for _, member := range members {
+		if slices.Contains(member.Roles, into.ID) {
+			continue
+		}
+		err = s.GuildMemberRoleAdd(guild, member.User.ID, into.ID)
+		if err != nil {
+			logger.Warn("Unable to move team member", slog.String("user", member.User.Username), slog.String("into", into.Name), slog.Any("error", err))
+			failed = append(failed, member.User.Mention())
+			continue
+		}
+		moved++
+	}
+	if moved > 0 {
+		err = mod.touchTeam(into.ID)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(failed) > 0 {
+		// Keep the old team, so that nobody is left without one. Merging again will pick up where this left off.
+		return fmt.Sprintf("⚠️ Moved %d %s from %s into %s, but couldn't move %s, so %s has been kept. Try merging again.",
+			moved, helpers.Pluralise(moved, "member", "members"), from.Name, into.Name, strings.Join(failed, ", "), from.Name), nil
+	}
+	// Deleting the old role removes it from all of its members.
+	err = mod.removeTeam(s, guild, from.ID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🔀 Merged %s into %s (%d %s moved)", from.Name, into.Name, moved, helpers.Pluralise(moved, "member", "members")), nil
+}
+
+func (mod *TeamRoles) adminKick(s *discordgo.Session, guild string, role *discordgo.Role, user *discordgo.User) (content string, err error) {
+	if !mod.isTeam(role.ID) {
+		return fmt.Sprintf("⚠️ %s.", ErrNotTeam), nil
+	}
+	err = validateUserIsRoleMember(s, user, guild, role)
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s is not a member of %s.", user.Mention(), role.Name), nil
+	}
+	err = s.GuildMemberRoleRemove(guild, user.ID, role.ID)
+	if err != nil {
+		return "", err
+	}
+	err = mod.handleMemberLeft(role, user.ID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("👢 Removed %s from %s", user.Mention(), role.Name), nil
+}
+
+func (mod *TeamRoles) adminLock(role *discordgo.Role, locked bool) (content string, err error) {
+	if !mod.isTeam(role.ID) {
+		return fmt.Sprintf("⚠️ %s.", ErrNotTeam), nil
+	}
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTeam(role.ID)
+	if err != nil {
+		return "", err
+	}
+	t.Locked = locked
+	err = mod.saveTeam(role.ID, t)
+	if err != nil {
+		return "", err
+	}
+	if locked {
+		return fmt.Sprintf("🔒 Locked %s", role.Name), nil
+	}
+	return fmt.Sprintf("🔓 Unlocked %s", role.Name), nil
+}
+
+func (mod *TeamRoles) adminColour(s *discordgo.Session, guild string, role *discordgo.Role, colour string) (content string, err error) {
+	if !mod.isTeam(role.ID) {
+		return fmt.Sprintf("⚠️ %s.", ErrNotTeam), nil
+	}
+	value, err := parseHexColour(colour)
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s is not a valid colour. Please use a hex code like #7E8186.", colour), nil
+	}
+	_, err = s.GuildRoleEdit(guild, role.ID, &discordgo.RoleParams{
+		Color: &value,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🎨 Set the colour of %s to #%06X", role.Name, value), nil
+}
+
+// removeTeam deletes a team entirely: its channels, its role, and everything we know about it.
+func (mod *TeamRoles) removeTeam(s *discordgo.Session, guild, roleID string) (err error) {
+	err = mod.deleteTeamChannels(s, roleID)
+	if err != nil {
+		return err
+	}
+	err = s.GuildRoleDelete(guild, roleID)
+	if err != nil {
+		return err
+	}
+	return mod.deleteTeam(roleID)
+}
+
+// parseHexColour parses a colour in the form #RRGGBB (the # is optional).
+func parseHexColour(colour string) (int, error) {
+	colour = strings.TrimPrefix(colour, "#")
+	if len(colour) != 6 {
+		return 0, strconv.ErrSyntax
+	}
+	value, err := strconv.ParseUint(colour, 16, 32)
+	return int(value), err
+}
+
+var hexColourMinLength = 6
+
+var defaultCrewCommandPermissions int64 = discordgo.PermissionAdministrator
+var defaultCrewCommandDMPermissions = false
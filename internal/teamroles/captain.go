@@ -0,0 +1,279 @@
+package teamroles
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// This file handles team captains, and the join request flow for invite-only teams.
+
+const (
+	teamRequestAcceptCustomIDPrefix  = "bigbot_team_request_accept_"
+	teamRequestDeclineCustomIDPrefix = "bigbot_team_request_decline_"
+)
+
+// validateUserCanCaptain checks that the given user is allowed to manage the given team
+// (i.e. they are its captain, or a member of Crew).
+func (mod *TeamRoles) validateUserCanCaptain(s *discordgo.Session, guild string, u *discordgo.User, t team) error {
+	if t.Captain != "" && t.Captain == u.ID {
+		return nil
+	}
+	isCrew, err := helpers.UserIsCrew(s, guild, u)
+	if err != nil {
+		return err
+	}
+	if isCrew {
+		return nil
+	}
+	return ErrNotCaptain
+}
+
+// setInviteOnly marks the given team as invite-only (or not).
+func (mod *TeamRoles) setInviteOnly(s *discordgo.Session, guild string, u *discordgo.User, role *discordgo.Role, inviteOnly bool) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTeam(role.ID)
+	if err != nil {
+		return "", err
+	}
+	if err = mod.validateUserCanCaptain(s, guild, u, t); err != nil {
+		return fmt.Sprintf("⚠️ %s", err), nil
+	}
+	if inviteOnly && t.Captain == "" {
+		return fmt.Sprintf("⚠️ %s has no captain to accept join requests. Please ask a member of Crew to set one.", role.Name), nil
+	}
+	t.InviteOnly = inviteOnly
+	if !inviteOnly {
+		// Nobody needs to ask any more.
+		t.JoinRequests = nil
+	}
+	if err = mod.saveTeam(role.ID, t); err != nil {
+		return "", err
+	}
+	if inviteOnly {
+		return fmt.Sprintln("📨", role.Name, "is now invite-only"), nil
+	}
+	return fmt.Sprintln("🚪", role.Name, "is now open to everyone"), nil
+}
+
+// setCaptain passes the captaincy of the given team to another of its members.
+func (mod *TeamRoles) setCaptain(s *discordgo.Session, guild string, u *discordgo.User, role *discordgo.Role, captain *discordgo.User) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTeam(role.ID)
+	if err != nil {
+		return "", err
+	}
+	if err = mod.validateUserCanCaptain(s, guild, u, t); err != nil {
+		return fmt.Sprintf("⚠️ %s", err), nil
+	}
+	if err = validateUserIsRoleMember(s, captain, guild, role); err != nil {
+		return fmt.Sprintf("⚠️ %s is not a member of %s.", captain.Mention(), role.Name), nil
+	}
+	t.Captain = captain.ID
+	if err = mod.saveTeam(role.ID, t); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🧢 %s is now the captain of %s", captain.Mention(), role.Name), nil
+}
+
+// handleMemberLeft tidies up team state after a member leaves (or is removed from) a team.
+func (mod *TeamRoles) handleMemberLeft(role *discordgo.Role, userID string) error {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTeam(role.ID)
+	if err != nil {
+		return err
+	}
+	if t.Captain != userID {
+		return nil
+	}
+	// With no captain, nobody would be able to accept join requests, so open the team back up.
+	logger.Info("Team captain left, opening team", slog.String("role", role.Name))
+	t.Captain = ""
+	t.InviteOnly = false
+	t.JoinRequests = nil
+	return mod.saveTeam(role.ID, t)
+}
+
+// requestToJoin records a join request for an invite-only team, then asks the team's captain to accept or decline it.
+func (mod *TeamRoles) requestToJoin(s *discordgo.Session, u *discordgo.User, role *discordgo.Role) error {
+	t, err := mod.addJoinRequest(role.ID, u.ID)
+	if err != nil {
+		return err
+	}
+
+	customIDSuffix := role.ID + "_" + u.ID
+	err = mod.sendToCaptain(s, t, &discordgo.MessageSend{
+		Content: fmt.Sprintf("📨 %s would like to join **%s**.", u.Mention(), role.Name),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Accept",
+						Style:    discordgo.SuccessButton,
+						CustomID: teamRequestAcceptCustomIDPrefix + customIDSuffix,
+					},
+					discordgo.Button{
+						Label:    "Decline",
+						Style:    discordgo.DangerButton,
+						CustomID: teamRequestDeclineCustomIDPrefix + customIDSuffix,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		// The captain never saw the request, so let the user try again later.
+		if _, takeErr := mod.takeJoinRequest(role.ID, u.ID, false); takeErr != nil {
+			logger.Error("Unable to withdraw join request", slog.Any("error", takeErr))
+		}
+	}
+	return err
+}
+
+// addJoinRequest records that the given user would like to join the given team, returning the updated team.
+func (mod *TeamRoles) addJoinRequest(roleID, userID string) (t team, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err = mod.getTeam(roleID)
+	if err != nil {
+		return t, err
+	}
+	if slices.Contains(t.JoinRequests, userID) {
+		return t, ErrJoinRequestPending
+	}
+	t.JoinRequests = append(t.JoinRequests, userID)
+	return t, mod.saveTeam(roleID, t)
+}
+
+// takeJoinRequest removes the given user's join request from the given team, returning whether it was still pending.
+// When accepting, requests to join a team that Crew have since locked are kept (returning ErrTeamLocked), in case they
+// unlock it again.
+func (mod *TeamRoles) takeJoinRequest(roleID, userID string, accept bool) (pending bool, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTeam(roleID)
+	if err != nil || !slices.Contains(t.JoinRequests, userID) {
+		return false, err
+	}
+	if accept && t.Locked {
+		return true, ErrTeamLocked
+	}
+	t.JoinRequests = slices.DeleteFunc(t.JoinRequests, func(id string) bool {
+		return id == userID
+	})
+	return true, mod.saveTeam(roleID, t)
+}
+
+// discordRequestToJoin asks the captain of an invite-only team to let the user in, returning the response for the user.
+func (mod *TeamRoles) discordRequestToJoin(s *discordgo.Session, u *discordgo.User, role *discordgo.Role) (content string) {
+	err := mod.requestToJoin(s, u, role)
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s", err.Error())
+	}
+	return fmt.Sprintln("📨", role.Name, "is invite-only, so we've asked the captain to let you in")
+}
+
+// sendToCaptain sends a message to the given team's captain.
+// This is done by DM where possible, falling back to the team's text channel (if it has one).
+func (mod *TeamRoles) sendToCaptain(s *discordgo.Session, t team, msg *discordgo.MessageSend) error {
+	if t.Captain == "" {
+		return errors.New("team has no captain")
+	}
+	channel, err := s.UserChannelCreate(t.Captain)
+	if err == nil {
+		_, err = s.ChannelMessageSendComplex(channel.ID, msg)
+		if err == nil {
+			return nil
+		}
+	}
+	if t.TextChannel == "" {
+		return fmt.Errorf("unable to contact the team captain: %w", err)
+	}
+	logger.Debug("Unable to DM team captain, using team channel", slog.Any("error", err))
+	// Make sure the captain notices.
+	channelMsg := *msg
+	channelMsg.Content = fmt.Sprintf("<@%s> %s", t.Captain, msg.Content)
+	channelMsg.AllowedMentions = &discordgo.MessageAllowedMentions{
+		Users: []string{t.Captain},
+	}
+	_, err = s.ChannelMessageSendComplex(t.TextChannel, &channelMsg)
+	if err != nil {
+		return fmt.Errorf("unable to contact the team captain: %w", err)
+	}
+	return nil
+}
+
+// discordHandleJoinRequestResponse handles a captain accepting or declining a join request.
+func (mod *TeamRoles) discordHandleJoinRequestResponse(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	customID := i.MessageComponentData().CustomID
+	accept := strings.HasPrefix(customID, teamRequestAcceptCustomIDPrefix)
+	ids := strings.TrimPrefix(strings.TrimPrefix(customID, teamRequestAcceptCustomIDPrefix), teamRequestDeclineCustomIDPrefix)
+	roleID, userID, found := strings.Cut(ids, "_")
+	if !found {
+		return fmt.Errorf("malformed join request ID %s", customID)
+	}
+	// These buttons are usually pressed in a DM, so we won't have a guild to go on.
+	guild := config.RuntimeConfig.Discord.GuildID
+
+	role, err := s.State.Role(guild, roleID)
+	if errors.Is(err, discordgo.ErrStateNotFound) {
+		return helpers.DiscordUpdateComponentMessage(s, i, "🤷 That team no longer exists.")
+	} else if err != nil {
+		return err
+	}
+	t, err := mod.getTeam(roleID)
+	if err != nil {
+		return err
+	}
+	if err = mod.validateUserCanCaptain(s, guild, helpers.DiscordInteractionUser(i), t); err != nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s", err))
+	}
+	// Crew may have locked the team since the request was made.
+	pending, err := mod.takeJoinRequest(roleID, userID, accept)
+	if errors.Is(err, ErrTeamLocked) {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s", err))
+	} else if err != nil {
+		return err
+	}
+	if !pending {
+		return helpers.DiscordUpdateComponentMessage(s, i, "🤷 That request has already been dealt with.")
+	}
+
+	user, err := s.User(userID)
+	if err != nil {
+		return err
+	}
+	var content, notification string
+	if accept {
+		if err = mod.validateUserCanJoinTeam(s, user, guild, roleID); err != nil {
+			return helpers.DiscordUpdateComponentMessage(s, i, fmt.Sprintf("⚠️ Unable to add %s to %s: %s", user.Mention(), role.Name, err))
+		}
+		if err = s.GuildMemberRoleAdd(guild, userID, roleID); err != nil {
+			return err
+		}
+		if err = mod.touchTeam(roleID); err != nil {
+			return err
+		}
+		content = fmt.Sprintf("✅ Accepted %s into %s", user.Mention(), role.Name)
+		notification = fmt.Sprintf("🤝 Your request to join **%s** was accepted!", role.Name)
+	} else {
+		content = fmt.Sprintf("❌ Declined %s's request to join %s", user.Mention(), role.Name)
+		notification = fmt.Sprintf("😔 Your request to join **%s** was declined.", role.Name)
+	}
+
+	// Let the requester know how it went. This is best-effort; they may not accept DMs.
+	if channel, err := s.UserChannelCreate(userID); err == nil {
+		if _, err = s.ChannelMessageSend(channel.ID, notification); err != nil {
+			logger.Debug("Unable to notify join requester", slog.Any("error", err))
+		}
+	}
+	return helpers.DiscordUpdateComponentMessage(s, i, content)
+}
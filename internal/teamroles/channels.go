@@ -0,0 +1,213 @@
+package teamroles
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"log/slog"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// This file handles the (optional) private text & voice channels that are created alongside each team.
+
+const (
+	// Permissions granted to team members in their channels.
+	teamChannelMemberPermissions = discordgo.PermissionViewChannel |
+		discordgo.PermissionSendMessages |
+		discordgo.PermissionReadMessageHistory |
+		discordgo.PermissionAddReactions |
+		discordgo.PermissionAttachFiles |
+		discordgo.PermissionEmbedLinks |
+		discordgo.PermissionVoiceConnect |
+		discordgo.PermissionVoiceSpeak |
+		discordgo.PermissionVoiceUseVAD
+)
+
+// teamChannelName renders the configured channel name template for the given team role name.
+func teamChannelName(roleName string) (string, error) {
+	name := teamDisplayName(roleName)
+	tmpl, err := template.New("channel").Parse(config.RuntimeConfig.Teams.Channels.NameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid team channel name template: %w", err)
+	}
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, struct{ Name string }{Name: name})
+	if err != nil {
+		return "", fmt.Errorf("invalid team channel name template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// teamChannelPermissions builds the permission overwrites for a team's channels,
+// hiding them from everybody except the team (and Crew).
+func teamChannelPermissions(s *discordgo.Session, guild string, role *discordgo.Role) []*discordgo.PermissionOverwrite {
+	overwrites := []*discordgo.PermissionOverwrite{
+		{
+			// The @everyone role shares its ID with the guild.
+			ID:   guild,
+			Type: discordgo.PermissionOverwriteTypeRole,
+			Deny: discordgo.PermissionViewChannel,
+		},
+		{
+			ID:    role.ID,
+			Type:  discordgo.PermissionOverwriteTypeRole,
+			Allow: teamChannelMemberPermissions,
+		},
+		{
+			// Make sure we can still see (and tidy up) the channels we create.
+			ID:    s.State.User.ID,
+			Type:  discordgo.PermissionOverwriteTypeMember,
+			Allow: discordgo.PermissionViewChannel | discordgo.PermissionManageChannels,
+		},
+	}
+	if crewRole := config.RuntimeConfig.Discord.Permissions.CrewRole; crewRole != "" {
+		overwrites = append(overwrites, &discordgo.PermissionOverwrite{
+			ID:    crewRole,
+			Type:  discordgo.PermissionOverwriteTypeRole,
+			Allow: teamChannelMemberPermissions,
+		})
+	}
+	return overwrites
+}
+
+// createTeamChannels creates the private text & voice channels for a newly created team, if enabled.
+func (mod *TeamRoles) createTeamChannels(s *discordgo.Session, guild string, role *discordgo.Role) error {
+	if !config.RuntimeConfig.Teams.Channels.Enabled {
+		return nil
+	}
+	name, err := teamChannelName(role.Name)
+	if err != nil {
+		return err
+	}
+	overwrites := teamChannelPermissions(s, guild, role)
+	text, err := s.GuildChannelCreateComplex(guild, discordgo.GuildChannelCreateData{
+		Name:                 name,
+		Type:                 discordgo.ChannelTypeGuildText,
+		Topic:                fmt.Sprintf("Private chat for %s", role.Name),
+		ParentID:             config.RuntimeConfig.Teams.Channels.CategoryID,
+		PermissionOverwrites: overwrites,
+	})
+	if err != nil {
+		return fmt.Errorf("problem creating team text channel: %w", err)
+	}
+
+	voice, err := s.GuildChannelCreateComplex(guild, discordgo.GuildChannelCreateData{
+		Name:                 name,
+		Type:                 discordgo.ChannelTypeGuildVoice,
+		ParentID:             config.RuntimeConfig.Teams.Channels.CategoryID,
+		PermissionOverwrites: overwrites,
+	})
+	if err != nil {
+		// Save what we did manage to create, so it gets tidied up with the team.
+		if saveErr := mod.saveTeamChannels(role.ID, text.ID, ""); saveErr != nil {
+			logger.Error("Unable to save team channels", slog.Any("error", saveErr))
+		}
+		return fmt.Errorf("problem creating team voice channel: %w", err)
+	}
+
+	logger.Debug("Created team channels", slog.String("role", role.Name), slog.String("text", text.ID), slog.String("voice", voice.ID))
+	return mod.saveTeamChannels(role.ID, text.ID, voice.ID)
+}
+
+// saveTeamChannels records the channels created for the given team.
+func (mod *TeamRoles) saveTeamChannels(roleID, textChannel, voiceChannel string) error {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTeam(roleID)
+	if err != nil {
+		return err
+	}
+	t.TextChannel = textChannel
+	t.VoiceChannel = voiceChannel
+	return mod.saveTeam(roleID, t)
+}
+
+// renameTeamChannels keeps the names of a team's channels in line with its (new) role name.
+func (mod *TeamRoles) renameTeamChannels(s *discordgo.Session, role *discordgo.Role) error {
+	t, err := mod.getTeam(role.ID)
+	if err != nil {
+		return err
+	}
+	if t.TextChannel == "" && t.VoiceChannel == "" {
+		return nil
+	}
+	name, err := teamChannelName(role.Name)
+	if err != nil {
+		return err
+	}
+	for _, channelID := range []string{t.TextChannel, t.VoiceChannel} {
+		if channelID == "" {
+			continue
+		}
+		if channel, err := s.State.Channel(channelID); err == nil && channel.Name == name {
+			// Already up to date (e.g. only the role's colour changed).
+			continue
+		}
+		_, err = s.ChannelEdit(channelID, &discordgo.ChannelEdit{
+			Name: name,
+		})
+		if err != nil && !isUnknownChannel(err) {
+			return fmt.Errorf("problem renaming team channel: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteTeamChannels removes a team's channels (if it has any).
+func (mod *TeamRoles) deleteTeamChannels(s *discordgo.Session, roleID string) error {
+	t, err := mod.getTeam(roleID)
+	if err != nil {
+		return err
+	}
+	for _, channelID := range []string{t.TextChannel, t.VoiceChannel} {
+		if channelID == "" {
+			continue
+		}
+		_, err = s.ChannelDelete(channelID)
+		// Somebody may have already deleted the channel by hand, which is fine.
+		if err != nil && !isUnknownChannel(err) {
+			return fmt.Errorf("problem deleting team channel: %w", err)
+		}
+	}
+	return nil
+}
+
+// discordHandleRoleUpdate keeps a team's channels named after its role, even if Crew rename the role by hand.
+// Teams are stored against their role ID, so the team itself needs no changes.
+func (mod *TeamRoles) discordHandleRoleUpdate(s *discordgo.Session, e *discordgo.GuildRoleUpdate) {
+	if e.GuildID != config.RuntimeConfig.Discord.GuildID || !mod.isTeam(e.Role.ID) {
+		return
+	}
+	if err := mod.renameTeamChannels(s, e.Role); err != nil {
+		logger.Error("Unable to rename team channels", slog.String("role", e.Role.Name), slog.Any("error", err))
+	}
+}
+
+// discordHandleRoleDelete tidies up after a team whose role Crew deleted by hand: its channels go, and so does
+// everything we know about it.
+func (mod *TeamRoles) discordHandleRoleDelete(s *discordgo.Session, e *discordgo.GuildRoleDelete) {
+	if e.GuildID != config.RuntimeConfig.Discord.GuildID || !mod.isTeam(e.RoleID) {
+		// Not a team, or one we've already removed ourselves.
+		return
+	}
+	logger.Info("Team role deleted, removing team", slog.String("id", e.RoleID))
+	if err := mod.deleteTeamChannels(s, e.RoleID); err != nil {
+		// Still forget the team; its channels can be deleted by hand.
+		logger.Error("Unable to delete team channels", slog.String("id", e.RoleID), slog.Any("error", err))
+	}
+	if err := mod.deleteTeam(e.RoleID); err != nil {
+		logger.Error("Unable to forget deleted team", slog.String("id", e.RoleID), slog.Any("error", err))
+	}
+}
+
+// isUnknownChannel returns whether the given error is Discord telling us a channel doesn't exist.
+func isUnknownChannel(err error) bool {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) {
+		return restErr.Response.StatusCode == http.StatusNotFound
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+package teamroles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file handles tidying up team roles once they're no longer in use.
+
+const (
+	// Empty teams younger than this are left alone, so that we don't race somebody creating (or re-joining) a team.
+	cleanupGracePeriod = 10 * time.Minute
+	// How often a message in a team's channel counts towards its activity.
+	activityThrottle = 10 * time.Minute
+
+	// The storage bucket that snapshots of archived teams are kept in.
+	teamsArchiveBucket = "teams_archive"
+
+	teamCleanupDeleteCustomIDPrefix = "bigbot_team_cleanup_delete_"
+	teamCleanupKeepCustomIDPrefix   = "bigbot_team_cleanup_keep_"
+)
+
+// archivedTeam is a snapshot of a team, kept after the team itself has been archived.
+type archivedTeam struct {
+	Name    string   `json:"name"`
+	Captain string   `json:"captain,omitempty"`
+	Members []string `json:"members"`
+}
+
+// channelActivity remembers when we last recorded activity for messages in each team channel.
+var channelActivity = struct {
+	seen map[string]time.Time
+	mtx  sync.Mutex
+}{seen: make(map[string]time.Time)}
+
+// touchTeam records that something happened in the given team, so that it isn't considered stale.
+func (mod *TeamRoles) touchTeam(roleID string) error {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTeam(roleID)
+	if err != nil {
+		return err
+	}
+	t.LastActive = time.Now()
+	t.Reported = false
+	return mod.saveTeam(roleID, t)
+}
+
+// teamLastActive returns when the given team was last active, falling back to when it was created.
+func teamLastActive(roleID string, t team) time.Time {
+	if !t.LastActive.IsZero() {
+		return t.LastActive
+	}
+	created, err := discordgo.SnowflakeTimestamp(roleID)
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+// recordChannelActivity counts messages in team text channels towards their team's activity.
+func (mod *TeamRoles) recordChannelActivity(s *discordgo.Session, m *discordgo.MessageCreate) error {
+	if !config.RuntimeConfig.Teams.Channels.Enabled || m.GuildID != config.RuntimeConfig.Discord.GuildID {
+		return nil
+	}
+	// Cheaply rule out anything that isn't in the team channel category.
+	channel, err := s.State.Channel(m.ChannelID)
+	if err != nil || channel.ParentID != config.RuntimeConfig.Teams.Channels.CategoryID {
+		return nil
+	}
+	channelActivity.mtx.Lock()
+	if time.Since(channelActivity.seen[m.ChannelID]) < activityThrottle {
+		channelActivity.mtx.Unlock()
+		return nil
+	}
+	channelActivity.seen[m.ChannelID] = time.Now()
+	channelActivity.mtx.Unlock()
+
+	for _, roleID := range mod.store.Keys(teamsBucket) {
+		t, err := mod.getTeam(roleID)
+		if err != nil {
+			return err
+		}
+		if t.TextChannel == m.ChannelID {
+			return mod.touchTeam(roleID)
+		}
+	}
+	return nil
+}
+
+// cleanupDaemon periodically looks for empty and stale teams until the context is cancelled.
+func (mod *TeamRoles) cleanupDaemon(ctx context.Context) error {
+	interval := config.RuntimeConfig.Teams.Cleanup.Interval
+	if interval <= 0 || (config.RuntimeConfig.Teams.Cleanup.ReportChannelID == "" && !config.RuntimeConfig.Teams.Cleanup.AutoDelete) {
+		logger.Debug("Team cleanup disabled")
+		return ctx.Err()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := mod.cleanupTeams(mod.discord, config.RuntimeConfig.Discord.GuildID)
+			if err != nil {
+				logger.Error("Error cleaning up teams", slog.Any("error", err))
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// cleanupTeams finds teams that are empty (or haven't been active since the configured cutoff),
+// and either deletes them or asks Crew to confirm their deletion. Only empty teams are ever deleted automatically;
+// a team with members in it may just be quiet, so Crew always get the final say.
+func (mod *TeamRoles) cleanupTeams(s *discordgo.Session, guild string) error {
+	teams, err := mod.listTeams(s, guild)
+	if err != nil {
+		return err
+	}
+	staleAfter := config.RuntimeConfig.Teams.Cleanup.StaleAfter
+	reportChannel := config.RuntimeConfig.Teams.Cleanup.ReportChannelID
+
+	for _, summary := range teams {
+		role := summary.Role
+		t, err := mod.getTeam(role.ID)
+		if err != nil {
+			return err
+		}
+		lastActive := teamLastActive(role.ID, t)
+
+		empty := summary.Members == 0 && time.Since(lastActive) > cleanupGracePeriod
+		var reason string
+		switch {
+		case empty:
+			reason = "has no members"
+		case staleAfter > 0 && time.Since(lastActive) > staleAfter:
+			reason = fmt.Sprintf("has not been active since <t:%d:f>", lastActive.Unix())
+		default:
+			continue
+		}
+		if t.Reported {
+			// Already waiting on Crew.
+			continue
+		}
+
+		if empty && config.RuntimeConfig.Teams.Cleanup.AutoDelete {
+			logger.Info("Automatically deleting team", slog.String("role", role.Name), slog.String("reason", reason))
+			err = mod.removeTeam(s, guild, role.ID)
+			if err != nil {
+				return err
+			}
+			if reportChannel != "" {
+				_, err = s.ChannelMessageSend(reportChannel, fmt.Sprintf("🧹 Deleted **%s**, as it %s.", role.Name, reason))
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if reportChannel == "" {
+			// Nowhere to ask Crew.
+			continue
+		}
+
+		_, err = s.ChannelMessageSendComplex(reportChannel, &discordgo.MessageSend{
+			Content: fmt.Sprintf("🧹 **%s** %s. Should it be deleted?", role.Name, reason),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "Delete",
+							Style:    discordgo.DangerButton,
+							CustomID: teamCleanupDeleteCustomIDPrefix + role.ID,
+						},
+						discordgo.Button{
+							Label:    "Keep",
+							Style:    discordgo.SecondaryButton,
+							CustomID: teamCleanupKeepCustomIDPrefix + role.ID,
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		err = mod.markTeamReported(role.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markTeamReported records that the given team is awaiting Crew's decision, so that it isn't reported again.
+func (mod *TeamRoles) markTeamReported(roleID string) error {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTeam(roleID)
+	if err != nil {
+		return err
+	}
+	t.Reported = true
+	return mod.saveTeam(roleID, t)
+}
+
+// discordHandleCleanupResponse handles Crew confirming (or rejecting) the deletion of a reported team.
+func (mod *TeamRoles) discordHandleCleanupResponse(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+
+	customID := i.MessageComponentData().CustomID
+	roleID := strings.TrimPrefix(strings.TrimPrefix(customID, teamCleanupDeleteCustomIDPrefix), teamCleanupKeepCustomIDPrefix)
+	role, err := s.State.Role(i.GuildID, roleID)
+	if errors.Is(err, discordgo.ErrStateNotFound) {
+		return helpers.DiscordUpdateComponentMessage(s, i, "🤷 That team no longer exists.")
+	} else if err != nil {
+		return err
+	}
+
+	var content string
+	if strings.HasPrefix(customID, teamCleanupDeleteCustomIDPrefix) {
+		err = mod.removeTeam(s, i.GuildID, roleID)
+		content = fmt.Sprintf("🗑️ %s deleted %s", helpers.DiscordInteractionUser(i).Mention(), role.Name)
+	} else {
+		// Treat keeping the team as activity, so we don't immediately report it again.
+		err = mod.touchTeam(roleID)
+		content = fmt.Sprintf("👍 %s kept %s", helpers.DiscordInteractionUser(i).Mention(), role.Name)
+	}
+	if err != nil {
+		return err
+	}
+	return helpers.DiscordUpdateComponentMessage(s, i, content)
+}
+
+// adminArchiveAll snapshots every team into storage, then deletes them all. This is intended for the end of an event.
+func (mod *TeamRoles) adminArchiveAll(s *discordgo.Session, guild string, confirm bool) (content string, err error) {
+	if !confirm {
+		return "🤔 This will delete **every** team. If you're sure, run this again with `confirm` set.", nil
+	}
+	teams, err := mod.listTeams(s, guild)
+	if err != nil {
+		return "", err
+	}
+	var archive []archivedTeam
+	for _, summary := range teams {
+		t, err := mod.getTeam(summary.Role.ID)
+		if err != nil {
+			return "", err
+		}
+		members, err := teamMembers(s, guild, summary.Role.ID)
+		if err != nil {
+			return "", err
+		}
+		snapshot := archivedTeam{
+			Name:    summary.Role.Name,
+			Captain: t.Captain,
+		}
+		for _, member := range members {
+			snapshot.Members = append(snapshot.Members, member.User.ID)
+		}
+		archive = append(archive, snapshot)
+	}
+	// Save the archive before deleting anything, so nothing is lost if we fail part-way through.
+	err = mod.store.Put(teamsArchiveBucket, time.Now().UTC().Format(time.RFC3339), archive)
+	if err != nil {
+		return "", err
+	}
+	for _, summary := range teams {
+		err = mod.removeTeam(s, guild, summary.Role.ID)
+		if err != nil {
+			return "", fmt.Errorf("problem removing %s: %w", summary.Role.Name, err)
+		}
+	}
+	return fmt.Sprintf("📦 Archived %d %s.", len(teams), helpers.Pluralise(len(teams), "team", "teams")), nil
+}
@@ -2,11 +2,18 @@ package teamroles
 
 import (
 	"errors"
-	"fmt"
-	"github.com/thebiggame/bigbot/internal/config"
 )
 
 var ErrAlreadyTeamMember = errors.New("You are already a member of that team")
 var ErrNotTeamMember = errors.New("You are not a member of that team")
 var ErrNotTeam = errors.New("This is not a team")
-var ErrMaxTeamsReached = errors.New(fmt.Sprintf("You are already a member of %d or more teams! Please contact an administrator if you need more", config.RuntimeConfig.Teams.MaxUserTeams))
+var ErrTeamLocked = errors.New("That team has been locked by Crew, so nobody new can join it")
+var ErrTeamExists = errors.New("A team with that name already exists")
+var ErrNotCaptain = errors.New("Only the team captain (or Crew) can do that")
+var ErrJoinRequestPending = errors.New("You have already asked to join that team. Please wait for the captain to respond")
+var ErrTeamNameMention = errors.New("Team names can't contain mentions")
+var ErrTeamNameLength = errors.New("Team names must be a sensible length")
+var ErrTeamNameNotAllowed = errors.New("That team name isn't allowed. Please pick another")
+var ErrMaxTeamsReached = errors.New("You are already a member of the maximum number of teams")
+var ErrTeamIconInvalid = errors.New("Team icons must be a single emoji (custom emoji aren't supported)")
+var ErrRoleIconsUnavailable = errors.New("This server needs more boosts before teams can have icons")
@@ -0,0 +1,209 @@
+package teamroles
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// This file handles listing teams and their rosters.
+// All lookups here are served from discordgo's State cache (kept up to date by the GuildMembers intent),
+// so that paging through the list doesn't hammer the Discord API.
+
+const (
+	// How many teams to show per page of /team list.
+	teamListPageSize = 10
+	// How many members to show in /team info before summarising the rest.
+	teamInfoMaxMembers = 30
+
+	teamListCustomIDPrefix = "bigbot_team_list_"
+)
+
+// teamSummary is a team role, along with how many members it has.
+type teamSummary struct {
+	Role    *discordgo.Role
+	Members int
+}
+
+// teamMembers returns every member of the guild that holds the given role.
+func teamMembers(s *discordgo.Session, guild, roleID string) (members []*discordgo.Member, err error) {
+	g, err := s.State.Guild(guild)
+	if err != nil {
+		return nil, err
+	}
+	s.State.RLock()
+	defer s.State.RUnlock()
+	for _, member := range g.Members {
+		if slices.Contains(member.Roles, roleID) {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+// listTeams returns every team in the guild, largest first.
+func (mod *TeamRoles) listTeams(s *discordgo.Session, guild string) (teams []teamSummary, err error) {
+	g, err := s.State.Guild(guild)
+	if err != nil {
+		return nil, err
+	}
+	s.State.RLock()
+	defer s.State.RUnlock()
+	counts := make(map[string]int)
+	for _, member := range g.Members {
+		for _, roleID := range member.Roles {
+			counts[roleID]++
+		}
+	}
+	for _, role := range g.Roles {
+		if mod.isTeam(role.ID) {
+			teams = append(teams, teamSummary{Role: role, Members: counts[role.ID]})
+		}
+	}
+	slices.SortFunc(teams, func(a, b teamSummary) int {
+		if a.Members != b.Members {
+			return b.Members - a.Members
+		}
+		return strings.Compare(strings.ToLower(a.Role.Name), strings.ToLower(b.Role.Name))
+	})
+	return teams, nil
+}
+
+// teamListPage renders the given page (zero-indexed) of the team list, along with its navigation buttons.
+func (mod *TeamRoles) teamListPage(s *discordgo.Session, guild string, page int) (content string, components []discordgo.MessageComponent, err error) {
+	teams, err := mod.listTeams(s, guild)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(teams) == 0 {
+		return "🤷 There are no teams yet. Why not create one with `/team new`?", nil, nil
+	}
+	pages := (len(teams) + teamListPageSize - 1) / teamListPageSize
+	page = max(0, min(page, pages-1))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧑‍🤝‍🧑 **Teams** (page %d of %d)\n", page+1, pages))
+	for idx, t := range teams[page*teamListPageSize : min(len(teams), (page+1)*teamListPageSize)] {
+		sb.WriteString(fmt.Sprintf("%d. **%s** - %d %s\n", page*teamListPageSize+idx+1, teamDisplayName(t.Role.Name), t.Members, helpers.Pluralise(t.Members, "member", "members")))
+	}
+
+	if pages > 1 {
+		components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Previous",
+						Style:    discordgo.SecondaryButton,
+						Disabled: page == 0,
+						CustomID: teamListCustomIDPrefix + strconv.Itoa(page-1),
+					},
+					discordgo.Button{
+						Label:    "Next",
+						Style:    discordgo.SecondaryButton,
+						Disabled: page == pages-1,
+						CustomID: teamListCustomIDPrefix + strconv.Itoa(page+1),
+					},
+				},
+			},
+		}
+	}
+	return sb.String(), components, nil
+}
+
+func (mod *TeamRoles) discordCommandTeamList(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	content, components, err := mod.teamListPage(s, i.GuildID, 0)
+	if err != nil {
+		return err
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// discordHandleTeamListPage handles the navigation buttons on the team list.
+func (mod *TeamRoles) discordHandleTeamListPage(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	page, err := strconv.Atoi(strings.TrimPrefix(i.MessageComponentData().CustomID, teamListCustomIDPrefix))
+	if err != nil {
+		return err
+	}
+	content, components, err := mod.teamListPage(s, i.GuildID, page)
+	if err != nil {
+		return err
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: components,
+		},
+	})
+}
+
+// teamInfo renders the roster of the given team.
+func (mod *TeamRoles) teamInfo(s *discordgo.Session, guild string, role *discordgo.Role) (content string, err error) {
+	if !mod.isTeam(role.ID) {
+		return "", ErrNotTeam
+	}
+	members, err := teamMembers(s, guild, role.ID)
+	if err != nil {
+		return "", err
+	}
+	t, err := mod.getTeam(role.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧑‍🤝‍🧑 **%s**\n", teamDisplayName(role.Name)))
+	if created, err := discordgo.SnowflakeTimestamp(role.ID); err == nil {
+		sb.WriteString(fmt.Sprintf("Created: <t:%d:f>\n", created.Unix()))
+	}
+	if t.Captain != "" {
+		sb.WriteString(fmt.Sprintf("Captain: <@%s>\n", t.Captain))
+	}
+	if t.Locked {
+		sb.WriteString("🔒 Locked by Crew\n")
+	}
+	if t.InviteOnly {
+		sb.WriteString("📨 Invite-only\n")
+	}
+	sb.WriteString(fmt.Sprintf("Members (%d):\n", len(members)))
+	slices.SortFunc(members, func(a, b *discordgo.Member) int {
+		return strings.Compare(strings.ToLower(helpers.DiscordMemberDisplayName(a)), strings.ToLower(helpers.DiscordMemberDisplayName(b)))
+	})
+	for idx, member := range members {
+		if idx == teamInfoMaxMembers {
+			sb.WriteString(fmt.Sprintf("- _...and %d more_\n", len(members)-teamInfoMaxMembers))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("- %s\n", member.Mention()))
+	}
+	return sb.String(), nil
+}
+
+func (mod *TeamRoles) discordCommandTeamInfo(s *discordgo.Session, i *discordgo.InteractionCreate, role *discordgo.Role) (err error) {
+	content, err := mod.teamInfo(s, i.GuildID, role)
+	if errors.Is(err, ErrNotTeam) {
+		content = fmt.Sprintf("⚠️ %s.", ErrNotTeam)
+	} else if err != nil {
+		return err
+	}
+	// Don't ping everyone listed in the roster.
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
@@ -6,10 +6,14 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/thebiggame/bigbot/internal/config"
 	"github.com/thebiggame/bigbot/internal/helpers"
+	"github.com/thebiggame/bigbot/internal/storage"
 	"log/slog"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -22,6 +26,11 @@ var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
 type TeamRoles struct {
 	discord *discordgo.Session
 	close   chan bool
+
+	// Persistent storage for team state.
+	store *storage.Store
+	// Held while changing team state, so that concurrent changes to the same team aren't lost.
+	mtx sync.Mutex
 }
 
 var commands = []*discordgo.ApplicationCommand{
@@ -55,6 +64,86 @@ var commands = []*discordgo.ApplicationCommand{
 					},
 				},
 			},
+			{
+				Name:        "invite-only",
+				Description: "🧑‍🤝‍🧑📨 Choose whether new members need the captain's approval to join your team.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "team",
+						Description: "The team you captain.",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "enabled",
+						Description: "Whether the team should be invite-only.",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Name:        "captain",
+				Description: "🧑‍🤝‍🧑🧢 Pass the captaincy of your team to another member.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "team",
+						Description: "The team you captain.",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "member",
+						Description: "The team member who should become captain.",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Name:        "style",
+				Description: "🧑‍🤝‍🧑🎨 Choose your team's colour and icon.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "team",
+						Description: "The team you captain.",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "colour",
+						Description: "The team's colour.",
+						Choices:     teamPaletteChoices(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "icon",
+						Description: "An emoji to show next to the team's members (or \"none\" to remove it).",
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Description: "🧑‍🤝‍🧑📋 List all teams.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "info",
+				Description: "🧑‍🤝‍🧑🔎 See who is in a team.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "team",
+						Description: "The team you want to know about.",
+						Required:    true,
+					},
+				},
+			},
 			{
 				Name:        "leave",
 				Description: "🧑‍🤝‍🧑👋 Leave a team",
@@ -72,9 +161,10 @@ var commands = []*discordgo.ApplicationCommand{
 	},
 }
 
-func New(discord *discordgo.Session) *TeamRoles {
+func New(discord *discordgo.Session, store *storage.Store) *TeamRoles {
 	return &TeamRoles{
 		discord: discord,
+		store:   store,
 	}
 }
 
@@ -83,21 +173,40 @@ func (mod *TeamRoles) SetLogger(log *slog.Logger) {
 }
 
 func (mod *TeamRoles) DiscordCommands() ([]*discordgo.ApplicationCommand, error) {
-	return commands, nil
+	return append(commands, adminCommands...), nil
 }
 
 func (mod *TeamRoles) Start(ctx context.Context) (err error) {
-	// This module simply registers handlers, and does not need to run continuously (so we don't need the context)
-	return ctx.Err()
+	// Keep teams in step with any changes Crew make to their roles by hand.
+	mod.discord.AddHandler(mod.discordHandleRoleUpdate)
+	mod.discord.AddHandler(mod.discordHandleRoleDelete)
+	err = mod.adoptTeamRoles(mod.discord, config.RuntimeConfig.Discord.GuildID)
+	if err != nil {
+		// Not fatal; anything we've not adopted just won't be treated as a team.
+		logger.Error("Unable to adopt existing team roles", slog.Any("error", err))
+	}
+	// Periodically tidy up teams that are no longer in use.
+	return mod.cleanupDaemon(ctx)
 }
 
 func (mod *TeamRoles) DiscordHandleMessage(session *discordgo.Session, message *discordgo.MessageCreate) (err error) {
+	return mod.recordChannelActivity(session, message)
+}
+
+func (mod *TeamRoles) DiscordHandleMessageUpdate(_ *discordgo.Session, _ *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *TeamRoles) DiscordHandleMessageDelete(_ *discordgo.Session, _ *discordgo.MessageDelete) (err error) {
 	return nil
 }
 
 func (mod *TeamRoles) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
+		if i.ApplicationCommandData().Name == "teams" {
+			return true, mod.discordHandleAdminCommand(s, i)
+		}
 		if i.ApplicationCommandData().Name != "team" {
 			return false, nil
 		}
@@ -120,16 +229,56 @@ func (mod *TeamRoles) DiscordHandleInteraction(s *discordgo.Session, i *discordg
 				break
 			}
 			roleName := options[0].Options[0].StringValue()
-			err := validateUserCanJoinRoleByName(s, i.Interaction.Member.User, i.GuildID, roleName)
+			var existingID string
+			if existing := mod.findTeamRoleByName(s, i.GuildID, roleName); existing != nil {
+				existingID = existing.ID
+			} else {
+				// This is a brand new team, so its name needs checking.
+				cleaned, reviewReason, err := moderateTeamName(roleName)
+				if err != nil {
+					content = fmt.Sprintf("⚠️ %s.", err.Error())
+					break
+				}
+				roleName = cleaned
+				if reviewReason != "" {
+					err = mod.validateUserCanJoinTeam(s, i.Interaction.Member.User, i.GuildID, "")
+					if err != nil {
+						content = fmt.Sprintf("⚠️ %s", err.Error())
+						break
+					}
+					content, err = mod.submitTeamNameForReview(s, i.Interaction.Member.User, roleName, reviewReason)
+					if err != nil {
+						content = fmt.Sprintf("⚠️ %s", err.Error())
+					}
+					break
+				}
+			}
+			err := mod.validateUserCanJoinTeam(s, i.Interaction.Member.User, i.GuildID, existingID)
 			if err != nil {
 				content = fmt.Sprintf("⚠️ %s", err.Error())
 				break
 			}
-			role, exists, err := createOrReturnRole(s, i.GuildID, roleName)
+			role, exists, err := mod.createOrReturnRole(s, i.GuildID, roleName)
 			if err != nil {
 				content = fmt.Sprintf("⚠️ %s", err.Error())
 				break
 			}
+			if exists {
+				err = mod.validateTeamIsOpen(role)
+				if err != nil {
+					content = fmt.Sprintf("⚠️ %s", err.Error())
+					break
+				}
+				t, err := mod.getTeam(role.ID)
+				if err != nil {
+					content = fmt.Sprintf("⚠️ %s", err.Error())
+					break
+				}
+				if t.InviteOnly {
+					content = mod.discordRequestToJoin(s, i.Interaction.Member.User, role)
+					break
+				}
+			}
 			err = s.GuildMemberRoleAdd(i.GuildID, i.Interaction.Member.User.ID, role.ID)
 			if err != nil {
 				content = fmt.Sprintf("⚠️ %s", err.Error())
@@ -137,8 +286,13 @@ func (mod *TeamRoles) DiscordHandleInteraction(s *discordgo.Session, i *discordg
 			}
 			if exists {
 				content = fmt.Sprintln("🤝 Joined existing", role.Name)
+				err = mod.touchTeam(role.ID)
+				if err != nil {
+					content = fmt.Sprintf("⚠️ %s", err.Error())
+					break
+				}
 			} else {
-				content = fmt.Sprintln("✨ Created", role.Name)
+				content = mod.setupNewTeam(s, i.GuildID, i.Interaction.Member.User, role)
 			}
 
 		case "join":
@@ -152,22 +306,87 @@ func (mod *TeamRoles) DiscordHandleInteraction(s *discordgo.Session, i *discordg
 				break
 			}
 			role := options[0].Options[0].RoleValue(s, i.GuildID)
-			isTeam, _ := getTeamName(role.Name)
-			if !isTeam {
+			if !mod.isTeam(role.ID) {
 				content = fmt.Sprintf("⚠️ %s. Stop that. <:ninja:449495170430533633>", ErrNotTeam)
 				break
 			}
-			err := validateUserCanJoinRole(s, i.Interaction.Member.User, i.GuildID, role)
+			err := mod.validateUserCanJoinTeam(s, i.Interaction.Member.User, i.GuildID, role.ID)
 			if err != nil {
 				content = fmt.Sprintf("⚠️ %s", err.Error())
 				break
 			}
+			err = mod.validateTeamIsOpen(role)
+			if err != nil {
+				content = fmt.Sprintf("⚠️ %s", err.Error())
+				break
+			}
+			t, err := mod.getTeam(role.ID)
+			if err != nil {
+				content = fmt.Sprintf("⚠️ %s", err.Error())
+				break
+			}
+			if t.InviteOnly {
+				content = mod.discordRequestToJoin(s, i.Interaction.Member.User, role)
+				break
+			}
 			err = s.GuildMemberRoleAdd(i.GuildID, i.Interaction.Member.User.ID, role.ID)
 			if err != nil {
 				content = fmt.Sprintf("⚠️ %s", err.Error())
 				break
 			}
+			err = mod.touchTeam(role.ID)
+			if err != nil {
+				content = fmt.Sprintf("⚠️ %s", err.Error())
+				break
+			}
 			content = fmt.Sprintln("🤝 Joined", role.Name)
+		case "invite-only", "captain", "style":
+			if i.Interaction.GuildID == "" {
+				content = "😡 This command can only be used in a server."
+				break
+			}
+			optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options[0].Options))
+			for _, opt := range options[0].Options {
+				optionMap[opt.Name] = opt
+			}
+			role := optionMap["team"].RoleValue(s, i.GuildID)
+			if !mod.isTeam(role.ID) {
+				content = fmt.Sprintf("⚠️ %s. Stop that. <:ninja:449495170430533633>", ErrNotTeam)
+				break
+			}
+			switch options[0].Name {
+			case "invite-only":
+				content, err = mod.setInviteOnly(s, i.GuildID, i.Interaction.Member.User, role, optionMap["enabled"].BoolValue())
+			case "captain":
+				content, err = mod.setCaptain(s, i.GuildID, i.Interaction.Member.User, role, optionMap["member"].UserValue(s))
+			case "style":
+				var colour *int
+				var icon *string
+				if opt, ok := optionMap["colour"]; ok {
+					value := int(opt.IntValue())
+					colour = &value
+				}
+				if opt, ok := optionMap["icon"]; ok {
+					value := opt.StringValue()
+					icon = &value
+				}
+				content, err = mod.setStyle(s, i.GuildID, i.Interaction.Member.User, role, colour, icon)
+			}
+			if err != nil {
+				return true, err
+			}
+		case "list":
+			if i.Interaction.GuildID == "" {
+				content = "😡 This command can only be used in a server."
+				break
+			}
+			return true, mod.discordCommandTeamList(s, i)
+		case "info":
+			if i.Interaction.GuildID == "" {
+				content = "😡 This command can only be used in a server."
+				break
+			}
+			return true, mod.discordCommandTeamInfo(s, i, options[0].Options[0].RoleValue(s, i.GuildID))
 		case "leave":
 			// OOB check
 			if len(options[0].Options) < 0 {
@@ -179,8 +398,7 @@ func (mod *TeamRoles) DiscordHandleInteraction(s *discordgo.Session, i *discordg
 				break
 			}
 			role := options[0].Options[0].RoleValue(s, i.GuildID)
-			isTeam, _ := getTeamName(role.Name)
-			if !isTeam {
+			if !mod.isTeam(role.ID) {
 				content = fmt.Sprintf("⚠️ %s. Stop that. <:ninja:449495170430533633>", ErrNotTeam)
 				break
 			}
@@ -194,120 +412,184 @@ func (mod *TeamRoles) DiscordHandleInteraction(s *discordgo.Session, i *discordg
 				content = fmt.Sprintf("⚠️ %s", err.Error())
 				break
 			}
+			err = mod.handleMemberLeft(role, i.Interaction.Member.User.ID)
+			if err != nil {
+				content = fmt.Sprintf("⚠️ %s", err.Error())
+				break
+			}
+			err = mod.touchTeam(role.ID)
+			if err != nil {
+				content = fmt.Sprintf("⚠️ %s", err.Error())
+				break
+			}
 			content = fmt.Sprintln("👋 Left", role.Name)
 		default:
 			content = "😶 Please use a sub-command."
 		}
 
 		return true, helpers.DiscordInteractionEphemeralResponse(s, i, content)
+	case discordgo.InteractionMessageComponent:
+		switch {
+		case strings.HasPrefix(i.MessageComponentData().CustomID, teamListCustomIDPrefix):
+			return true, mod.discordHandleTeamListPage(s, i)
+		case strings.HasPrefix(i.MessageComponentData().CustomID, teamRequestAcceptCustomIDPrefix),
+			strings.HasPrefix(i.MessageComponentData().CustomID, teamRequestDeclineCustomIDPrefix):
+			return true, mod.discordHandleJoinRequestResponse(s, i)
+		case strings.HasPrefix(i.MessageComponentData().CustomID, teamCleanupDeleteCustomIDPrefix),
+			strings.HasPrefix(i.MessageComponentData().CustomID, teamCleanupKeepCustomIDPrefix):
+			return true, mod.discordHandleCleanupResponse(s, i)
+		case strings.HasPrefix(i.MessageComponentData().CustomID, teamNameApproveCustomIDPrefix),
+			strings.HasPrefix(i.MessageComponentData().CustomID, teamNameRejectCustomIDPrefix):
+			return true, mod.discordHandleTeamNameReview(s, i)
+		default:
+			// This isn't anything to do with us.
+			return false, nil
+		}
 	default:
 		return false, nil
 	}
 }
 
-func validateUserCanJoinRoleByName(s *discordgo.Session, u *discordgo.User, guild, targetRole string) error {
-	// This function validates that the given GuildMember satisfies the following rules:
-	// - is not already assigned to more than 5 Team Roles
-	// - is trying to join a team
-	// - is not already assigned to the given targetRole
-	var roleCount int
-	member, err := s.GuildMember(guild, u.ID)
+// guildMember fetches a member of the guild, preferring the State cache.
+func guildMember(s *discordgo.Session, guild, userID string) (*discordgo.Member, error) {
+	member, err := s.State.Member(guild, userID)
+	if err == nil {
+		return member, nil
+	}
+	return s.GuildMember(guild, userID)
+}
+
+// validateUserCanJoinTeam checks that the given user:
+// - is not already a member of the maximum number of teams
+// - is not already a member of the given team (if joining an existing team, rather than creating one)
+func (mod *TeamRoles) validateUserCanJoinTeam(s *discordgo.Session, u *discordgo.User, guild, roleID string) error {
+	member, err := guildMember(s, guild, u.ID)
 	if err != nil {
 		return err
 	}
+	var teamCount int
 	for _, v := range member.Roles {
-		role, err := s.State.Role(guild, v)
-		if err != nil {
-			return err
+		if !mod.isTeam(v) {
+			continue
 		}
-		isTeam, _ := getTeamName(role.Name)
-		// role names get normalized to lower case during the lookup only
-		if isTeam {
-			if strings.ToLower(role.Name) == strings.ToLower(targetRole) {
-				// The Member is already part of the given GuildRole!
-				return ErrAlreadyTeamMember
-			}
-			// Check if it's a team role, and if it is, add to the counter
-			roleCount++
+		if v == roleID {
+			return ErrAlreadyTeamMember
 		}
+		teamCount++
 	}
-	if roleCount >= config.RuntimeConfig.Teams.MaxUserTeams {
-		// Joining this Role would take the user over their limit
-		return ErrMaxTeamsReached
+	if teamCount >= config.RuntimeConfig.Teams.MaxUserTeams {
+		// Joining this team would take the user over their limit
+		return fmt.Errorf("%w (%d)! Please contact an administrator if you need more", ErrMaxTeamsReached, config.RuntimeConfig.Teams.MaxUserTeams)
 	}
-
-	// Succ(ess)
 	return nil
 }
 
-func validateUserCanJoinRole(s *discordgo.Session, u *discordgo.User, guild string, targetRole *discordgo.Role) (err error) {
-	// TODO This whole thing of matching on name sucks. Refactor me.
-	roleName := targetRole.Name
-	return validateUserCanJoinRoleByName(s, u, guild, roleName)
-}
-
+// validateUserIsRoleMember checks that the given user holds the given role.
 func validateUserIsRoleMember(s *discordgo.Session, u *discordgo.User, guild string, targetRole *discordgo.Role) error {
-	// This function validates that the given GuildMember satisfies the following rules:
-	// - is assigned to the given guild role
-	member, err := s.GuildMember(guild, u.ID)
+	member, err := guildMember(s, guild, u.ID)
 	if err != nil {
 		return err
 	}
-	for _, v := range member.Roles {
-		role, err := s.State.Role(guild, v)
-		if err != nil {
-			return err
-		}
-		if role != nil && role.ID == targetRole.ID {
-			return nil
-		}
+	if slices.Contains(member.Roles, targetRole.ID) {
+		return nil
 	}
-
-	// Failure
 	return ErrNotTeamMember
 }
 
+// teamRoleNamePattern matches role names that follow the team naming convention, capturing the team's name.
+var teamRoleNamePattern = regexp.MustCompile(`(?i)^` + regexp.QuoteMeta(teamRolePrefix) + `\s*([^:\s].*)$`)
+
+// getTeamName splits the team name out of a role name that follows the team naming convention.
+// Note that this says nothing about whether the role is actually a team; use isTeam for that.
 func getTeamName(roleName string) (isTeam bool, team string) {
-	getRoleName := regexp.MustCompile(`(?i)^` + teamRolePrefix + `* ?(.*)`)
-	teamName := getRoleName.FindAllStringSubmatch(roleName, -1)
-	if teamName != nil && teamName[0][1] != "" {
-		return true, teamName[0][1]
+	match := teamRoleNamePattern.FindStringSubmatch(roleName)
+	if match == nil {
+		return false, ""
 	}
-	return false, ""
+	return true, match[1]
 }
 
-func createOrReturnRole(s *discordgo.Session, guild string, rname string) (v *discordgo.Role, roleExisted bool, err error) {
-	roles, err := s.GuildRoles(guild)
-	isRole, _ := getTeamName(rname)
+// teamDisplayName returns the name of the team with the given role name.
+// Crew may have renamed the role without the team prefix, in which case the whole role name is used.
+func teamDisplayName(roleName string) string {
+	if isTeam, name := getTeamName(roleName); isTeam {
+		return name
+	}
+	return roleName
+}
+
+// teamRoleName returns the full role name for the given team name, adding the team prefix if necessary.
+func teamRoleName(name string) string {
+	isRole, _ := getTeamName(name)
 	if !isRole {
-		rname = fmt.Sprintln(teamRolePrefix, rname)
+		name = fmt.Sprintln(teamRolePrefix, name)
 	}
-	rname = strings.Replace(rname, "\n", "", -1)
-	if err == nil {
-		for _, v := range roles {
-			// role names get normalized to lower case during the lookup only
-			if strings.ToLower(v.Name) == strings.ToLower(rname) {
-				logger.Debug("Tying to existing team role", slog.String("requested_role", rname), slog.String("existing_role", v.Name))
-				return v, true, nil
-			}
-		}
-		// couldn't find the role in our list, create it
-		logger.Debug("Creating new team role", slog.String("role", rname))
-		var rColour = 8290694
-		var rHoist = true
-		var rMentionable = true
-		var rPerms int64 = 0
-		rParams := discordgo.RoleParams{
-			Name:         rname,
-			Color:        &rColour,
-			Hoist:        &rHoist,
-			Permissions:  &rPerms,
-			Mentionable:  &rMentionable,
-			UnicodeEmoji: nil,
-			Icon:         nil,
+	return strings.Replace(name, "\n", "", -1)
+}
+
+// findTeamRoleByName returns the existing team with the given name (if any).
+func (mod *TeamRoles) findTeamRoleByName(s *discordgo.Session, guild string, rname string) *discordgo.Role {
+	// Names are compared by their folded key, so that look-alike names are treated as the same team.
+	key := teamNameKey(rname)
+	for _, v := range mod.teamRoles(s, guild) {
+		if teamNameKey(v.Name) == key {
+			return v
 		}
-		role, err := s.GuildRoleCreate(guild, &rParams)
-		return role, false, err
 	}
-	return nil, false, fmt.Errorf("problem creating the target role: %w", err)
+	return nil
+}
+
+// setupNewTeam does everything needed once a new team's role has been created and given to its creator,
+// returning the message to show them.
+func (mod *TeamRoles) setupNewTeam(s *discordgo.Session, guild string, u *discordgo.User, role *discordgo.Role) (content string) {
+	mod.mtx.Lock()
+	t, err := mod.getTeam(role.ID)
+	if err == nil {
+		// Whoever creates the team captains it.
+		t.Captain = u.ID
+		t.LastActive = time.Now()
+		err = mod.saveTeam(role.ID, t)
+	}
+	mod.mtx.Unlock()
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s", err.Error())
+	}
+	content = fmt.Sprintln("✨ Created", role.Name)
+	err = mod.createTeamChannels(s, guild, role)
+	if err != nil {
+		// The team itself is fine, so don't fail the whole command.
+		logger.Error("Unable to create team channels", slog.String("role", role.Name), slog.Any("error", err))
+		content += "⚠️ Your team's channels couldn't be created. Please contact a member of Crew."
+	}
+	return content
+}
+
+func (mod *TeamRoles) createOrReturnRole(s *discordgo.Session, guild string, rname string) (v *discordgo.Role, roleExisted bool, err error) {
+	rname = teamRoleName(rname)
+	existing := mod.findTeamRoleByName(s, guild, rname)
+	if existing != nil {
+		logger.Debug("Tying to existing team role", slog.String("requested_role", rname), slog.String("existing_role", existing.Name))
+		return existing, true, nil
+	}
+	// couldn't find the role in our list, create it
+	logger.Debug("Creating new team role", slog.String("role", rname))
+	var rColour = defaultTeamColour
+	var rHoist = true
+	var rMentionable = true
+	var rPerms int64 = 0
+	rParams := discordgo.RoleParams{
+		Name:         rname,
+		Color:        &rColour,
+		Hoist:        &rHoist,
+		Permissions:  &rPerms,
+		Mentionable:  &rMentionable,
+		UnicodeEmoji: nil,
+		Icon:         nil,
+	}
+	role, err := s.GuildRoleCreate(guild, &rParams)
+	if err != nil {
+		return nil, false, err
+	}
+	// Start tracking the team straight away, so it isn't lost if anything after this fails.
+	return role, false, mod.saveTeam(role.ID, team{LastActive: time.Now()})
 }
@@ -1,6 +1,21 @@
 package teamroles
 
-import "testing"
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"github.com/thebiggame/bigbot/internal/storage/storagetest"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 var teamNameValidTests map[string]string = map[string]string{
 	teamRolePrefix + " Test":               "Test",
@@ -12,6 +27,10 @@ var teamNameValidTests map[string]string = map[string]string{
 var teamNameInvalidTests []string = []string{
 	"@everyone",
 	"Test Team",
+	"Teamwork",
+	teamRolePrefix,
+	teamRolePrefix + "::",
+	teamRolePrefix + " ",
 }
 
 func TestGetTeamName(t *testing.T) {
@@ -35,3 +54,511 @@ func TestGetTeamName(t *testing.T) {
 		}
 	})
 }
+
+func TestParseHexColour(t *testing.T) {
+	valid := map[string]int{
+		"#7E8186": 0x7E8186,
+		"7e8186":  0x7E8186,
+		"#000000": 0,
+	}
+	for colour, expected := range valid {
+		value, err := parseHexColour(colour)
+		if err != nil {
+			t.Errorf("Colour '%s' should be valid: %v", colour, err)
+		}
+		if value != expected {
+			t.Errorf("Colour '%s' should be %d, got %d", colour, expected, value)
+		}
+	}
+	for _, colour := range []string{"", "#FFF", "#GGGGGG", "#1234567"} {
+		if _, err := parseHexColour(colour); err == nil {
+			t.Errorf("Colour '%s' should be invalid", colour)
+		}
+	}
+}
+
+func TestTeamChannelName(t *testing.T) {
+	config.RuntimeConfig.Teams.Channels.NameTemplate = "team-{{.Name}}"
+	name, err := teamChannelName(teamRolePrefix + " iBUYJEFFS")
+	if err != nil {
+		t.Fatalf("teamChannelName err: %v", err)
+	}
+	if name != "team-iBUYJEFFS" {
+		t.Errorf("Channel name should be 'team-iBUYJEFFS', got '%s'", name)
+	}
+}
+
+func TestTeamNameKey(t *testing.T) {
+	same := [][2]string{
+		{"iBUYJEFFS", "ibuyjeffs"},
+		{"iBUYJEFFS", "i BUY jeffs"},
+		{"iBUYJEFFS", teamRolePrefix + " iBUYJEFFS"},
+		{"Café Racers", "cafe racers"},
+		// Cyrillic 'а' and 'о'
+		{"Lаn Gоds", "lan gods"},
+		// Zero-width space
+		{"Lan\u200bGods", "LanGods"},
+		// Fullwidth letters
+		{"ＴＢＧ", "tbg"},
+	}
+	for _, pair := range same {
+		if teamNameKey(pair[0]) != teamNameKey(pair[1]) {
+			t.Errorf("'%s' and '%s' should collide, got '%s' and '%s'", pair[0], pair[1], teamNameKey(pair[0]), teamNameKey(pair[1]))
+		}
+	}
+	if teamNameKey("Lan Gods") == teamNameKey("Lan Dogs") {
+		t.Errorf("'Lan Gods' and 'Lan Dogs' should not collide")
+	}
+}
+
+func TestCleanTeamName(t *testing.T) {
+	cases := map[string]string{
+		"  Lan   Gods ": "Lan Gods",
+		"Lan\u200bGods": "LanGods",
+		"Lan\tGods\n":   "Lan Gods",
+		"🧑‍🤝‍🧑 Friends": "🧑‍🤝‍🧑 Friends",
+	}
+	for name, expected := range cases {
+		if cleaned := cleanTeamName(name); cleaned != expected {
+			t.Errorf("'%s' should clean to '%s', got '%s'", name, expected, cleaned)
+		}
+	}
+}
+
+func TestModerateTeamName(t *testing.T) {
+	config.RuntimeConfig.Teams.Names.MinLength = 2
+	config.RuntimeConfig.Teams.Names.MaxLength = 32
+	config.RuntimeConfig.Teams.Names.Reserved = []string{"crew"}
+	config.RuntimeConfig.Teams.Names.DenyList = []string{"jeff"}
+
+	cleaned, reason, err := moderateTeamName("  Lan  Gods ")
+	if err != nil || reason != "" || cleaned != "Lan Gods" {
+		t.Errorf("'Lan Gods' should be allowed, got '%s', '%s', %v", cleaned, reason, err)
+	}
+	for _, name := range []string{"CREW", "Сrew", "iBUYJEFFS", "i buy jeffs"} {
+		if _, reason, err := moderateTeamName(name); err != nil || reason == "" {
+			t.Errorf("'%s' should need review, got '%s', %v", name, reason, err)
+		}
+	}
+	if _, _, err := moderateTeamName("x"); !errors.Is(err, ErrTeamNameLength) {
+		t.Errorf("'x' should be too short, got %v", err)
+	}
+	if _, _, err := moderateTeamName(strings.Repeat("x", 33)); !errors.Is(err, ErrTeamNameLength) {
+		t.Errorf("33 characters should be too long, got %v", err)
+	}
+	for _, name := range []string{"@everyone", "<@1234>", "<#1234>"} {
+		if _, _, err := moderateTeamName(name); !errors.Is(err, ErrTeamNameMention) {
+			t.Errorf("'%s' should be rejected as a mention, got %v", name, err)
+		}
+	}
+}
+
+func TestFindTeamRoleByName(t *testing.T) {
+	store := storagetest.Open(t)
+	mod := New(nil, store)
+	s := &discordgo.Session{State: discordgo.NewState()}
+	err := s.State.GuildAdd(&discordgo.Guild{
+		ID: "1",
+		Roles: []*discordgo.Role{
+			{ID: "10", Name: teamRolePrefix + " iBUYJEFFS"},
+			{ID: "11", Name: teamRolePrefix + " Untracked"},
+			// Renamed by hand, without the team prefix.
+			{ID: "12", Name: "Lan Gods"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GuildAdd err: %v", err)
+	}
+	for _, roleID := range []string{"10", "12"} {
+		if err = mod.saveTeam(roleID, team{}); err != nil {
+			t.Fatalf("saveTeam err: %v", err)
+		}
+	}
+
+	found := map[string]string{
+		"ibuyjeffs":                    "10",
+		teamRolePrefix + " ibuy jeffs": "10",
+		"Lan Gods":                     "12",
+	}
+	for name, roleID := range found {
+		role := mod.findTeamRoleByName(s, "1", name)
+		if role == nil || role.ID != roleID {
+			t.Errorf("'%s' should find role %s, got %v", name, roleID, role)
+		}
+	}
+	if role := mod.findTeamRoleByName(s, "1", "Untracked"); role != nil {
+		t.Errorf("Untracked roles should not be found, got %v", role)
+	}
+}
+
+func TestValidateTeamIcon(t *testing.T) {
+	for _, icon := range []string{"🔥", " 🔥 ", "❤️", "🧑‍🤝‍🧑", "👍🏽", "🇬🇧", "1️⃣", "🏴󠁧󠁢󠁳󠁣󠁴󠁿"} {
+		if _, err := validateTeamIcon(icon); err != nil {
+			t.Errorf("Icon '%s' should be valid: %v", icon, err)
+		}
+	}
+	for _, icon := range []string{"", "🔥🔥", "abc", "1", "<:ninja:449495170430533633>", "🇬", "🇬🇧🇫🇷"} {
+		if _, err := validateTeamIcon(icon); err == nil {
+			t.Errorf("Icon '%s' should be invalid", icon)
+		}
+	}
+}
+
+func TestColourClashes(t *testing.T) {
+	if !colourClashes(0xE91E63, 0xE91E63) || !colourClashes(0xE91E63, 0xE91E60) {
+		t.Errorf("Near-identical colours should clash")
+	}
+	if colourClashes(0x000000, 0xFFFFFF) || colourClashes(0xE91E63, 0x1565C0) {
+		t.Errorf("Distinct colours should not clash")
+	}
+	// The palette is no use if its colours can't be told apart.
+	for idx, a := range teamPalette {
+		for _, b := range teamPalette[idx+1:] {
+			if colourClashes(a.Value, b.Value) {
+				t.Errorf("Palette colours %s and %s clash", a.Name, b.Name)
+			}
+		}
+	}
+}
+
+// fakeDiscord answers Discord API requests without going anywhere near Discord, recording what was asked of it.
+type fakeDiscord struct {
+	mtx sync.Mutex
+	// Canned responses, keyed on "METHOD /path" (relative to the API root). Anything else gets "{}".
+	responses map[string]string
+	// The body of each request, keyed the same way.
+	requests map[string]string
+	// Requests that should fail with a 403, keyed the same way.
+	forbidden map[string]bool
+}
+
+func (fake *fakeDiscord) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " /" + strings.TrimPrefix(req.URL.Path, "/api/v"+discordgo.APIVersion+"/")
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	fake.mtx.Lock()
+	defer fake.mtx.Unlock()
+	fake.requests[key] = string(body)
+	status := http.StatusOK
+	response, ok := fake.responses[key]
+	if fake.forbidden[key] {
+		status = http.StatusForbidden
+		response = `{"message":"Missing Permissions","code":50013}`
+	} else if !ok {
+		response = "{}"
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(response)),
+		Request:    req,
+	}, nil
+}
+
+func TestTeamNameReviewButtons(t *testing.T) {
+	config.RuntimeConfig.Discord.Permissions.CrewRole = "100"
+	store := storagetest.Open(t)
+	mod := New(nil, store)
+	if err := store.Put(teamNameReviewsBucket, "abc", teamNameReview{UserID: "2", Name: "Lan Gods", Reason: "is reserved"}); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+
+	fake := &fakeDiscord{
+		responses: map[string]string{
+			"GET /guilds/1/members/3": `{"user":{"id":"3","username":"crew"},"roles":["100"]}`,
+			"GET /users/2":            `{"id":"2","username":"requester"}`,
+		},
+		requests: map[string]string{},
+	}
+	s, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatalf("discordgo.New err: %v", err)
+	}
+	s.Client = &http.Client{Transport: fake}
+	if err = s.State.GuildAdd(&discordgo.Guild{ID: "1", Roles: []*discordgo.Role{{ID: "100", Name: "Crew"}}}); err != nil {
+		t.Fatalf("GuildAdd err: %v", err)
+	}
+
+	handled, err := mod.DiscordHandleInteraction(s, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:      "50",
+		Token:   "token",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "3", Username: "crew"}},
+		Data:    discordgo.MessageComponentInteractionData{CustomID: teamNameRejectCustomIDPrefix + "abc"},
+	}})
+	if !handled || err != nil {
+		t.Fatalf("Expected the Reject button to be handled, got %v (%v)", handled, err)
+	}
+	if err = store.Get(teamNameReviewsBucket, "abc", &teamNameReview{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected the review to be removed, got %v", err)
+	}
+	if response := fake.requests["POST /interactions/50/token/callback"]; !strings.Contains(response, "rejected") || !strings.Contains(response, "Lan Gods") {
+		t.Errorf("Expected the review message to say the name was rejected, got %q", response)
+	}
+}
+
+func TestRoleChangedByHand(t *testing.T) {
+	config.RuntimeConfig.Discord.GuildID = "1"
+	config.RuntimeConfig.Teams.Channels.NameTemplate = "team-{{.Name}}"
+	store := storagetest.Open(t)
+	mod := New(nil, store)
+	if err := mod.saveTeam("10", team{TextChannel: "20", VoiceChannel: "21"}); err != nil {
+		t.Fatalf("saveTeam err: %v", err)
+	}
+	fake := &fakeDiscord{responses: map[string]string{}, requests: map[string]string{}}
+	s, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatalf("discordgo.New err: %v", err)
+	}
+	s.Client = &http.Client{Transport: fake}
+	err = s.State.GuildAdd(&discordgo.Guild{
+		ID: "1",
+		Channels: []*discordgo.Channel{
+			{ID: "20", GuildID: "1", Name: "team-old", Type: discordgo.ChannelTypeGuildText},
+			// Somebody's already renamed this one.
+			{ID: "21", GuildID: "1", Name: "team-new", Type: discordgo.ChannelTypeGuildVoice},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GuildAdd err: %v", err)
+	}
+
+	mod.discordHandleRoleUpdate(s, &discordgo.GuildRoleUpdate{GuildRole: &discordgo.GuildRole{
+		GuildID: "1",
+		Role:    &discordgo.Role{ID: "10", Name: teamRolePrefix + " new"},
+	}})
+	if body, ok := fake.requests["PATCH /channels/20"]; !ok || !strings.Contains(body, "team-new") {
+		t.Errorf("Expected the text channel to be renamed, got %q", body)
+	}
+	if _, ok := fake.requests["PATCH /channels/21"]; ok {
+		t.Error("Expected the up-to-date voice channel to be left alone")
+	}
+
+	// Roles that aren't teams are nothing to do with us.
+	mod.discordHandleRoleDelete(s, &discordgo.GuildRoleDelete{GuildID: "1", RoleID: "11"})
+	if len(fake.requests) != 1 {
+		t.Errorf("Expected no requests for a role that isn't a team, got %v", fake.requests)
+	}
+
+	mod.discordHandleRoleDelete(s, &discordgo.GuildRoleDelete{GuildID: "1", RoleID: "10"})
+	for _, channel := range []string{"DELETE /channels/20", "DELETE /channels/21"} {
+		if _, ok := fake.requests[channel]; !ok {
+			t.Errorf("Expected %s, got %v", channel, fake.requests)
+		}
+	}
+	if mod.isTeam("10") {
+		t.Error("Expected the team to be forgotten")
+	}
+}
+
+// testSession returns a Discord session that talks to fake, with a guild ("1") holding the given roles and members.
+func testSession(t *testing.T, fake *fakeDiscord, roles []*discordgo.Role, members []*discordgo.Member) *discordgo.Session {
+	s, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatalf("discordgo.New err: %v", err)
+	}
+	s.Client = &http.Client{Transport: fake}
+	s.State.User = &discordgo.User{ID: "99"}
+	if err = s.State.GuildAdd(&discordgo.Guild{ID: "1", Roles: roles, Members: members}); err != nil {
+		t.Fatalf("GuildAdd err: %v", err)
+	}
+	return s
+}
+
+func TestAdminMerge(t *testing.T) {
+	store := storagetest.Open(t)
+	mod := New(nil, store)
+	from := &discordgo.Role{ID: "10", Name: teamRolePrefix + " From"}
+	into := &discordgo.Role{ID: "11", Name: teamRolePrefix + " Into"}
+	for _, role := range []*discordgo.Role{from, into} {
+		if err := mod.saveTeam(role.ID, team{}); err != nil {
+			t.Fatalf("saveTeam err: %v", err)
+		}
+	}
+	fake := &fakeDiscord{
+		responses: map[string]string{},
+		requests:  map[string]string{},
+		forbidden: map[string]bool{"PUT /guilds/1/members/32/roles/11": true},
+	}
+	s := testSession(t, fake, []*discordgo.Role{from, into}, []*discordgo.Member{
+		{GuildID: "1", User: &discordgo.User{ID: "31"}, Roles: []string{"10"}},
+		{GuildID: "1", User: &discordgo.User{ID: "32"}, Roles: []string{"10"}},
+		// Already in both teams, so doesn't need moving.
+		{GuildID: "1", User: &discordgo.User{ID: "33"}, Roles: []string{"10", "11"}},
+	})
+
+	content, err := mod.adminMerge(s, "1", from, into)
+	if err != nil {
+		t.Fatalf("adminMerge err: %v", err)
+	}
+	if !strings.Contains(content, "Moved 1 member") || !strings.Contains(content, "<@32>") {
+		t.Errorf("Expected one member moved and one failure, got %q", content)
+	}
+	if !mod.isTeam("10") {
+		t.Error("Expected the old team to be kept while somebody still needs moving")
+	}
+
+	delete(fake.forbidden, "PUT /guilds/1/members/32/roles/11")
+	content, err = mod.adminMerge(s, "1", from, into)
+	if err != nil {
+		t.Fatalf("adminMerge err: %v", err)
+	}
+	if !strings.Contains(content, "(2 members moved)") {
+		t.Errorf("Expected two members moved, got %q", content)
+	}
+	if _, ok := fake.requests["DELETE /guilds/1/roles/10"]; !ok || mod.isTeam("10") {
+		t.Error("Expected the old team to be removed")
+	}
+}
+
+func TestAdminCommandError(t *testing.T) {
+	config.RuntimeConfig.Discord.Permissions.CrewRole = "100"
+	config.RuntimeConfig.Teams.Names.MinLength = 2
+	config.RuntimeConfig.Teams.Names.MaxLength = 32
+	store := storagetest.Open(t)
+	mod := New(nil, store)
+	if err := mod.saveTeam("10", team{}); err != nil {
+		t.Fatalf("saveTeam err: %v", err)
+	}
+	fake := &fakeDiscord{
+		responses: map[string]string{"GET /guilds/1/members/3": `{"user":{"id":"3"},"roles":["100"]}`},
+		requests:  map[string]string{},
+		forbidden: map[string]bool{"PATCH /guilds/1/roles/10": true},
+	}
+	s := testSession(t, fake, []*discordgo.Role{{ID: "10", Name: teamRolePrefix + " Old"}, {ID: "100", Name: "Crew"}}, nil)
+
+	handled, err := mod.DiscordHandleInteraction(s, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:      "50",
+		AppID:   "99",
+		Token:   "token",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "3"}},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "teams",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Name: "admin",
+				Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+					Name: "rename",
+					Type: discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandInteractionDataOption{
+						{Name: "team", Type: discordgo.ApplicationCommandOptionRole, Value: "10"},
+						{Name: "team-name", Type: discordgo.ApplicationCommandOptionString, Value: "New"},
+					},
+				}},
+			}},
+		},
+	}})
+	// The interaction has been deferred, so the error must be reported with a followup rather than returned.
+	if !handled || err != nil {
+		t.Fatalf("Expected the error to be handled, got %v (%v)", handled, err)
+	}
+	if followup := fake.requests["POST /webhooks/99/token"]; !strings.Contains(followup, "Missing Permissions") {
+		t.Errorf("Expected the error in a followup, got %q", followup)
+	}
+}
+
+func TestJoinRequestAcceptWhenLocked(t *testing.T) {
+	config.RuntimeConfig.Discord.GuildID = "1"
+	store := storagetest.Open(t)
+	mod := New(nil, store)
+	// The request was made before Crew locked the team.
+	if err := mod.saveTeam("10", team{Captain: "3", Locked: true, JoinRequests: []string{"4"}}); err != nil {
+		t.Fatalf("saveTeam err: %v", err)
+	}
+	fake := &fakeDiscord{responses: map[string]string{}, requests: map[string]string{}}
+	s := testSession(t, fake, []*discordgo.Role{{ID: "10", Name: teamRolePrefix + " Locked"}}, nil)
+
+	handled, err := mod.DiscordHandleInteraction(s, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:    "50",
+		Token: "token",
+		Type:  discordgo.InteractionMessageComponent,
+		User:  &discordgo.User{ID: "3"},
+		Data:  discordgo.MessageComponentInteractionData{CustomID: teamRequestAcceptCustomIDPrefix + "10_4"},
+	}})
+	if !handled || err != nil {
+		t.Fatalf("Expected the Accept button to be handled, got %v (%v)", handled, err)
+	}
+	if response := fake.requests["POST /interactions/50/token/callback"]; !strings.Contains(response, "locked") {
+		t.Errorf("Expected the locked message, got %q", response)
+	}
+	if _, ok := fake.requests["PUT /guilds/1/members/4/roles/10"]; ok {
+		t.Error("Expected nobody to be added to a locked team")
+	}
+	if got, _ := mod.getTeam("10"); !slices.Contains(got.JoinRequests, "4") {
+		t.Errorf("Expected the join request to be kept, got %v", got.JoinRequests)
+	}
+}
+
+func TestConcurrentTeamChanges(t *testing.T) {
+	// Saving to disk makes the window for losing changes much wider.
+	store, err := storage.Open(filepath.Join(t.TempDir(), "storage.json"))
+	if err != nil {
+		t.Fatalf("storage.Open err: %v", err)
+	}
+	mod := New(nil, store)
+	if err = mod.saveTeam("10", team{Captain: "3"}); err != nil {
+		t.Fatalf("saveTeam err: %v", err)
+	}
+	// Chatting in the team channel while people ask to join mustn't lose any of their requests.
+	var wg sync.WaitGroup
+	for n := range 100 {
+		userID := strconv.Itoa(1000 + n)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := mod.addJoinRequest("10", userID); err != nil {
+				t.Errorf("addJoinRequest err: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := mod.touchTeam("10"); err != nil {
+				t.Errorf("touchTeam err: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, _ := mod.getTeam("10"); len(got.JoinRequests) != 100 || got.LastActive.IsZero() {
+		t.Errorf("Expected every join request to be kept, got %+v", got)
+	}
+}
+
+func TestCleanupAutoDelete(t *testing.T) {
+	previous := config.RuntimeConfig.Teams.Cleanup
+	t.Cleanup(func() { config.RuntimeConfig.Teams.Cleanup = previous })
+	config.RuntimeConfig.Teams.Cleanup.StaleAfter = time.Hour
+	config.RuntimeConfig.Teams.Cleanup.AutoDelete = true
+	config.RuntimeConfig.Teams.Cleanup.ReportChannelID = "40"
+	store := storagetest.Open(t)
+	mod := New(nil, store)
+	longAgo := time.Now().Add(-2 * time.Hour)
+	for _, roleID := range []string{"10", "11"} {
+		if err := mod.saveTeam(roleID, team{LastActive: longAgo}); err != nil {
+			t.Fatalf("saveTeam err: %v", err)
+		}
+	}
+	fake := &fakeDiscord{responses: map[string]string{}, requests: map[string]string{}}
+	s := testSession(t, fake,
+		[]*discordgo.Role{{ID: "10", Name: teamRolePrefix + " Empty"}, {ID: "11", Name: teamRolePrefix + " Quiet"}},
+		[]*discordgo.Member{{User: &discordgo.User{ID: "30"}, Roles: []string{"11"}}},
+	)
+
+	if err := mod.cleanupTeams(s, "1"); err != nil {
+		t.Fatalf("cleanupTeams err: %v", err)
+	}
+	if mod.isTeam("10") {
+		t.Error("Expected the empty team to be deleted")
+	}
+	// A team with members in it may just be quiet, so Crew must confirm.
+	if !mod.isTeam("11") {
+		t.Error("Expected the stale team with members to be kept")
+	}
+	if got, _ := mod.getTeam("11"); !got.Reported {
+		t.Error("Expected the stale team to be reported to Crew")
+	}
+}
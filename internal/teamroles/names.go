@@ -0,0 +1,126 @@
+package teamroles
+
+import (
+	"fmt"
+	"github.com/thebiggame/bigbot/internal/config"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file handles the moderation of team names.
+// Every requested name is cleaned up, then checked against a few hard rules (which reject the name outright),
+// then against the deny-list and reserved names (which send the name to Crew for review).
+
+// mentionPattern matches anything that looks like it's trying to mention somebody (or something).
+var mentionPattern = regexp.MustCompile(`@|<[@#:]|<a:`)
+
+// confusables maps characters that look like latin letters or digits onto the character they're imitating.
+// This isn't exhaustive, but covers the usual suspects. (NFKD decomposition takes care of most stylised letters.)
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'з': '3', 'і': 'i', 'ї': 'i', 'ј': 'j', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ь': 'b',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u',
+	'χ': 'x', 'ω': 'w',
+	// Latin oddities
+	'ı': 'i', 'ł': 'l', 'ø': 'o', 'đ': 'd', 'ħ': 'h', 'ɡ': 'g', 'ſ': 's',
+	// Digits & symbols standing in for letters
+	'0': 'o', '1': 'l', '|': 'l', '$': 's',
+}
+
+// cleanTeamName tidies up a requested team name for display:
+// invisible and control characters are removed, and runs of whitespace are collapsed.
+func cleanTeamName(name string) string {
+	name = norm.NFC.String(name)
+	var sb strings.Builder
+	var prev rune
+	for _, r := range name {
+		switch {
+		case r == '\u200d' && unicode.Is(unicode.So, prev):
+			// Zero-width joiners are fine within emoji sequences (e.g. 🧑‍🤝‍🧑), but nowhere else.
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			if !unicode.IsSpace(prev) && sb.Len() > 0 {
+				sb.WriteRune(' ')
+			}
+		case unicode.Is(unicode.Cf, r), unicode.IsControl(r):
+			// Invisible formatting characters (zero-width spaces etc.) - drop them.
+			continue
+		default:
+			sb.WriteRune(r)
+		}
+		prev = r
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// teamNameKey folds a team name down to a form that can be compared with other team names,
+// so that names which merely look alike (different case, accents, look-alike characters, spacing) collide.
+func teamNameKey(name string) string {
+	if isTeam, teamName := getTeamName(name); isTeam {
+		name = teamName
+	}
+	name = norm.NFKD.String(cleanTeamName(name))
+	var sb strings.Builder
+	for _, r := range name {
+		if unicode.Is(unicode.Mn, r) {
+			// Combining marks (i.e. accents, once decomposed).
+			continue
+		}
+		r = unicode.ToLower(r)
+		if folded, ok := confusables[r]; ok {
+			r = folded
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.So, r) {
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() == 0 {
+		// Nothing meaningful left, so fall back to a plain comparison.
+		return strings.ToLower(name)
+	}
+	return sb.String()
+}
+
+// moderateTeamName runs a requested team name through the moderation pipeline.
+// It returns the cleaned-up name, and if the name needs Crew to review it, the reason why.
+// Names that break the hard rules return an error.
+func moderateTeamName(name string) (cleaned string, reviewReason string, err error) {
+	cleaned, err = validateTeamName(name)
+	if err != nil {
+		return "", "", err
+	}
+	key := teamNameKey(cleaned)
+	for _, reserved := range config.RuntimeConfig.Teams.Names.Reserved {
+		if reserved != "" && key == teamNameKey(reserved) {
+			return cleaned, fmt.Sprintf("matches the reserved name \"%s\"", reserved), nil
+		}
+	}
+	for _, denied := range config.RuntimeConfig.Teams.Names.DenyList {
+		if denied != "" && strings.Contains(key, teamNameKey(denied)) {
+			return cleaned, fmt.Sprintf("contains the denied word \"%s\"", denied), nil
+		}
+	}
+	return cleaned, "", nil
+}
+
+// validateTeamName cleans up a team name and checks it against the rules that apply to everybody (including Crew).
+func validateTeamName(name string) (cleaned string, err error) {
+	if mentionPattern.MatchString(name) {
+		return "", ErrTeamNameMention
+	}
+	cleaned = cleanTeamName(name)
+	if isTeam, teamName := getTeamName(cleaned); isTeam {
+		cleaned = teamName
+	}
+	length := utf8.RuneCountInString(cleaned)
+	minLength, maxLength := config.RuntimeConfig.Teams.Names.MinLength, config.RuntimeConfig.Teams.Names.MaxLength
+	if length < minLength || length > maxLength {
+		return "", fmt.Errorf("%w (between %d and %d characters)", ErrTeamNameLength, minLength, maxLength)
+	}
+	return cleaned, nil
+}
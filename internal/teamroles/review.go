@@ -0,0 +1,140 @@
+package teamroles
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file handles the Crew review queue for team names that trip the name filter.
+
+const (
+	// The storage bucket that team names awaiting review are kept in.
+	teamNameReviewsBucket = "team_name_reviews"
+
+	teamNameApproveCustomIDPrefix = "bigbot_team_name_approve_"
+	teamNameRejectCustomIDPrefix  = "bigbot_team_name_reject_"
+)
+
+// teamNameReview is a team that somebody wants to create, but whose name needs Crew's approval first.
+type teamNameReview struct {
+	// Who asked for the team.
+	UserID string `json:"user_id"`
+	// The (cleaned up) name of the team.
+	Name string `json:"name"`
+	// Why the name needs reviewing.
+	Reason string `json:"reason"`
+}
+
+// submitTeamNameForReview sends a team name to Crew for review, returning the response for the user.
+func (mod *TeamRoles) submitTeamNameForReview(s *discordgo.Session, u *discordgo.User, name, reason string) (content string, err error) {
+	channelID := config.RuntimeConfig.Teams.Names.ReviewChannelID
+	if channelID == "" {
+		return fmt.Sprintf("⚠️ %s.", ErrTeamNameNotAllowed), nil
+	}
+	reviewID := strconv.FormatInt(time.Now().UnixNano(), 36)
+	err = mod.store.Put(teamNameReviewsBucket, reviewID, teamNameReview{
+		UserID: u.ID,
+		Name:   name,
+		Reason: reason,
+	})
+	if err != nil {
+		return "", err
+	}
+	_, err = s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("🔎 %s would like to create the team **%s**, but it %s.", u.Mention(), name, reason),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Approve",
+						Style:    discordgo.SuccessButton,
+						CustomID: teamNameApproveCustomIDPrefix + reviewID,
+					},
+					discordgo.Button{
+						Label:    "Reject",
+						Style:    discordgo.DangerButton,
+						CustomID: teamNameRejectCustomIDPrefix + reviewID,
+					},
+				},
+			},
+		},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		return "", err
+	}
+	return "🔎 That team name needs to be checked by Crew first. We'll let you know when it's been approved.", nil
+}
+
+// discordHandleTeamNameReview handles Crew approving or rejecting a team name.
+func (mod *TeamRoles) discordHandleTeamNameReview(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+
+	customID := i.MessageComponentData().CustomID
+	approve := strings.HasPrefix(customID, teamNameApproveCustomIDPrefix)
+	reviewID := strings.TrimPrefix(strings.TrimPrefix(customID, teamNameApproveCustomIDPrefix), teamNameRejectCustomIDPrefix)
+
+	var review teamNameReview
+	err = mod.store.Get(teamNameReviewsBucket, reviewID, &review)
+	if errors.Is(err, storage.ErrNotFound) {
+		return helpers.DiscordUpdateComponentMessage(s, i, "🤷 That team name has already been reviewed.")
+	} else if err != nil {
+		return err
+	}
+	if err = mod.store.Delete(teamNameReviewsBucket, reviewID); err != nil {
+		return err
+	}
+	user, err := s.User(review.UserID)
+	if err != nil {
+		return err
+	}
+
+	var content, notification string
+	if approve {
+		notification = mod.createApprovedTeam(s, i.GuildID, user, review.Name)
+		content = fmt.Sprintf("✅ %s approved %s's team **%s**", helpers.DiscordInteractionUser(i).Mention(), user.Mention(), review.Name)
+	} else {
+		notification = fmt.Sprintf("😔 Crew didn't approve the team name **%s**. Please pick another.", review.Name)
+		content = fmt.Sprintf("❌ %s rejected %s's team **%s**", helpers.DiscordInteractionUser(i).Mention(), user.Mention(), review.Name)
+	}
+	logger.Info("Team name reviewed", slog.String("name", review.Name), slog.Bool("approved", approve), slog.String("reviewer", helpers.DiscordInteractionUser(i).Username))
+
+	// Let the requester know how it went. This is best-effort; they may not accept DMs.
+	if channel, err := s.UserChannelCreate(user.ID); err == nil {
+		if _, err = s.ChannelMessageSend(channel.ID, notification); err != nil {
+			logger.Debug("Unable to notify team name requester", slog.Any("error", err))
+		}
+	}
+	return helpers.DiscordUpdateComponentMessage(s, i, content)
+}
+
+// createApprovedTeam creates a team whose name Crew have approved, returning a message for the requester.
+func (mod *TeamRoles) createApprovedTeam(s *discordgo.Session, guild string, u *discordgo.User, name string) (content string) {
+	// Things may have changed while the name was waiting for review.
+	err := mod.validateUserCanJoinTeam(s, u, guild, "")
+	if err != nil {
+		return fmt.Sprintf("⚠️ Your team **%s** was approved, but couldn't be created: %s", name, err)
+	}
+	role, exists, err := mod.createOrReturnRole(s, guild, name)
+	if err != nil {
+		return fmt.Sprintf("⚠️ Your team **%s** was approved, but couldn't be created: %s", name, err)
+	}
+	if exists {
+		return fmt.Sprintf("⚠️ Your team **%s** was approved, but somebody else created it in the meantime. Try `/team join`!", name)
+	}
+	err = s.GuildMemberRoleAdd(guild, u.ID, role.ID)
+	if err != nil {
+		return fmt.Sprintf("⚠️ Your team **%s** was approved, but couldn't be created: %s", name, err)
+	}
+	return "✅ Crew approved your team name! " + mod.setupNewTeam(s, guild, u, role)
+}
@@ -0,0 +1,145 @@
+package teamroles
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"time"
+)
+
+// teamsBucket is the storage bucket team state is kept in.
+const teamsBucket = "teams"
+
+// team is the state BIGbot persists about a Team role, keyed on the role ID.
+// Every team BIGbot manages has one of these; a role without one is not a team, whatever it's called.
+type team struct {
+	// The user ID of the team's captain (by default, whoever created the team).
+	Captain string `json:"captain,omitempty"`
+	// Whether Crew have locked the team, preventing anybody new from joining.
+	Locked bool `json:"locked"`
+	// Whether new members need the captain's approval to join.
+	InviteOnly bool `json:"invite_only"`
+	// The user IDs of everybody waiting on the captain to approve their join request.
+	JoinRequests []string `json:"join_requests,omitempty"`
+	// The team's private channels (if they were created).
+	TextChannel  string `json:"text_channel,omitempty"`
+	VoiceChannel string `json:"voice_channel,omitempty"`
+	// When something last happened in the team (members joining or leaving, or chatting in its channel).
+	LastActive time.Time `json:"last_active"`
+	// Whether the team has been reported to Crew as empty or stale, and is awaiting their decision.
+	Reported bool `json:"reported,omitempty"`
+}
+
+// isTeam returns whether the given role is one of our teams.
+func (mod *TeamRoles) isTeam(roleID string) bool {
+	return mod.store.Has(teamsBucket, roleID)
+}
+
+// IsTeam returns whether the given role is one of our teams.
+func (mod *TeamRoles) IsTeam(roleID string) bool {
+	return mod.isTeam(roleID)
+}
+
+// TeamCaptain returns the user ID of the given team's captain, or an empty string if it doesn't have one.
+func (mod *TeamRoles) TeamCaptain(roleID string) (string, error) {
+	t, err := mod.getTeam(roleID)
+	return t.Captain, err
+}
+
+// TeamName returns the display name of the team with the given role.
+func (mod *TeamRoles) TeamName(role *discordgo.Role) string {
+	return teamDisplayName(role.Name)
+}
+
+// teamRoles returns the role of every team, served from the State cache.
+// Teams whose role has gone missing are skipped.
+func (mod *TeamRoles) teamRoles(s *discordgo.Session, guild string) (roles []*discordgo.Role) {
+	for _, roleID := range mod.store.Keys(teamsBucket) {
+		role, err := s.State.Role(guild, roleID)
+		if err != nil {
+			continue
+		}
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// adoptTeamRoles brings storage in line with the guild's roles.
+// Teams used to be tracked by name alone, so any role named like a team that we don't know about yet is adopted,
+// and teams whose role has since been deleted are forgotten.
+// Adopted teams count as active from now, so that cleanup doesn't mistake every long-standing team for a stale one.
+func (mod *TeamRoles) adoptTeamRoles(s *discordgo.Session, guild string) error {
+	roles, err := s.GuildRoles(guild)
+	if err != nil {
+		return err
+	}
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	exists := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		exists[role.ID] = true
+		if isTeam, _ := getTeamName(role.Name); !isTeam || mod.isTeam(role.ID) {
+			continue
+		}
+		logger.Info("Adopting existing team role", slog.String("role", role.Name), slog.String("id", role.ID))
+		err = mod.saveTeam(role.ID, team{LastActive: time.Now()})
+		if err != nil {
+			return err
+		}
+	}
+	for _, roleID := range mod.store.Keys(teamsBucket) {
+		if exists[roleID] {
+			// Teams adopted by older versions of BIGbot have no activity recorded yet.
+			t, err := mod.getTeam(roleID)
+			if err != nil {
+				return err
+			}
+			if t.LastActive.IsZero() {
+				t.LastActive = time.Now()
+				if err = mod.saveTeam(roleID, t); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		logger.Info("Forgetting team whose role no longer exists", slog.String("id", roleID))
+		err = mod.deleteTeam(roleID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getTeam fetches the stored state for the given team role.
+// Teams that BIGbot has no state for yet return the default state.
+func (mod *TeamRoles) getTeam(roleID string) (t team, err error) {
+	err = mod.store.Get(teamsBucket, roleID, &t)
+	if errors.Is(err, storage.ErrNotFound) {
+		return team{}, nil
+	}
+	return t, err
+}
+
+// saveTeam stores the state for the given team role.
+// mod.mtx MUST be held from reading the team to saving it, so that concurrent changes to it aren't lost.
+func (mod *TeamRoles) saveTeam(roleID string, t team) error {
+	return mod.store.Put(teamsBucket, roleID, t)
+}
+
+func (mod *TeamRoles) deleteTeam(roleID string) error {
+	return mod.store.Delete(teamsBucket, roleID)
+}
+
+// validateTeamIsOpen checks that new members are currently allowed to join the given team.
+func (mod *TeamRoles) validateTeamIsOpen(role *discordgo.Role) error {
+	t, err := mod.getTeam(role.ID)
+	if err != nil {
+		return err
+	}
+	if t.Locked {
+		return ErrTeamLocked
+	}
+	return nil
+}
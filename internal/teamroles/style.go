@@ -0,0 +1,193 @@
+package teamroles
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file handles teams customising their role's colour and icon.
+
+const (
+	// The colour new teams are given.
+	defaultTeamColour = 0x7E8186
+	// How close (as a distance in RGB space) a team colour may get to a protected role's colour.
+	colourClashDistance = 60
+	// Discord's feature flag for guilds that can give roles icons.
+	guildFeatureRoleIcons = "ROLE_ICONS"
+)
+
+// teamColour is one of the colours teams can pick from.
+type teamColour struct {
+	Name  string
+	Value int
+}
+
+// teamPalette is the curated list of colours teams can pick from.
+// These are chosen to be readable on both light and dark themes, and to stay away from the usual Crew/staff colours.
+var teamPalette = []teamColour{
+	{"Grey", defaultTeamColour},
+	{"Cherry", 0xC2185B},
+	{"Tangerine", 0xF57C00},
+	{"Sunflower", 0xFBC02D},
+	{"Lime", 0x9CCC65},
+	{"Forest", 0x2E7D32},
+	{"Teal", 0x00897B},
+	{"Sky", 0x4FC3F7},
+	{"Ocean", 0x1565C0},
+	{"Indigo", 0x5C6BC0},
+	{"Lavender", 0xB39DDB},
+	{"Plum", 0x8E24AA},
+	{"Bubblegum", 0xF48FB1},
+	{"Chocolate", 0x795548},
+	{"Sand", 0xD7CCC8},
+	{"Slate", 0x546E7A},
+}
+
+// teamPaletteChoices returns the palette as command option choices.
+func teamPaletteChoices() (choices []*discordgo.ApplicationCommandOptionChoice) {
+	for _, colour := range teamPalette {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  colour.Name,
+			Value: colour.Value,
+		})
+	}
+	return choices
+}
+
+// colourDistance returns how far apart two colours are in RGB space.
+// This uses the "redmean" approximation, which is a little closer to how people see colour than plain RGB distance.
+func colourDistance(a, b int) int {
+	r1, g1, b1 := (a>>16)&0xFF, (a>>8)&0xFF, a&0xFF
+	r2, g2, b2 := (b>>16)&0xFF, (b>>8)&0xFF, b&0xFF
+	rmean := (r1 + r2) / 2
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	// Scaled down by 256 to stay in integers; the square root is skipped by comparing against the squared threshold.
+	return ((512+rmean)*dr*dr)>>8 + 4*dg*dg + ((767-rmean)*db*db)>>8
+}
+
+// colourClashes returns whether two colours are too close to tell apart at a glance.
+func colourClashes(a, b int) bool {
+	return colourDistance(a, b) < colourClashDistance*colourClashDistance
+}
+
+// protectedRoles returns the roles that teams may not imitate the colour of: Crew, plus anything configured.
+func protectedRoles(s *discordgo.Session, guild string) (roles []*discordgo.Role) {
+	ids := slices.Clone(config.RuntimeConfig.Teams.Style.ProtectedRoles)
+	if crewRole := config.RuntimeConfig.Discord.Permissions.CrewRole; crewRole != "" {
+		ids = append(ids, crewRole)
+	}
+	for _, id := range ids {
+		role, err := s.State.Role(guild, id)
+		// Roles without a colour don't have anything to clash with.
+		if err != nil || role.Color == 0 {
+			continue
+		}
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// validateTeamIcon checks that the given icon is a single unicode emoji, returning it tidied up.
+func validateTeamIcon(icon string) (string, error) {
+	icon = strings.TrimSpace(icon)
+	if icon == "" || utf8.RuneCountInString(icon) > 16 {
+		return "", ErrTeamIconInvalid
+	}
+	var bases, regionalIndicators int
+	var prev rune
+	for _, r := range icon {
+		switch {
+		case r >= 0x1F1E6 && r <= 0x1F1FF:
+			// Flags are made of a pair of regional indicators.
+			regionalIndicators++
+		case unicode.Is(unicode.So, r):
+			// Emoji joined onto the previous one (e.g. 🧑‍🤝‍🧑) are part of the same emoji.
+			if prev != '\u200d' {
+				bases++
+			}
+		case r == '\u200d', r == '\ufe0f', r == '\u20e3':
+			// Zero-width joiners, emoji presentation selectors and keycaps.
+		case r >= 0x1F3FB && r <= 0x1F3FF:
+			// Skin tones.
+		case r >= 0xE0020 && r <= 0xE007F:
+			// Tags (used in subdivision flags, e.g. 🏴󠁧󠁢󠁥󠁮󠁧󠁿).
+		case (r >= '0' && r <= '9') || r == '#' || r == '*':
+			// Keycap bases (e.g. 1️⃣), which must be followed by the keycap.
+			if !strings.ContainsRune(icon, '\u20e3') {
+				return "", ErrTeamIconInvalid
+			}
+			bases++
+		default:
+			return "", ErrTeamIconInvalid
+		}
+		prev = r
+	}
+	if bases+regionalIndicators/2 != 1 || regionalIndicators%2 != 0 || (bases > 0 && regionalIndicators > 0) {
+		return "", ErrTeamIconInvalid
+	}
+	return icon, nil
+}
+
+// setStyle sets the colour and/or icon of the given team.
+// Crew may pick colours that clash with protected roles; nobody else can.
+func (mod *TeamRoles) setStyle(s *discordgo.Session, guild string, u *discordgo.User, role *discordgo.Role, colour *int, icon *string) (content string, err error) {
+	if colour == nil && icon == nil {
+		return "🤔 Please pick a colour or an icon.", nil
+	}
+	t, err := mod.getTeam(role.ID)
+	if err != nil {
+		return "", err
+	}
+	if err = mod.validateUserCanCaptain(s, guild, u, t); err != nil {
+		return fmt.Sprintf("⚠️ %s", err), nil
+	}
+	isCrew, err := helpers.UserIsCrew(s, guild, u)
+	if err != nil {
+		return "", err
+	}
+
+	params := &discordgo.RoleParams{}
+	var changes []string
+	if colour != nil {
+		if !isCrew {
+			for _, protected := range protectedRoles(s, guild) {
+				if colourClashes(*colour, protected.Color) {
+					return fmt.Sprintf("⚠️ That colour is too close to %s's. Please pick another.", protected.Name), nil
+				}
+			}
+		}
+		params.Color = colour
+		changes = append(changes, fmt.Sprintf("colour to #%06X", *colour))
+	}
+	if icon != nil {
+		g, err := s.State.Guild(guild)
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(g.Features, guildFeatureRoleIcons) && g.PremiumTier < discordgo.PremiumTier2 {
+			return fmt.Sprintf("⚠️ %s.", ErrRoleIconsUnavailable), nil
+		}
+		value := ""
+		if !strings.EqualFold(strings.TrimSpace(*icon), "none") {
+			value, err = validateTeamIcon(*icon)
+			if err != nil {
+				return fmt.Sprintf("⚠️ %s.", err), nil
+			}
+			changes = append(changes, "icon to "+value)
+		} else {
+			changes = append(changes, "icon to nothing")
+		}
+		params.UnicodeEmoji = &value
+	}
+	_, err = s.GuildRoleEdit(guild, role.ID, params)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🎨 Set the %s for %s", strings.Join(changes, " and "), role.Name), nil
+}
@@ -0,0 +1,11 @@
+package tickets
+
+import "errors"
+
+var ErrHelpDeskUnavailable = errors.New("The help desk isn't set up yet. Please find a member of Crew")
+var ErrUnknownCategory = errors.New("That isn't a help desk category")
+var ErrTooManyOpenTickets = errors.New("You already have too many open tickets. Please wait for Crew to get back to you")
+var ErrNotTicket = errors.New("This isn't a help desk ticket")
+var ErrTicketClosed = errors.New("This ticket has already been closed")
+var ErrNotCrewOrRequester = errors.New("Only Crew or the person who opened this ticket can close it")
+var ErrBridgeUnavailable = errors.New("Event Bridge is not available")
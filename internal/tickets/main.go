@@ -0,0 +1,595 @@
+package tickets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/config"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	newTicketCustomIDPrefix = "bigbot_helpdesk_new_"
+	claimTicketCustomID     = "bigbot_helpdesk_claim"
+	closeTicketCustomID     = "bigbot_helpdesk_close"
+)
+
+// logger stores the module's logger instance.
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+type Tickets struct {
+	discord *discordgo.Session
+
+	// Persistent storage for tickets.
+	store *storage.Store
+
+	// Held while changing tickets, so that (for example) two people can't claim the same ticket at once.
+	mtx sync.Mutex
+}
+
+var commands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "help-desk",
+		Description: "🛟 Ask Crew for help with a problem.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "category",
+				Description: "What kind of problem you have.",
+				Required:    true,
+				Choices:     categoryChoices(),
+			},
+		},
+	},
+	{
+		Name:                     "tickets",
+		Description:              "🛟🛠️ Manage help desk tickets (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "admin",
+				Description: "🛟🛠️ Help desk administration.",
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "list",
+						Description: "📋 List the open tickets.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+					},
+					{
+						Name:        "publish",
+						Description: "📺 Re-send the open ticket count to NodeCG.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+					},
+				},
+			},
+		},
+	},
+}
+
+// categoryChoices returns the help desk categories as command option choices.
+func categoryChoices() (choices []*discordgo.ApplicationCommandOptionChoice) {
+	for _, c := range categories {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  c.Emoji + " " + c.Name,
+			Value: c.Value,
+		})
+	}
+	return choices
+}
+
+func New(discord *discordgo.Session, store *storage.Store) (mod *Tickets, err error) {
+	return &Tickets{
+		discord: discord,
+		store:   store,
+	}, nil
+}
+
+func (mod *Tickets) SetLogger(log *slog.Logger) {
+	logger = log
+}
+
+func (mod *Tickets) DiscordCommands() ([]*discordgo.ApplicationCommand, error) {
+	return commands, nil
+}
+
+func (mod *Tickets) Start(ctx context.Context) (err error) {
+	// Make sure the crew dashboard is up to date with anything that happened while we were away.
+	mod.publish()
+	// This module simply registers handlers, and does not need to run continuously (so we don't need the context)
+	return ctx.Err()
+}
+
+func (mod *Tickets) DiscordHandleMessage(_ *discordgo.Session, _ *discordgo.MessageCreate) (err error) {
+	return nil
+}
+
+func (mod *Tickets) DiscordHandleMessageUpdate(_ *discordgo.Session, _ *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *Tickets) DiscordHandleMessageDelete(_ *discordgo.Session, _ *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
+func (mod *Tickets) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		switch i.ApplicationCommandData().Name {
+		case "help-desk":
+			return true, mod.discordHandleHelpDesk(s, i)
+		case "tickets":
+			return true, mod.discordHandleAdminCommand(s, i)
+		}
+	case discordgo.InteractionModalSubmit:
+		if strings.HasPrefix(i.ModalSubmitData().CustomID, newTicketCustomIDPrefix) {
+			return true, mod.discordHandleNewTicket(s, i)
+		}
+	case discordgo.InteractionMessageComponent:
+		switch i.MessageComponentData().CustomID {
+		case claimTicketCustomID:
+			return true, mod.discordHandleClaim(s, i)
+		case closeTicketCustomID:
+			return true, mod.discordHandleClose(s, i)
+		}
+	}
+	return false, nil
+}
+
+// discordHandleHelpDesk pops up a modal asking the attendee to describe their problem.
+func (mod *Tickets) discordHandleHelpDesk(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	if config.RuntimeConfig.Tickets.ChannelID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", ErrHelpDeskUnavailable))
+	}
+	c, err := getCategory(i.ApplicationCommandData().Options[0].StringValue())
+	if err != nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", err))
+	}
+	// Check this before they go to the effort of writing everything out.
+	if err = mod.validateUserCanOpenTicket(i.Member.User.ID); err != nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", err))
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: newTicketCustomIDPrefix + c.Value,
+			Title:    fmt.Sprintf("%s Help desk: %s", c.Emoji, c.Name),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "summary",
+							Label:       "What's the problem?",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "e.g. My network port isn't working",
+							Required:    true,
+							MinLength:   1,
+							MaxLength:   80,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "description",
+							Label:       "Anything else we should know? Where are you?",
+							Style:       discordgo.TextInputParagraph,
+							Placeholder: "e.g. I'm in seat B12, and it stopped working after I moved my PC",
+							Required:    false,
+							MaxLength:   1000,
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+// validateUserCanOpenTicket checks that the given user doesn't have too many tickets open already.
+func (mod *Tickets) validateUserCanOpenTicket(userID string) error {
+	tickets, err := mod.listTickets()
+	if err != nil {
+		return err
+	}
+	if maxOpen := config.RuntimeConfig.Tickets.MaxOpen; maxOpen > 0 && userOpenTickets(tickets, userID) >= maxOpen {
+		return ErrTooManyOpenTickets
+	}
+	return nil
+}
+
+// discordHandleNewTicket opens a ticket from the details the attendee gave in the modal.
+func (mod *Tickets) discordHandleNewTicket(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	data := i.ModalSubmitData()
+	c, err := getCategory(strings.TrimPrefix(data.CustomID, newTicketCustomIDPrefix))
+	if err != nil {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", err))
+	}
+	// Creating the thread can take a moment, so let the client know we're working on it.
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+
+	t := ticket{
+		Category: c.Value,
+		UserID:   i.Member.User.ID,
+	}
+	for _, row := range data.Components {
+		for _, component := range row.(*discordgo.ActionsRow).Components {
+			input := component.(*discordgo.TextInput)
+			switch input.CustomID {
+			case "summary":
+				t.Summary = strings.TrimSpace(input.Value)
+			case "description":
+				t.Description = strings.TrimSpace(input.Value)
+			}
+		}
+	}
+
+	content, err := mod.openTicket(s, i.Member.User, t)
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		logger.Error("Unable to open ticket", slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		content = helpers.DiscordErrorContent(s, i, err)
+	}
+	if _, err = helpers.DiscordInteractionFollowupMessage(s, i, content); err != nil {
+		logger.Error("Unable to send ticket response", slog.Any("error", err))
+	}
+	return nil
+}
+
+// openTicket creates a private thread for the given ticket, and lets Crew know about it.
+func (mod *Tickets) openTicket(s *discordgo.Session, u *discordgo.User, t ticket) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	// Somebody may have opened a few tickets at once in different modals.
+	if err = mod.validateUserCanOpenTicket(u.ID); errors.Is(err, ErrTooManyOpenTickets) {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	} else if err != nil {
+		return "", err
+	}
+
+	t.Number = len(mod.store.Keys(ticketsBucket)) + 1
+	t.OpenedAt = time.Now()
+	c, _ := getCategory(t.Category)
+	thread, err := s.ThreadStartComplex(config.RuntimeConfig.Tickets.ChannelID, &discordgo.ThreadStart{
+		Name:                threadName(t, c),
+		AutoArchiveDuration: 10080,
+		Type:                discordgo.ChannelTypeGuildPrivateThread,
+		Invitable:           false,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = s.ThreadMemberAdd(thread.ID, u.ID); err != nil {
+		return "", err
+	}
+
+	// Mentioning people in a private thread adds them to it, which is how Crew get to see the ticket.
+	mentions := u.Mention()
+	allowedMentions := &discordgo.MessageAllowedMentions{Users: []string{u.ID}}
+	if crewRole := config.RuntimeConfig.Discord.Permissions.CrewRole; crewRole != "" {
+		mentions = fmt.Sprintf("<@&%s> %s", crewRole, mentions)
+		allowedMentions.Roles = []string{crewRole}
+	}
+	msg, err := s.ChannelMessageSendComplex(thread.ID, &discordgo.MessageSend{
+		Content:         mentions,
+		Embeds:          []*discordgo.MessageEmbed{ticketEmbed(t)},
+		Components:      ticketComponents(t),
+		AllowedMentions: allowedMentions,
+	})
+	if err != nil {
+		return "", err
+	}
+	t.MessageID = msg.ID
+	if err = mod.saveTicket(thread.ID, t); err != nil {
+		return "", err
+	}
+	logger.Info("Help desk ticket opened", slog.Int("number", t.Number), slog.String("category", t.Category), slog.String("user", u.Username))
+	go mod.publish()
+	return fmt.Sprintf("🛟 Opened ticket #%d: <#%s>. Crew will be with you as soon as they can!", t.Number, thread.ID), nil
+}
+
+// discordHandleClaim handles a member of Crew claiming a ticket.
+func (mod *Tickets) discordHandleClaim(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTicket(i.ChannelID)
+	if err != nil {
+		return mod.ticketErrorResponse(s, i, err)
+	}
+	if !t.Open() {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", ErrTicketClosed))
+	}
+	if t.ClaimedBy == i.Member.User.ID {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "🤷 You've already claimed this ticket.")
+	}
+	previous := t.ClaimedBy
+	t.ClaimedBy = i.Member.User.ID
+	t.ClaimedAt = time.Now()
+	if err = mod.saveTicket(i.ChannelID, t); err != nil {
+		return err
+	}
+	logger.Info("Help desk ticket claimed", slog.Int("number", t.Number), slog.String("user", i.Member.User.Username))
+	go mod.publish()
+
+	if err = updateTicketMessage(s, i, t); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("🙋 %s is looking into this.", i.Member.User.Mention())
+	if previous != "" {
+		content = fmt.Sprintf("🙋 %s has taken this over from <@%s>.", i.Member.User.Mention(), previous)
+	}
+	_, err = s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// discordHandleClose handles Crew (or the person who opened it) closing a ticket.
+func (mod *Tickets) discordHandleClose(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTicket(i.ChannelID)
+	if err != nil {
+		return mod.ticketErrorResponse(s, i, err)
+	}
+	if t.UserID != i.Member.User.ID {
+		isCrew, err := helpers.UserIsCrew(s, i.GuildID, i.Member.User)
+		if err != nil {
+			return err
+		}
+		if !isCrew {
+			return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("🚫 %s.", ErrNotCrewOrRequester))
+		}
+	}
+	if !t.Open() {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", ErrTicketClosed))
+	}
+	t.ClosedBy = i.Member.User.ID
+	t.ClosedAt = time.Now()
+	if err = mod.saveTicket(i.ChannelID, t); err != nil {
+		return err
+	}
+	logger.Info("Help desk ticket closed", slog.Int("number", t.Number), slog.String("user", i.Member.User.Username))
+	go mod.publish()
+
+	if err = updateTicketMessage(s, i, t); err != nil {
+		return err
+	}
+	_, err = s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("🔒 %s closed this ticket. If you need more help, please use `/help-desk` again.", i.Member.User.Mention()),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		return err
+	}
+	// Archive and lock the thread, so that it drops out of everybody's list.
+	archived, locked := true, true
+	_, err = s.ChannelEditComplex(i.ChannelID, &discordgo.ChannelEdit{
+		Archived: &archived,
+		Locked:   &locked,
+	})
+	return err
+}
+
+// ticketErrorResponse responds to a button on something that isn't a ticket (or a ticket we've lost track of).
+func (mod *Tickets) ticketErrorResponse(s *discordgo.Session, i *discordgo.InteractionCreate, err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, fmt.Sprintf("⚠️ %s.", ErrNotTicket))
+	}
+	return err
+}
+
+// updateTicketMessage updates the message a button was clicked on to show the current state of the ticket.
+func updateTicketMessage(s *discordgo.Session, i *discordgo.InteractionCreate, t ticket) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{ticketEmbed(t)},
+			Components: ticketComponents(t),
+		},
+	})
+}
+
+// threadName returns the name of the given ticket's thread. Discord limits thread names to 100 characters.
+func threadName(t ticket, c category) string {
+	name := []rune(fmt.Sprintf("#%d %s %s", t.Number, c.Emoji, t.Summary))
+	if len(name) > 100 {
+		name = append(name[:99], '…')
+	}
+	return string(name)
+}
+
+// ticketEmbed describes the given ticket.
+func ticketEmbed(t ticket) *discordgo.MessageEmbed {
+	c, err := getCategory(t.Category)
+	if err != nil {
+		c = category{Name: t.Category, Emoji: "❓"}
+	}
+	status, colour := "🟡 Waiting for Crew", 0xFBC02D
+	switch {
+	case !t.Open():
+		status, colour = fmt.Sprintf("🔒 Closed by <@%s> <t:%d:R>", t.ClosedBy, t.ClosedAt.Unix()), 0x7E8186
+	case t.ClaimedBy != "":
+		status, colour = fmt.Sprintf("🙋 Claimed by <@%s> <t:%d:R>", t.ClaimedBy, t.ClaimedAt.Unix()), 0x1565C0
+	}
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Category", Value: c.Emoji + " " + c.Name, Inline: true},
+		{Name: "Opened by", Value: fmt.Sprintf("<@%s> <t:%d:R>", t.UserID, t.OpenedAt.Unix()), Inline: true},
+		{Name: "Status", Value: status},
+	}
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Ticket #%d: %s", t.Number, t.Summary),
+		Description: t.Description,
+		Color:       colour,
+		Fields:      fields,
+	}
+}
+
+// ticketComponents returns the buttons for the given ticket. Closed tickets have none.
+func ticketComponents(t ticket) []discordgo.MessageComponent {
+	if !t.Open() {
+		return []discordgo.MessageComponent{}
+	}
+	claimLabel := "Claim"
+	if t.ClaimedBy != "" {
+		claimLabel = "Take over"
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    claimLabel,
+					Style:    discordgo.PrimaryButton,
+					CustomID: claimTicketCustomID,
+				},
+				discordgo.Button{
+					Label:    "Close",
+					Style:    discordgo.SecondaryButton,
+					CustomID: closeTicketCustomID,
+				},
+			},
+		},
+	}
+}
+
+func (mod *Tickets) discordHandleAdminCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 || options[0].Name != "admin" || len(options[0].Options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+	var content string
+	subcommand := options[0].Options[0]
+	switch subcommand.Name {
+	case "list":
+		content, err = mod.adminList()
+	case "publish":
+		content, err = mod.adminPublish()
+	default:
+		content = "😶 Please use a sub-command."
+	}
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		logger.Error("Help desk administration failed", slog.String("action", subcommand.Name), slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		content = helpers.DiscordErrorContent(s, i, err)
+	}
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logger.Error("Unable to send help desk administration response", slog.Any("error", err))
+	}
+	return nil
+}
+
+func (mod *Tickets) adminList() (content string, err error) {
+	tickets, err := mod.listTickets()
+	if err != nil {
+		return "", err
+	}
+	type openTicket struct {
+		threadID string
+		ticket
+	}
+	var open []openTicket
+	for threadID, t := range tickets {
+		if t.Open() {
+			open = append(open, openTicket{threadID, t})
+		}
+	}
+	if len(open) == 0 {
+		return "🎉 There are no open tickets.", nil
+	}
+	sort.Slice(open, func(a, b int) bool {
+		return open[a].Number < open[b].Number
+	})
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🛟 %d open %s:\n", len(open), helpers.Pluralise(len(open), "ticket", "tickets")))
+	for _, t := range open {
+		c, _ := getCategory(t.Category)
+		claimed := "unclaimed"
+		if t.ClaimedBy != "" {
+			claimed = fmt.Sprintf("claimed by <@%s>", t.ClaimedBy)
+		}
+		line := fmt.Sprintf("- %s <#%s> (opened <t:%d:R>, %s)\n", c.Emoji, t.threadID, t.OpenedAt.Unix(), claimed)
+		// Discord limits messages to 2000 characters.
+		if sb.Len()+len(line) > 1900 {
+			sb.WriteString("…and more.")
+			break
+		}
+		sb.WriteString(line)
+	}
+	return sb.String(), nil
+}
+
+func (mod *Tickets) sendReplicant() error {
+	if !bridge_wan.BridgeIsAvailable() {
+		return ErrBridgeUnavailable
+	}
+	tickets, err := mod.listTickets()
+	if err != nil {
+		return err
+	}
+	open, unclaimed := ticketCounts(tickets)
+	return bridge_wan.ReplicantSet(ngtbg.ReplicantHelpDesk, ngtbg.NodeCGReplicantDataHelpDesk{
+		Open:      open,
+		Unclaimed: unclaimed,
+	})
+}
+
+// publish sends the open ticket count to NodeCG, if enabled and the bridge is available.
+// This is best-effort; Crew can always re-send with /tickets admin publish.
+func (mod *Tickets) publish() {
+	if !config.RuntimeConfig.Tickets.Publish {
+		return
+	}
+	err := mod.sendReplicant()
+	if errors.Is(err, ErrBridgeUnavailable) {
+		logger.Debug("Event Bridge not available, not publishing help desk tickets")
+	} else if err != nil {
+		logger.Error("Unable to publish help desk tickets to NodeCG", slog.Any("error", err))
+	}
+}
+
+func (mod *Tickets) adminPublish() (content string, err error) {
+	err = mod.sendReplicant()
+	if errors.Is(err, ErrBridgeUnavailable) {
+		return "👻 **Event Bridge is not available**", nil
+	} else if err != nil {
+		return "", err
+	}
+	return "📺 Sent the open ticket count to NodeCG.", nil
+}
+
+var defaultCrewCommandPermissions int64 = discordgo.PermissionAdministrator
+var defaultCrewCommandDMPermissions = false
@@ -0,0 +1,84 @@
+package tickets
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var testOpenedAt = time.Date(2024, 8, 23, 18, 0, 0, 0, time.UTC)
+
+func TestGetCategory(t *testing.T) {
+	for _, choice := range categoryChoices() {
+		if _, err := getCategory(choice.Value.(string)); err != nil {
+			t.Errorf("Category choice %s should be valid, got %v", choice.Name, err)
+		}
+	}
+	if _, err := getCategory("catering"); !errors.Is(err, ErrUnknownCategory) {
+		t.Errorf("Unknown category should be rejected, got %v", err)
+	}
+}
+
+func TestThreadName(t *testing.T) {
+	c, _ := getCategory("network")
+	name := threadName(ticket{Number: 12, Summary: "Port B12 is dead"}, c)
+	if name != "#12 🌐 Port B12 is dead" {
+		t.Errorf("Unexpected thread name %q", name)
+	}
+	name = threadName(ticket{Number: 12, Summary: strings.Repeat("ä", 120)}, c)
+	if utf8.RuneCountInString(name) != 100 || !strings.HasSuffix(name, "…") {
+		t.Errorf("Long thread names should be cut to 100 characters, got %d: %q", utf8.RuneCountInString(name), name)
+	}
+}
+
+func TestTicketCounts(t *testing.T) {
+	tickets := map[string]ticket{
+		"1": {UserID: "10", OpenedAt: testOpenedAt},
+		"2": {UserID: "10", OpenedAt: testOpenedAt, ClaimedBy: "20"},
+		"3": {UserID: "10", OpenedAt: testOpenedAt, ClosedBy: "20", ClosedAt: testOpenedAt.Add(time.Hour)},
+		"4": {UserID: "11", OpenedAt: testOpenedAt},
+	}
+	open, unclaimed := ticketCounts(tickets)
+	if open != 3 || unclaimed != 2 {
+		t.Errorf("Expected 3 open and 2 unclaimed tickets, got %d and %d", open, unclaimed)
+	}
+	if count := userOpenTickets(tickets, "10"); count != 2 {
+		t.Errorf("Expected user to have 2 open tickets, got %d", count)
+	}
+	if count := userOpenTickets(tickets, "12"); count != 0 {
+		t.Errorf("Expected user to have no open tickets, got %d", count)
+	}
+}
+
+func TestTicketMessage(t *testing.T) {
+	tk := ticket{Number: 1, Category: "power", Summary: "No power", UserID: "10", OpenedAt: testOpenedAt}
+
+	embed := ticketEmbed(tk)
+	if embed.Title != "Ticket #1: No power" || !strings.Contains(embed.Fields[2].Value, "Waiting") {
+		t.Errorf("Unexpected embed for new ticket: %q, %q", embed.Title, embed.Fields[2].Value)
+	}
+	buttons := ticketComponents(tk)[0].(discordgo.ActionsRow).Components
+	if buttons[0].(discordgo.Button).Label != "Claim" {
+		t.Errorf("New ticket should have a Claim button, got %q", buttons[0].(discordgo.Button).Label)
+	}
+
+	tk.ClaimedBy, tk.ClaimedAt = "20", testOpenedAt.Add(time.Minute)
+	if status := ticketEmbed(tk).Fields[2].Value; !strings.Contains(status, "<@20>") {
+		t.Errorf("Claimed ticket should say who claimed it, got %q", status)
+	}
+	buttons = ticketComponents(tk)[0].(discordgo.ActionsRow).Components
+	if buttons[0].(discordgo.Button).Label != "Take over" {
+		t.Errorf("Claimed ticket should have a Take over button, got %q", buttons[0].(discordgo.Button).Label)
+	}
+
+	tk.ClosedBy, tk.ClosedAt = "10", testOpenedAt.Add(time.Hour)
+	if status := ticketEmbed(tk).Fields[2].Value; !strings.Contains(status, "Closed by <@10>") {
+		t.Errorf("Closed ticket should say who closed it, got %q", status)
+	}
+	if components := ticketComponents(tk); len(components) != 0 {
+		t.Errorf("Closed ticket shouldn't have any buttons, got %d", len(components))
+	}
+}
@@ -0,0 +1,115 @@
+package tickets
+
+import (
+	"time"
+)
+
+const (
+	// The storage bucket tickets are kept in, keyed on the ID of the ticket's thread.
+	ticketsBucket = "helpdesk_tickets"
+)
+
+// category is a kind of problem attendees can ask the help desk about.
+type category struct {
+	// The value used for the category in commands and storage.
+	Value string
+	// The name shown to people.
+	Name string
+	// An emoji to show alongside the name.
+	Emoji string
+}
+
+// categories are the kinds of problem attendees can ask the help desk about.
+var categories = []category{
+	{"network", "Network", "🌐"},
+	{"power", "Power", "🔌"},
+	{"hardware", "Hardware", "🖥️"},
+	{"lost-property", "Lost property", "🧳"},
+	{"seating", "Seating", "🪑"},
+	{"other", "Something else", "❓"},
+}
+
+// getCategory returns the category with the given value.
+func getCategory(value string) (category, error) {
+	for _, c := range categories {
+		if c.Value == value {
+			return c, nil
+		}
+	}
+	return category{}, ErrUnknownCategory
+}
+
+// ticket is one request for help.
+type ticket struct {
+	// The ticket's number, as shown to people.
+	Number int `json:"number"`
+	// The value of the ticket's category.
+	Category string `json:"category"`
+	// A short summary of the problem, and any more detail the attendee gave.
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	// Who opened the ticket, and when.
+	UserID   string    `json:"user_id"`
+	OpenedAt time.Time `json:"opened_at"`
+	// The ID of the message in the ticket's thread with the claim/close buttons on.
+	MessageID string `json:"message_id"`
+	// The member of Crew dealing with the ticket, if anybody has claimed it.
+	ClaimedBy string    `json:"claimed_by,omitempty"`
+	ClaimedAt time.Time `json:"claimed_at,omitempty"`
+	// Who closed the ticket and when, if it has been closed.
+	ClosedBy string    `json:"closed_by,omitempty"`
+	ClosedAt time.Time `json:"closed_at,omitempty"`
+}
+
+// Open returns whether the ticket is still waiting to be dealt with.
+func (t ticket) Open() bool {
+	return t.ClosedAt.IsZero()
+}
+
+// getTicket fetches the ticket with the given thread ID. Returns storage.ErrNotFound if there is no such ticket.
+func (mod *Tickets) getTicket(threadID string) (t ticket, err error) {
+	err = mod.store.Get(ticketsBucket, threadID, &t)
+	return t, err
+}
+
+func (mod *Tickets) saveTicket(threadID string, t ticket) error {
+	return mod.store.Put(ticketsBucket, threadID, t)
+}
+
+// listTickets returns every ticket, keyed on thread ID.
+func (mod *Tickets) listTickets() (tickets map[string]ticket, err error) {
+	keys := mod.store.Keys(ticketsBucket)
+	tickets = make(map[string]ticket, len(keys))
+	for _, key := range keys {
+		t, err := mod.getTicket(key)
+		if err != nil {
+			return nil, err
+		}
+		tickets[key] = t
+	}
+	return tickets, nil
+}
+
+// ticketCounts returns how many tickets are open, and how many of those nobody has claimed yet.
+func ticketCounts(tickets map[string]ticket) (open, unclaimed int) {
+	for _, t := range tickets {
+		if !t.Open() {
+			continue
+		}
+		open++
+		if t.ClaimedBy == "" {
+			unclaimed++
+		}
+	}
+	return open, unclaimed
+}
+
+// userOpenTickets returns how many open tickets the given user has.
+func userOpenTickets(tickets map[string]ticket, userID string) (count int) {
+	for _, t := range tickets {
+		if t.Open() && t.UserID == userID {
+			count++
+		}
+	}
+	return count
+}
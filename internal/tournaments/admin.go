@@ -0,0 +1,407 @@
+package tournaments
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/avbridge/ngtbg"
+	bridge_wan "github.com/thebiggame/bigbot/internal/bridge-wan"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// This file handles the Crew-only tournament administration commands, exports, and publishing to NodeCG.
+
+var adminCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:                     "tournaments",
+		Description:              "🏆🛠️ Administer LAN tournaments (you must be a crew member)",
+		DefaultMemberPermissions: &defaultCrewCommandPermissions,
+		DMPermission:             &defaultCrewCommandDMPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "admin",
+				Description: "🏆🛠️ Tournament administration.",
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "create",
+						Description: "✨ Create a tournament (registrations start closed).",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "name",
+								Description: "The name of the tournament.",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "min-players",
+								Description: "The smallest roster a team can register with.",
+								Required:    true,
+								MinValue:    &minPlayersMinValue,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "max-players",
+								Description: "The largest roster a team can register with (leave out for no limit).",
+								MinValue:    &minPlayersMinValue,
+							},
+						},
+					},
+					{
+						Name:        "open",
+						Description: "✍️ Open or close registrations for a tournament.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							tournamentOption,
+							{
+								Type:        discordgo.ApplicationCommandOptionBoolean,
+								Name:        "open",
+								Description: "Whether teams can register.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "remove",
+						Description: "👢 Remove a team's registration from a tournament.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							tournamentOption,
+							{
+								Type:        discordgo.ApplicationCommandOptionRole,
+								Name:        "team",
+								Description: "The team to remove.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "delete",
+						Description: "🗑️ Delete a tournament, along with all of its registrations.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							tournamentOption,
+							{
+								Type:        discordgo.ApplicationCommandOptionBoolean,
+								Name:        "confirm",
+								Description: "Set to confirm that you really want to delete the tournament.",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Name:        "export",
+						Description: "📤 Export the registrations for a tournament.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							tournamentOption,
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "format",
+								Description: "The format to export in.",
+								Required:    true,
+								Choices: []*discordgo.ApplicationCommandOptionChoice{
+									{Name: "CSV", Value: "csv"},
+									{Name: "JSON", Value: "json"},
+								},
+							},
+						},
+					},
+					{
+						Name:        "publish",
+						Description: "📺 Send every tournament's registrations to NodeCG.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+					},
+				},
+			},
+		},
+	},
+}
+
+func (mod *Tournaments) discordHandleAdminCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.Interaction.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	if isCrew, err := helpers.DiscordRequireCrew(s, i); !isCrew {
+		return err
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 || options[0].Name != "admin" || len(options[0].Options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+	subcommand := options[0].Options[0]
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subcommand.Options))
+	for _, opt := range subcommand.Options {
+		optionMap[opt.Name] = opt
+	}
+
+	if err = helpers.DiscordDeferEphemeralInteraction(s, i); err != nil {
+		return err
+	}
+
+	var content string
+	var files []*discordgo.File
+	switch subcommand.Name {
+	case "create":
+		var maxPlayers int
+		if opt, ok := optionMap["max-players"]; ok {
+			maxPlayers = int(opt.IntValue())
+		}
+		content, err = mod.adminCreate(optionMap["name"].StringValue(), int(optionMap["min-players"].IntValue()), maxPlayers)
+	case "open":
+		content, err = mod.adminOpen(optionMap["tournament"].StringValue(), optionMap["open"].BoolValue())
+	case "remove":
+		content, err = mod.adminRemove(optionMap["tournament"].StringValue(), optionMap["team"].RoleValue(s, i.GuildID))
+	case "delete":
+		content, err = mod.adminDelete(optionMap["tournament"].StringValue(), optionMap["confirm"].BoolValue())
+	case "export":
+		content, files, err = mod.adminExport(optionMap["tournament"].StringValue(), optionMap["format"].StringValue())
+	case "publish":
+		content, err = mod.adminPublish()
+	default:
+		content = "😶 Please use a sub-command."
+	}
+	if errors.Is(err, ErrUnknownTournament) {
+		content, err = fmt.Sprintf("⚠️ %s.", ErrUnknownTournament), nil
+	}
+	if err != nil {
+		// We've already acknowledged the interaction, so report the problem ourselves.
+		logger.Error("Tournament administration failed", slog.String("action", subcommand.Name), slog.String("user", i.Member.User.Username), slog.Any("error", err))
+		content, files = helpers.DiscordErrorContent(s, i, err), nil
+	} else {
+		logger.Info("Tournament administration performed", slog.String("action", subcommand.Name), slog.String("user", i.Member.User.Username))
+	}
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Files:   files,
+	})
+	if err != nil {
+		logger.Error("Unable to send tournament administration response", slog.Any("error", err))
+	}
+	return nil
+}
+
+func (mod *Tournaments) adminCreate(name string, minPlayers, maxPlayers int) (content string, err error) {
+	name = strings.TrimSpace(name)
+	if maxPlayers != 0 && maxPlayers < minPlayers {
+		return "⚠️ The maximum number of players can't be smaller than the minimum.", nil
+	}
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	_, err = mod.getTournament(name)
+	if err == nil {
+		return fmt.Sprintf("⚠️ %s.", ErrTournamentExists), nil
+	} else if !errors.Is(err, ErrUnknownTournament) {
+		return "", err
+	}
+	t := tournament{
+		Name:       name,
+		MinPlayers: minPlayers,
+		MaxPlayers: maxPlayers,
+	}
+	err = mod.saveTournament(t)
+	if err != nil {
+		return "", err
+	}
+	mod.publish()
+	return fmt.Sprintf("✨ Created **%s** (%s). Registrations are closed until you open them.", t.Name, rosterLimits(t)), nil
+}
+
+func (mod *Tournaments) adminOpen(name string, open bool) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTournament(name)
+	if err != nil {
+		return "", err
+	}
+	t.Open = open
+	err = mod.saveTournament(t)
+	if err != nil {
+		return "", err
+	}
+	mod.publish()
+	if open {
+		return fmt.Sprintf("✍️ Opened registrations for **%s**", t.Name), nil
+	}
+	return fmt.Sprintf("🔒 Closed registrations for **%s**", t.Name), nil
+}
+
+func (mod *Tournaments) adminRemove(name string, role *discordgo.Role) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTournament(name)
+	if err != nil {
+		return "", err
+	}
+	idx := t.registrationIndex(role.ID)
+	if idx == -1 {
+		return fmt.Sprintf("⚠️ %s.", ErrNotRegistered), nil
+	}
+	t.Registrations = slices.Delete(t.Registrations, idx, idx+1)
+	err = mod.saveTournament(t)
+	if err != nil {
+		return "", err
+	}
+	mod.publish()
+	return fmt.Sprintf("👢 Removed %s from **%s**", role.Name, t.Name), nil
+}
+
+func (mod *Tournaments) adminDelete(name string, confirm bool) (content string, err error) {
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTournament(name)
+	if err != nil {
+		return "", err
+	}
+	if !confirm {
+		return fmt.Sprintf("🤔 This will delete **%s** and its %d %s. If you're sure, run this again with `confirm` set.", t.Name, len(t.Registrations), helpers.Pluralise(len(t.Registrations), "registration", "registrations")), nil
+	}
+	err = mod.deleteTournament(t.Name)
+	if err != nil {
+		return "", err
+	}
+	mod.publish()
+	return fmt.Sprintf("🗑️ Deleted **%s**", t.Name), nil
+}
+
+func (mod *Tournaments) adminExport(name, format string) (content string, files []*discordgo.File, err error) {
+	t, err := mod.getTournament(name)
+	if err != nil {
+		return "", nil, err
+	}
+	var data []byte
+	var contentType string
+	switch format {
+	case "csv":
+		data, err = exportCSV(t)
+		contentType = "text/csv"
+	case "json":
+		data, err = json.MarshalIndent(t, "", "  ")
+		contentType = "application/json"
+	default:
+		return fmt.Sprintf("⚠️ Unknown export format %s.", format), nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	fileName := fmt.Sprintf("%s-%s.%s", exportFileName(t.Name), time.Now().Format("20060102-150405"), format)
+	files = []*discordgo.File{
+		{
+			Name:        fileName,
+			ContentType: contentType,
+			Reader:      bytes.NewReader(data),
+		},
+	}
+	return fmt.Sprintf("📤 Registrations for **%s** (%d %s)", t.Name, len(t.Registrations), helpers.Pluralise(len(t.Registrations), "team", "teams")), files, nil
+}
+
+// exportCSV renders a tournament's registrations as CSV, with one row per player.
+func exportCSV(t tournament) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	err := w.Write([]string{"tournament", "team", "team_id", "captain", "captain_id", "player", "player_id", "registered_at"})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range t.Registrations {
+		for _, p := range r.Players {
+			err = w.Write([]string{t.Name, r.TeamName, r.TeamID, r.Captain.Name, r.Captain.ID, p.Name, p.ID, r.RegisteredAt.UTC().Format(time.RFC3339)})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// exportFileName turns a tournament name into something safe to use in a file name.
+func exportFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+}
+
+// replicantData builds the NodeCG replicant for the given tournaments.
+func replicantData(tournaments []tournament) ngtbg.NodeCGReplicantDataTournaments {
+	data := ngtbg.NodeCGReplicantDataTournaments{
+		Tournaments: []ngtbg.NodeCGReplicantDataTournament{},
+	}
+	for _, t := range tournaments {
+		entry := ngtbg.NodeCGReplicantDataTournament{
+			Name:       t.Name,
+			Open:       t.Open,
+			MinPlayers: t.MinPlayers,
+			MaxPlayers: t.MaxPlayers,
+			Teams:      []ngtbg.NodeCGReplicantDataTournamentTeam{},
+		}
+		for _, r := range t.Registrations {
+			team := ngtbg.NodeCGReplicantDataTournamentTeam{
+				Name:    r.TeamName,
+				Captain: r.Captain.Name,
+				Players: []string{},
+			}
+			for _, p := range r.Players {
+				team.Players = append(team.Players, p.Name)
+			}
+			entry.Teams = append(entry.Teams, team)
+		}
+		data.Tournaments = append(data.Tournaments, entry)
+	}
+	return data
+}
+
+// sendReplicant sends every tournament to NodeCG.
+func (mod *Tournaments) sendReplicant() error {
+	if !bridge_wan.BridgeIsAvailable() {
+		return ErrBridgeUnavailable
+	}
+	tournaments, err := mod.listTournaments()
+	if err != nil {
+		return err
+	}
+	return bridge_wan.ReplicantSet(ngtbg.ReplicantTournaments, replicantData(tournaments))
+}
+
+// publish sends every tournament to NodeCG, if the bridge is available.
+// This is best-effort; Crew can always re-send with /tournaments admin publish.
+func (mod *Tournaments) publish() {
+	err := mod.sendReplicant()
+	if errors.Is(err, ErrBridgeUnavailable) {
+		logger.Debug("Event Bridge not available, not publishing tournaments")
+	} else if err != nil {
+		logger.Error("Unable to publish tournaments to NodeCG", slog.Any("error", err))
+	}
+}
+
+func (mod *Tournaments) adminPublish() (content string, err error) {
+	err = mod.sendReplicant()
+	if errors.Is(err, ErrBridgeUnavailable) {
+		return "👻 **Event Bridge is not available**", nil
+	} else if err != nil {
+		return "", err
+	}
+	return "📺 Sent tournaments to NodeCG.", nil
+}
+
+var minPlayersMinValue float64 = 1
+
+var defaultCrewCommandPermissions int64 = discordgo.PermissionAdministrator
+var defaultCrewCommandDMPermissions = false
@@ -0,0 +1,15 @@
+package tournaments
+
+import "errors"
+
+var ErrUnknownTournament = errors.New("There is no tournament with that name")
+var ErrTournamentExists = errors.New("A tournament with that name already exists")
+var ErrTournamentClosed = errors.New("Registrations for that tournament are closed")
+var ErrNotTeam = errors.New("This is not a team")
+var ErrNotCaptain = errors.New("Only the team captain (or Crew) can do that. If your team doesn't have a captain, ask Crew to appoint one")
+var ErrAlreadyRegistered = errors.New("That team is already registered for this tournament")
+var ErrNotRegistered = errors.New("That team is not registered for this tournament")
+var ErrRosterTooSmall = errors.New("That team doesn't have enough players for this tournament")
+var ErrRosterTooLarge = errors.New("That team has too many players for this tournament")
+var ErrPlayerAlreadyRegistered = errors.New("Players can only play for one team per tournament")
+var ErrBridgeUnavailable = errors.New("Event Bridge is not available")
@@ -0,0 +1,92 @@
+package tournaments
+
+import (
+	"context"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// logger stores the module's logger instance.
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+// Teams is how we find out about LAN teams (which are managed by the teamroles module).
+type Teams interface {
+	// IsTeam returns whether the given role is a team.
+	IsTeam(roleID string) bool
+	// TeamCaptain returns the user ID of the given team's captain, or an empty string if it doesn't have one.
+	TeamCaptain(roleID string) (string, error)
+	// TeamName returns the display name of the team with the given role.
+	TeamName(role *discordgo.Role) string
+}
+
+type Tournaments struct {
+	discord *discordgo.Session
+
+	// Persistent storage for tournaments and their registrations.
+	store *storage.Store
+
+	// The LAN's teams.
+	teams Teams
+
+	// Held while registrations are being changed.
+	mtx sync.Mutex
+}
+
+func New(discord *discordgo.Session, store *storage.Store, teams Teams) (mod *Tournaments, err error) {
+	return &Tournaments{
+		discord: discord,
+		store:   store,
+		teams:   teams,
+	}, nil
+}
+
+func (mod *Tournaments) SetLogger(log *slog.Logger) {
+	logger = log
+}
+
+func (mod *Tournaments) DiscordCommands() ([]*discordgo.ApplicationCommand, error) {
+	return append(commands, adminCommands...), nil
+}
+
+func (mod *Tournaments) Start(ctx context.Context) (err error) {
+	// This module simply registers handlers, and does not need to run continuously (so we don't need the context)
+	return ctx.Err()
+}
+
+func (mod *Tournaments) DiscordHandleMessage(_ *discordgo.Session, _ *discordgo.MessageCreate) (err error) {
+	return nil
+}
+
+func (mod *Tournaments) DiscordHandleMessageUpdate(_ *discordgo.Session, _ *discordgo.MessageUpdate) (err error) {
+	return nil
+}
+
+func (mod *Tournaments) DiscordHandleMessageDelete(_ *discordgo.Session, _ *discordgo.MessageDelete) (err error) {
+	return nil
+}
+
+func (mod *Tournaments) DiscordHandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) (handled bool, err error) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		switch i.ApplicationCommandData().Name {
+		case "tournament":
+			return true, mod.discordHandleCommand(s, i)
+		case "tournaments":
+			return true, mod.discordHandleAdminCommand(s, i)
+		default:
+			return false, nil
+		}
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		switch i.ApplicationCommandData().Name {
+		case "tournament", "tournaments":
+			return true, mod.discordHandleAutocomplete(s, i)
+		default:
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,81 @@
+package tournaments
+
+import (
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTournament = tournament{
+	Name:       "Rocket League 3v3",
+	Open:       true,
+	MinPlayers: 3,
+	MaxPlayers: 4,
+	Registrations: []registration{
+		{
+			TeamID:   "10",
+			TeamName: "iBUYJEFFS",
+			Captain:  player{ID: "1", Name: "Alice"},
+			Players: []player{
+				{ID: "1", Name: "Alice"},
+				{ID: "2", Name: "Bob"},
+				{ID: "3", Name: "Carol"},
+			},
+			RegisteredAt: time.Date(2024, 8, 23, 18, 0, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestValidateRoster(t *testing.T) {
+	roster := []player{{ID: "4", Name: "Dave"}, {ID: "5", Name: "Erin"}, {ID: "6", Name: "Frank"}}
+	if err := validateRoster(testTournament, "11", roster); err != nil {
+		t.Errorf("Roster should be valid, got %v", err)
+	}
+	if err := validateRoster(testTournament, "10", roster); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("Registered team should be rejected, got %v", err)
+	}
+	if err := validateRoster(testTournament, "11", roster[:2]); !errors.Is(err, ErrRosterTooSmall) {
+		t.Errorf("Small roster should be rejected, got %v", err)
+	}
+	large := append(roster, player{ID: "7", Name: "Grace"}, player{ID: "8", Name: "Heidi"})
+	if err := validateRoster(testTournament, "11", large); !errors.Is(err, ErrRosterTooLarge) {
+		t.Errorf("Large roster should be rejected, got %v", err)
+	}
+	overlapping := append(roster[:2], player{ID: "2", Name: "Bob"})
+	if err := validateRoster(testTournament, "11", overlapping); !errors.Is(err, ErrPlayerAlreadyRegistered) {
+		t.Errorf("Roster sharing a player should be rejected, got %v", err)
+	}
+
+	unlimited := testTournament
+	unlimited.MaxPlayers = 0
+	if err := validateRoster(unlimited, "11", large); err != nil {
+		t.Errorf("Tournament without a maximum should accept large rosters, got %v", err)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	data, err := exportCSV(testTournament)
+	if err != nil {
+		t.Fatalf("exportCSV err: %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		t.Fatalf("Export should be valid CSV: %v", err)
+	}
+	// A header, plus a row per player.
+	if len(records) != 4 {
+		t.Fatalf("Export should have 4 rows, got %d", len(records))
+	}
+	expected := []string{"Rocket League 3v3", "iBUYJEFFS", "10", "Alice", "1", "Bob", "2", "2024-08-23T18:00:00Z"}
+	if strings.Join(records[2], ",") != strings.Join(expected, ",") {
+		t.Errorf("Row should be %v, got %v", expected, records[2])
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	if name := exportFileName("Rocket League 3v3!"); name != "rocket-league-3v3-" {
+		t.Errorf("File name should be 'rocket-league-3v3-', got '%s'", name)
+	}
+}
@@ -0,0 +1,342 @@
+package tournaments
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/thebiggame/bigbot/internal/helpers"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// This file handles teams registering for (and withdrawing from) tournaments.
+
+// tournamentOption is the (autocompleted) option used to pick a tournament.
+var tournamentOption = &discordgo.ApplicationCommandOption{
+	Type:         discordgo.ApplicationCommandOptionString,
+	Name:         "tournament",
+	Description:  "The name of the tournament.",
+	Required:     true,
+	Autocomplete: true,
+}
+
+var commands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "tournament",
+		Description: "🏆 Register your team for LAN tournaments.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "register",
+				Description: "🏆✍️ Register your team for a tournament (you must be the team captain).",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					tournamentOption,
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "team",
+						Description: "The team you captain.",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Name:        "withdraw",
+				Description: "🏆👋 Withdraw your team from a tournament (you must be the team captain).",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					tournamentOption,
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "team",
+						Description: "The team you captain.",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Description: "🏆📋 List all tournaments.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "info",
+				Description: "🏆🔎 See which teams are registered for a tournament.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					tournamentOption,
+				},
+			},
+		},
+	},
+}
+
+func (mod *Tournaments) discordHandleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	if i.Interaction.GuildID == "" {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😡 This command can only be used in a server.")
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return helpers.DiscordInteractionEphemeralResponse(s, i, "😶 Please use a sub-command.")
+	}
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options[0].Options))
+	for _, opt := range options[0].Options {
+		optionMap[opt.Name] = opt
+	}
+
+	var content string
+	switch options[0].Name {
+	case "register":
+		content, err = mod.register(s, i.GuildID, i.Member.User, optionMap["tournament"].StringValue(), optionMap["team"].RoleValue(s, i.GuildID))
+	case "withdraw":
+		content, err = mod.withdraw(s, i.GuildID, i.Member.User, optionMap["tournament"].StringValue(), optionMap["team"].RoleValue(s, i.GuildID))
+	case "list":
+		content, err = mod.tournamentList()
+	case "info":
+		content, err = mod.tournamentInfo(optionMap["tournament"].StringValue())
+	default:
+		content = "😶 Please use a sub-command."
+	}
+	if errors.Is(err, ErrUnknownTournament) {
+		content, err = fmt.Sprintf("⚠️ %s.", ErrUnknownTournament), nil
+	}
+	if err != nil {
+		return err
+	}
+	return helpers.DiscordInteractionEphemeralResponse(s, i, content)
+}
+
+// discordHandleAutocomplete suggests tournament names as they're typed.
+func (mod *Tournaments) discordHandleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
+	var typed string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Type == discordgo.ApplicationCommandOptionSubCommandGroup {
+			opt = opt.Options[0]
+		}
+		for _, o := range opt.Options {
+			if o.Focused {
+				typed = tournamentKey(o.StringValue())
+			}
+		}
+	}
+	tournaments, err := mod.listTournaments()
+	if err != nil {
+		return err
+	}
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, t := range tournaments {
+		if !strings.Contains(tournamentKey(t.Name), typed) {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: t.Name, Value: t.Name})
+		// Discord won't show any more than this.
+		if len(choices) == 25 {
+			break
+		}
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+// validateUserCanManageTeam checks that the given user captains the given team (or is Crew).
+func (mod *Tournaments) validateUserCanManageTeam(s *discordgo.Session, guild string, u *discordgo.User, role *discordgo.Role) error {
+	if !mod.teams.IsTeam(role.ID) {
+		return ErrNotTeam
+	}
+	captain, err := mod.teams.TeamCaptain(role.ID)
+	if err != nil {
+		return err
+	}
+	if captain == u.ID {
+		return nil
+	}
+	isCrew, err := helpers.UserIsCrew(s, guild, u)
+	if err != nil {
+		return err
+	}
+	if !isCrew {
+		return ErrNotCaptain
+	}
+	return nil
+}
+
+// teamRoster returns every member of the given team, in name order.
+func teamRoster(s *discordgo.Session, guild, roleID string) (players []player, err error) {
+	g, err := s.State.Guild(guild)
+	if err != nil {
+		return nil, err
+	}
+	s.State.RLock()
+	defer s.State.RUnlock()
+	for _, member := range g.Members {
+		if slices.Contains(member.Roles, roleID) {
+			players = append(players, player{ID: member.User.ID, Name: helpers.DiscordMemberDisplayName(member)})
+		}
+	}
+	slices.SortFunc(players, func(a, b player) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return players, nil
+}
+
+// validateRoster checks that the given team (with the given roster) can register for the tournament.
+func validateRoster(t tournament, teamID string, players []player) error {
+	if t.registrationIndex(teamID) != -1 {
+		return ErrAlreadyRegistered
+	}
+	if len(players) < t.MinPlayers {
+		return fmt.Errorf("%w (it needs at least %d)", ErrRosterTooSmall, t.MinPlayers)
+	}
+	if t.MaxPlayers > 0 && len(players) > t.MaxPlayers {
+		return fmt.Errorf("%w (it allows at most %d)", ErrRosterTooLarge, t.MaxPlayers)
+	}
+	for _, r := range t.Registrations {
+		for _, p := range r.Players {
+			if slices.ContainsFunc(players, func(candidate player) bool { return candidate.ID == p.ID }) {
+				return fmt.Errorf("%w, and %s is already playing for %s", ErrPlayerAlreadyRegistered, p.Name, r.TeamName)
+			}
+		}
+	}
+	return nil
+}
+
+func (mod *Tournaments) register(s *discordgo.Session, guild string, u *discordgo.User, name string, role *discordgo.Role) (content string, err error) {
+	err = mod.validateUserCanManageTeam(s, guild, u, role)
+	if errors.Is(err, ErrNotTeam) || errors.Is(err, ErrNotCaptain) {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	} else if err != nil {
+		return "", err
+	}
+	players, err := teamRoster(s, guild, role.ID)
+	if err != nil {
+		return "", err
+	}
+
+	// Hold the lock while checking rosters, so two teams can't sneak the same player in at once.
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTournament(name)
+	if err != nil {
+		return "", err
+	}
+	if !t.Open {
+		return fmt.Sprintf("⚠️ %s.", ErrTournamentClosed), nil
+	}
+	err = validateRoster(t, role.ID, players)
+	if err != nil {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	}
+	captain := player{ID: u.ID, Name: u.Username}
+	if member, err := s.State.Member(guild, u.ID); err == nil {
+		captain.Name = helpers.DiscordMemberDisplayName(member)
+	}
+	t.Registrations = append(t.Registrations, registration{
+		TeamID:       role.ID,
+		TeamName:     mod.teams.TeamName(role),
+		Captain:      captain,
+		Players:      players,
+		RegisteredAt: time.Now(),
+	})
+	err = mod.saveTournament(t)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("Team registered for tournament", slog.String("tournament", t.Name), slog.String("team", role.Name), slog.Int("players", len(players)))
+	mod.publish()
+
+	var names []string
+	for _, p := range players {
+		names = append(names, p.Name)
+	}
+	return fmt.Sprintf("🏆 Registered **%s** for **%s** with %d %s: %s", mod.teams.TeamName(role), t.Name, len(players), helpers.Pluralise(len(players), "player", "players"), strings.Join(names, ", ")), nil
+}
+
+func (mod *Tournaments) withdraw(s *discordgo.Session, guild string, u *discordgo.User, name string, role *discordgo.Role) (content string, err error) {
+	err = mod.validateUserCanManageTeam(s, guild, u, role)
+	if errors.Is(err, ErrNotTeam) || errors.Is(err, ErrNotCaptain) {
+		return fmt.Sprintf("⚠️ %s.", err), nil
+	} else if err != nil {
+		return "", err
+	}
+
+	mod.mtx.Lock()
+	defer mod.mtx.Unlock()
+	t, err := mod.getTournament(name)
+	if err != nil {
+		return "", err
+	}
+	if !t.Open {
+		return fmt.Sprintf("⚠️ %s. Please contact a member of Crew.", ErrTournamentClosed), nil
+	}
+	idx := t.registrationIndex(role.ID)
+	if idx == -1 {
+		return fmt.Sprintf("⚠️ %s.", ErrNotRegistered), nil
+	}
+	t.Registrations = slices.Delete(t.Registrations, idx, idx+1)
+	err = mod.saveTournament(t)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("Team withdrew from tournament", slog.String("tournament", t.Name), slog.String("team", role.Name))
+	mod.publish()
+	return fmt.Sprintf("👋 Withdrew **%s** from **%s**", mod.teams.TeamName(role), t.Name), nil
+}
+
+// tournamentList renders a summary of every tournament.
+func (mod *Tournaments) tournamentList() (content string, err error) {
+	tournaments, err := mod.listTournaments()
+	if err != nil {
+		return "", err
+	}
+	if len(tournaments) == 0 {
+		return "🤷 There are no tournaments yet.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("🏆 **Tournaments**\n")
+	for _, t := range tournaments {
+		status := "🔒 closed"
+		if t.Open {
+			status = "✍️ open"
+		}
+		sb.WriteString(fmt.Sprintf("- **%s** (%s, %s) - %d %s registered\n", t.Name, rosterLimits(t), status, len(t.Registrations), helpers.Pluralise(len(t.Registrations), "team", "teams")))
+	}
+	return sb.String(), nil
+}
+
+// tournamentInfo renders the teams registered for a tournament.
+func (mod *Tournaments) tournamentInfo(name string) (content string, err error) {
+	t, err := mod.getTournament(name)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🏆 **%s** (%s)\n", t.Name, rosterLimits(t)))
+	if len(t.Registrations) == 0 {
+		sb.WriteString("No teams have registered yet.\n")
+	}
+	for idx, r := range t.Registrations {
+		var names []string
+		for _, p := range r.Players {
+			names = append(names, p.Name)
+		}
+		sb.WriteString(fmt.Sprintf("%d. **%s** - %s\n", idx+1, r.TeamName, strings.Join(names, ", ")))
+	}
+	return sb.String(), nil
+}
+
+// rosterLimits describes the roster size limits of a tournament.
+func rosterLimits(t tournament) string {
+	switch {
+	case t.MaxPlayers == 0:
+		return fmt.Sprintf("%d+ players", t.MinPlayers)
+	case t.MinPlayers == t.MaxPlayers:
+		return fmt.Sprintf("%d %s", t.MinPlayers, helpers.Pluralise(t.MinPlayers, "player", "players"))
+	default:
+		return fmt.Sprintf("%d-%d players", t.MinPlayers, t.MaxPlayers)
+	}
+}
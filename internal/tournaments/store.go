@@ -0,0 +1,89 @@
+package tournaments
+
+import (
+	"errors"
+	"github.com/thebiggame/bigbot/internal/storage"
+	"slices"
+	"strings"
+	"time"
+)
+
+// tournamentsBucket is the storage bucket tournaments are kept in.
+const tournamentsBucket = "tournaments"
+
+// tournament is a tournament that teams can register for, keyed on tournamentKey(Name).
+type tournament struct {
+	// The name of the tournament, as Crew wrote it.
+	Name string `json:"name"`
+	// Whether teams can currently register (or withdraw).
+	Open bool `json:"open"`
+	// The smallest and largest roster allowed. A MaxPlayers of 0 means there is no limit.
+	MinPlayers int `json:"min_players"`
+	MaxPlayers int `json:"max_players"`
+	// Every registered team, in order of registration.
+	Registrations []registration `json:"registrations"`
+}
+
+// registration is a team's entry into a tournament.
+type registration struct {
+	// The team's role.
+	TeamID string `json:"team_id"`
+	// The name of the team when it registered.
+	TeamName string `json:"team_name"`
+	// Who registered the team.
+	Captain player `json:"captain"`
+	// The team's roster when it registered.
+	Players      []player  `json:"players"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// player is a member of a team's roster.
+type player struct {
+	ID string `json:"id"`
+	// The player's display name when the team registered.
+	Name string `json:"name"`
+}
+
+// tournamentKey returns the storage key for the tournament with the given name.
+// Tournament names are case-insensitive.
+func tournamentKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// getTournament fetches the tournament with the given name.
+// Returns ErrUnknownTournament if there is no such tournament.
+func (mod *Tournaments) getTournament(name string) (t tournament, err error) {
+	err = mod.store.Get(tournamentsBucket, tournamentKey(name), &t)
+	if errors.Is(err, storage.ErrNotFound) {
+		return tournament{}, ErrUnknownTournament
+	}
+	return t, err
+}
+
+func (mod *Tournaments) saveTournament(t tournament) error {
+	return mod.store.Put(tournamentsBucket, tournamentKey(t.Name), t)
+}
+
+func (mod *Tournaments) deleteTournament(name string) error {
+	return mod.store.Delete(tournamentsBucket, tournamentKey(name))
+}
+
+// listTournaments returns every tournament, in name order.
+func (mod *Tournaments) listTournaments() (tournaments []tournament, err error) {
+	for _, key := range mod.store.Keys(tournamentsBucket) {
+		var t tournament
+		err = mod.store.Get(tournamentsBucket, key, &t)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, nil
+}
+
+// registrationIndex returns the index of the given team's registration, or -1 if it isn't registered.
+func (t tournament) registrationIndex(teamID string) int {
+	return slices.IndexFunc(t.Registrations, func(r registration) bool {
+		return r.TeamID == teamID
+	})
+}
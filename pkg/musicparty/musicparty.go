@@ -0,0 +1,203 @@
+// Package musicparty defines an API for communicating with a MusicParty (or compatible) music server.
+//
+// The server is expected to speak JSON over HTTP:
+//
+//	GET    /api/now-playing       what's playing right now (NowPlaying)
+//	GET    /api/queue             the tracks waiting to be played ({"tracks": [Track]})
+//	GET    /api/search?q=(query)  tracks matching a query ({"tracks": [Track]})
+//	POST   /api/queue             add a track to the queue ({"track_id", "requested_by"}, returns {"track": Track})
+//	DELETE /api/queue/(id)        remove a track from the queue
+//	POST   /api/skip              skip the track that's playing
+//
+// Errors are returned with a non-2xx status and a body of {"error": "(message)"}.
+package musicparty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	apiPrefix        = "/api"
+	nowPlayingPath   = apiPrefix + "/now-playing"
+	queuePath        = apiPrefix + "/queue"
+	searchPath       = apiPrefix + "/search"
+	skipPath         = apiPrefix + "/skip"
+	requestTimeout   = 5 * time.Second
+	maxErrorBodySize = 4 << 10
+)
+
+var (
+	ErrUnauthorized = errors.New("MusicParty rejected our key")
+	ErrNotFound     = errors.New("MusicParty couldn't find that")
+	ErrServerError  = errors.New("MusicParty error")
+)
+
+// Client talks to a MusicParty server.
+type Client struct {
+	Hostname string
+	Key      string
+}
+
+func New(host string) *Client {
+	return &Client{
+		Hostname: host,
+	}
+}
+
+func (c *Client) WithKey(key string) *Client {
+	c.Key = key
+	return c
+}
+
+// Track is one song.
+type Track struct {
+	// A unique ID for the track, used to request it.
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Album  string `json:"album,omitempty"`
+	// The length of the track, in seconds.
+	Duration int `json:"duration,omitempty"`
+	// Where the track's artwork can be found, if it has any.
+	ArtURL string `json:"art_url,omitempty"`
+	// Who asked for the track to be played, if anybody did.
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+// Length returns the length of the track.
+func (t Track) Length() time.Duration {
+	return time.Duration(t.Duration) * time.Second
+}
+
+// NowPlaying is what's playing right now.
+type NowPlaying struct {
+	// Whether anything is playing. Track may still be set if the current track is paused.
+	Playing bool   `json:"playing"`
+	Track   *Track `json:"track"`
+	// How far through the track we are, in seconds.
+	Position int `json:"position,omitempty"`
+}
+
+type tracksResponse struct {
+	Tracks []Track `json:"tracks"`
+}
+
+type trackResponse struct {
+	Track Track `json:"track"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type requestEnqueue struct {
+	TrackID     string `json:"track_id"`
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+// NowPlaying fetches what's playing right now.
+func (c *Client) NowPlaying(ctx context.Context) (np NowPlaying, err error) {
+	err = c.do(ctx, http.MethodGet, nowPlayingPath, nil, &np)
+	return np, err
+}
+
+// Queue fetches the tracks waiting to be played, in the order they'll be played.
+func (c *Client) Queue(ctx context.Context) (tracks []Track, err error) {
+	var resp tracksResponse
+	err = c.do(ctx, http.MethodGet, queuePath, nil, &resp)
+	return resp.Tracks, err
+}
+
+// Search fetches the tracks matching the given query, best match first.
+func (c *Client) Search(ctx context.Context, query string) (tracks []Track, err error) {
+	var resp tracksResponse
+	err = c.do(ctx, http.MethodGet, searchPath+"?"+url.Values{"q": {query}}.Encode(), nil, &resp)
+	return resp.Tracks, err
+}
+
+// Enqueue adds the track with the given ID to the end of the queue, noting who asked for it.
+func (c *Client) Enqueue(ctx context.Context, trackID, requestedBy string) (track Track, err error) {
+	var resp trackResponse
+	err = c.do(ctx, http.MethodPost, queuePath, requestEnqueue{
+		TrackID:     trackID,
+		RequestedBy: requestedBy,
+	}, &resp)
+	return resp.Track, err
+}
+
+// Remove takes the track with the given ID out of the queue.
+func (c *Client) Remove(ctx context.Context, trackID string) (err error) {
+	return c.do(ctx, http.MethodDelete, queuePath+"/"+url.PathEscape(trackID), nil, nil)
+}
+
+// Skip moves on to the next track in the queue.
+func (c *Client) Skip(ctx context.Context) (err error) {
+	return c.do(ctx, http.MethodPost, skipPath, nil, nil)
+}
+
+// do makes a request to the MusicParty API, decoding the response into target (if it isn't nil).
+func (c *Client) do(ctx context.Context, method, path string, body any, target any) (err error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	tCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(tCtx, method, c.Hostname+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Key)
+	}
+
+	respData, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer respData.Body.Close()
+
+	if respData.StatusCode < 200 || respData.StatusCode > 299 {
+		return responseError(respData)
+	}
+	if target == nil {
+		return nil
+	}
+	return json.NewDecoder(respData.Body).Decode(target)
+}
+
+// responseError turns an unsuccessful response into an error.
+func responseError(resp *http.Response) error {
+	var sentinel error
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		sentinel = ErrUnauthorized
+	case http.StatusNotFound:
+		sentinel = ErrNotFound
+	default:
+		sentinel = ErrServerError
+	}
+	var errResp errorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBodySize)).Decode(&errResp); err != nil || errResp.Error == "" {
+		return fmt.Errorf("%w (%s)", sentinel, resp.Status)
+	}
+	return fmt.Errorf("%w: %s", sentinel, errResp.Error)
+}
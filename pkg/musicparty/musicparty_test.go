@@ -0,0 +1,111 @@
+package musicparty_test
+
+import (
+	"context"
+	"errors"
+	"github.com/thebiggame/bigbot/pkg/musicparty"
+	"github.com/thebiggame/bigbot/pkg/musicparty/musicpartytest"
+	"testing"
+)
+
+var testCatalogue = []musicparty.Track{
+	{ID: "1", Title: "Never Gonna Give You Up", Artist: "Rick Astley", Duration: 213},
+	{ID: "2", Title: "Sandstorm", Artist: "Darude", Duration: 225},
+	{ID: "3", Title: "Together Forever", Artist: "Rick Astley", Duration: 205},
+}
+
+func TestNowPlaying(t *testing.T) {
+	server := musicpartytest.NewServer("secret", testCatalogue...)
+	defer server.Close()
+	client := musicparty.New(server.URL).WithKey("secret")
+
+	np, err := client.NowPlaying(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if np.Playing || np.Track != nil {
+		t.Errorf("Nothing should be playing, got %+v", np)
+	}
+
+	server.SetPlaying(&testCatalogue[1])
+	np, err = client.NowPlaying(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !np.Playing || np.Track == nil || np.Track.Title != "Sandstorm" || np.Track.Length().Seconds() != 225 {
+		t.Errorf("Sandstorm should be playing, got %+v", np)
+	}
+}
+
+func TestSearchAndEnqueue(t *testing.T) {
+	server := musicpartytest.NewServer("secret", testCatalogue...)
+	defer server.Close()
+	client := musicparty.New(server.URL).WithKey("secret")
+
+	tracks, err := client.Search(context.Background(), "rick astley")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 {
+		t.Fatalf("Expected 2 matching tracks, got %d", len(tracks))
+	}
+	track, err := client.Enqueue(context.Background(), tracks[1].ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.Title != "Together Forever" || track.RequestedBy != "alice" {
+		t.Errorf("Unexpected track queued: %+v", track)
+	}
+
+	queue, err := client.Queue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].ID != "3" {
+		t.Errorf("Expected Together Forever to be queued, got %+v", queue)
+	}
+	if _, err = client.Enqueue(context.Background(), "404", "alice"); !errors.Is(err, musicparty.ErrNotFound) {
+		t.Errorf("Unknown tracks should be rejected, got %v", err)
+	}
+}
+
+func TestRemoveAndSkip(t *testing.T) {
+	server := musicpartytest.NewServer("", testCatalogue...)
+	defer server.Close()
+	client := musicparty.New(server.URL)
+
+	for _, track := range testCatalogue {
+		if _, err := client.Enqueue(context.Background(), track.ID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.Remove(context.Background(), "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Remove(context.Background(), "2"); !errors.Is(err, musicparty.ErrNotFound) {
+		t.Errorf("Removing an unqueued track should fail, got %v", err)
+	}
+	if err := client.Skip(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	np, err := client.NowPlaying(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if np.Track == nil || np.Track.ID != "1" {
+		t.Errorf("Expected the first track to be playing after a skip, got %+v", np.Track)
+	}
+	if queue := server.Queue(); len(queue) != 1 || queue[0].ID != "3" {
+		t.Errorf("Expected only the third track to be left in the queue, got %+v", queue)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	server := musicpartytest.NewServer("secret", testCatalogue...)
+	defer server.Close()
+	client := musicparty.New(server.URL).WithKey("wrong")
+
+	if _, err := client.Queue(context.Background()); !errors.Is(err, musicparty.ErrUnauthorized) {
+		t.Errorf("Wrong key should be rejected, got %v", err)
+	}
+}
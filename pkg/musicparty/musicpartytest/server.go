@@ -0,0 +1,159 @@
+// Package musicpartytest provides a fake MusicParty server, for testing things that talk to MusicParty.
+package musicpartytest
+
+import (
+	"encoding/json"
+	"github.com/thebiggame/bigbot/pkg/musicparty"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Server is a fake MusicParty server. It plays nothing, but keeps track of what it would be playing.
+type Server struct {
+	*httptest.Server
+
+	// The key clients must give, if any.
+	key string
+
+	mtx sync.Mutex
+	// Every track the server knows about.
+	catalogue []musicparty.Track
+	// The track that's playing, if any.
+	playing *musicparty.Track
+	// The tracks waiting to be played.
+	queue []musicparty.Track
+}
+
+// NewServer starts a fake MusicParty server that knows about the given tracks. Call Close when finished with it.
+func NewServer(key string, catalogue ...musicparty.Track) *Server {
+	s := &Server{
+		key:       key,
+		catalogue: catalogue,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/now-playing", s.handleNowPlaying)
+	mux.HandleFunc("GET /api/queue", s.handleQueue)
+	mux.HandleFunc("GET /api/search", s.handleSearch)
+	mux.HandleFunc("POST /api/queue", s.handleEnqueue)
+	mux.HandleFunc("DELETE /api/queue/{id}", s.handleRemove)
+	mux.HandleFunc("POST /api/skip", s.handleSkip)
+	s.Server = httptest.NewServer(s.authenticate(mux))
+	return s
+}
+
+// SetPlaying sets the track that's playing (or nothing, if nil).
+func (s *Server) SetPlaying(track *musicparty.Track) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.playing = track
+}
+
+// Next moves on to the next track in the queue, returning it (or nil, if the queue was empty).
+func (s *Server) Next() *musicparty.Track {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.playing = nil
+	if len(s.queue) > 0 {
+		next := s.queue[0]
+		s.queue = s.queue[1:]
+		s.playing = &next
+	}
+	return s.playing
+}
+
+// Queue returns the tracks waiting to be played.
+func (s *Server) Queue() []musicparty.Track {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return slices.Clone(s.queue)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.key != "" && r.Header.Get("Authorization") != "Bearer "+s.key {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "bad key"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleNowPlaying(w http.ResponseWriter, _ *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	writeJSON(w, http.StatusOK, musicparty.NowPlaying{
+		Playing: s.playing != nil,
+		Track:   s.playing,
+	})
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, _ *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	writeJSON(w, http.StatusOK, map[string][]musicparty.Track{"tracks": s.queue})
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(r.URL.Query().Get("q"))
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	tracks := []musicparty.Track{}
+	for _, track := range s.catalogue {
+		if strings.Contains(strings.ToLower(track.Title), query) || strings.Contains(strings.ToLower(track.Artist), query) {
+			tracks = append(tracks, track)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string][]musicparty.Track{"tracks": tracks})
+}
+
+func (s *Server) handleEnqueue(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TrackID     string `json:"track_id"`
+		RequestedBy string `json:"requested_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	idx := slices.IndexFunc(s.catalogue, func(track musicparty.Track) bool {
+		return track.ID == req.TrackID
+	})
+	if idx == -1 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no such track"})
+		return
+	}
+	track := s.catalogue[idx]
+	track.RequestedBy = req.RequestedBy
+	s.queue = append(s.queue, track)
+	writeJSON(w, http.StatusOK, map[string]musicparty.Track{"track": track})
+}
+
+func (s *Server) handleRemove(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	idx := slices.IndexFunc(s.queue, func(track musicparty.Track) bool {
+		return track.ID == r.PathValue("id")
+	})
+	if idx == -1 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "that track isn't queued"})
+		return
+	}
+	s.queue = slices.Delete(s.queue, idx, idx+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSkip(w http.ResponseWriter, _ *http.Request) {
+	s.Next()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
@@ -9,7 +9,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"reflect"
 	"time"
 )
@@ -18,6 +20,8 @@ const (
 	nodecgMessagePrefix   = "/message"
 	nodecgReplicantPrefix = "/replicant"
 	nodecgRestPrefix      = "/rest"
+	nodecgBundlesPrefix   = "/bundles"
+	nodecgSchemasPrefix   = "/schemas"
 
 	StatusSuccess = "OK"
 	StatusError   = "ERROR"
@@ -28,6 +32,8 @@ var (
 	ErrNodeCGUnknownError  = errors.New("unknown NodeCG error")
 	ErrNodeCGGeneralError  = errors.New("NodeCG error")
 
+	ErrSchemaNotFound = errors.New("schema not found")
+
 	ErrNotBool   = errors.New("non-boolean returned")
 	ErrNotString = errors.New("non-string returned")
 )
@@ -260,3 +266,45 @@ func (s *NodeCGServer) MessageSend(ctx context.Context, bundle, messageChannel s
 	}
 	return nil
 }
+
+// BundleSchema fetches the JSON schema a bundle has for one of its replicants (from the bundle's schemas directory).
+// Returns ErrSchemaNotFound if the bundle has no schema for it.
+func (s *NodeCGServer) BundleSchema(ctx context.Context, bundle, replicant string) (schema json.RawMessage, err error) {
+	// Build URL.
+	schemaURL := s.Hostname + nodecgBundlesPrefix + "/" + url.PathEscape(bundle) + nodecgSchemasPrefix + "/" + url.PathEscape(replicant) + ".json"
+	if s.Key != "" {
+		schemaURL += "?" + url.Values{"key": {s.Key}}.Encode()
+	}
+
+	tCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(tCtx, http.MethodGet, schemaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	respData, err := HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer respData.Body.Close()
+
+	switch respData.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrSchemaNotFound
+	case http.StatusInternalServerError:
+		return nil, ErrNodeCGInternalError
+	default:
+		return nil, ErrNodeCGUnknownError
+	}
+	schema, err = io.ReadAll(respData.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(schema) {
+		return nil, fmt.Errorf("%w: schema for %s isn't valid JSON", ErrNodeCGGeneralError, replicant)
+	}
+	return schema, nil
+}